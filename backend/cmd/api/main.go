@@ -66,7 +66,11 @@ func main() {
 	}
 
 	// --- Realtime (in-memory fanout, DB remains source of truth) ---
-	rt := realtime.NewRegistry()
+	rt, err := realtimeFromEnv(ctx, logger, pool)
+	if err != nil {
+		logger.Printf("realtime config error: %v", err)
+		os.Exit(1)
+	}
 
 	// --- Repo + API ---
 	coreRepo := core.NewRepo(pool)
@@ -80,6 +84,28 @@ func main() {
 
 	api := httpapi.NewServer(coreRepo, nttRepo, rt, authSvc, httpapi.NewNameThatTuneModule())
 
+	roomState, err := roomStateFromEnv(logger, pool)
+	if err != nil {
+		logger.Printf("room state config error: %v", err)
+		os.Exit(1)
+	}
+	api.SetRoomStateStore(roomState)
+	api.SetPlaylistSource(playlistSourceFromEnv(logger))
+	// Deleted playlists stay restorable for BES_PLAYLIST_RETENTION, then get purged.
+	api.SetPlaylistRetention(envDuration("BES_PLAYLIST_RETENTION", namethattune.DefaultPlaylistRetention))
+	go api.RunPlaylistPurge(ctx, envDuration("BES_PLAYLIST_PURGE_INTERVAL", time.Hour))
+	// Players reclaim their seat with a resume token until BES_SEAT_GRACE_PERIOD after they
+	// left; tokens are signed with BES_RESUME_SECRET.
+	resumeSecret := strings.TrimSpace(os.Getenv("BES_RESUME_SECRET"))
+	if resumeSecret == "" {
+		logger.Printf("BES_RESUME_SECRET not set: resume tokens will not survive restarts")
+	}
+	api.SetResumeSecret(resumeSecret)
+	api.SetSeatGracePeriod(envDuration("BES_SEAT_GRACE_PERIOD", namethattune.DefaultSeatGracePeriod))
+	go api.RunSeatExpiry(ctx, envDuration("BES_SEAT_EXPIRY_INTERVAL", time.Minute))
+	// A seat without any room WebSocket for BES_PRESENCE_TIMEOUT counts as disconnected.
+	api.SetPresenceTimeout(envDuration("BES_PRESENCE_TIMEOUT", httpapi.DefaultPresenceTimeout))
+
 	allowedOrigins := splitCommaEnv("BES_CORS_ALLOWED_ORIGINS")
 	handler := api.Handler(httpapi.Options{
 		AllowedOrigins: allowedOrigins,
@@ -153,6 +179,54 @@ func runMigrations(ctx context.Context, logger *log.Logger, pool *pgxpool.Pool)
 	return nil
 }
 
+// realtimeFromEnv builds the realtime registry.
+// Supported env vars:
+// - BES_REALTIME_BACKEND: "memory" (default, single instance) or "postgres" (LISTEN/NOTIFY fan-out across replicas)
+//
+// Game timers, buzz arbitration and presence stay in-process whatever the backend, so
+// several replicas need room affinity: every request of a room must reach the same one.
+// - BES_REALTIME_CHANNEL: NOTIFY channel name for the postgres backend (default "bes_realtime")
+func realtimeFromEnv(ctx context.Context, logger *log.Logger, pool *pgxpool.Pool) (*realtime.Registry, error) {
+	switch strings.ToLower(envOrDefault("BES_REALTIME_BACKEND", "memory")) {
+	case "memory":
+		return realtime.NewRegistry(), nil
+	case "postgres":
+		backend := realtime.NewPostgresBackend(pool, envOrDefault("BES_REALTIME_CHANNEL", realtime.DefaultPostgresChannel))
+		go backend.Run(ctx)
+		logger.Printf("realtime: postgres LISTEN/NOTIFY backend enabled")
+		return realtime.NewRegistryWithBackend(backend), nil
+	default:
+		return nil, fmt.Errorf("unknown BES_REALTIME_BACKEND %q", os.Getenv("BES_REALTIME_BACKEND"))
+	}
+}
+
+// roomStateFromEnv builds the store for volatile per-room state (tokens, buzz cooldowns, playback sync).
+// Supported env vars:
+// - BES_ROOM_STATE_BACKEND: "memory" (default, lost on restart) or "postgres" (survives restarts, shared by replicas)
+func roomStateFromEnv(logger *log.Logger, pool *pgxpool.Pool) (namethattune.RoomStateStore, error) {
+	switch strings.ToLower(envOrDefault("BES_ROOM_STATE_BACKEND", "memory")) {
+	case "memory":
+		return namethattune.NewMemoryRoomStateStore(), nil
+	case "postgres":
+		logger.Printf("room state: postgres store enabled")
+		return namethattune.NewPostgresRoomStateStore(pool), nil
+	default:
+		return nil, fmt.Errorf("unknown BES_ROOM_STATE_BACKEND %q", os.Getenv("BES_ROOM_STATE_BACKEND"))
+	}
+}
+
+// playlistSourceFromEnv builds the source used to import remote playlists.
+// Supported env vars:
+// - BES_YOUTUBE_API_KEY: YouTube Data API v3 key; playlist import is disabled when unset
+func playlistSourceFromEnv(logger *log.Logger) namethattune.PlaylistSource {
+	key := strings.TrimSpace(os.Getenv("BES_YOUTUBE_API_KEY"))
+	if key == "" {
+		logger.Printf("playlist import: disabled (BES_YOUTUBE_API_KEY not set)")
+		return nil
+	}
+	return namethattune.NewYouTubeDataAPISource(key)
+}
+
 func envOrDefault(key, def string) string {
 	v := strings.TrimSpace(os.Getenv(key))
 	if v == "" {
@@ -0,0 +1,160 @@
+package namethattune
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Typed answers are checked against the track's answer candidates after normalization:
+// accents folded, case lowered, bracketed suffixes ("(Official Video)", "[4K]") and
+// featured artists ("feat. X") dropped, punctuation removed. A typo budget proportional
+// to the candidate's length is then allowed.
+
+// featMarkers start a featured-artist suffix; everything from the marker on is dropped.
+var featMarkers = []string{" feat. ", " feat ", " ft. ", " ft ", " featuring "}
+
+// NormalizeAnswer folds a title or a typed guess into its comparable form.
+func NormalizeAnswer(s string) string {
+	folded, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), s)
+	if err == nil {
+		s = folded
+	}
+	s = strings.ToLower(s)
+	s = stripBrackets(s)
+
+	padded := " " + s + " "
+	for _, m := range featMarkers {
+		if i := strings.Index(padded, m); i >= 0 {
+			padded = padded[:i]
+		}
+	}
+	s = padded
+
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(r)
+		case r == '\'' || r == '’':
+			// "don't" and "dont" are the same answer.
+		default:
+			space = true
+		}
+	}
+	return b.String()
+}
+
+// stripBrackets removes (...), [...] and {...} groups, nested or not.
+func stripBrackets(s string) string {
+	var b strings.Builder
+	depth := 0
+	for _, r := range s {
+		switch r {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			if depth > 0 {
+				depth--
+			}
+		default:
+			if depth == 0 {
+				b.WriteRune(r)
+			}
+		}
+	}
+	return b.String()
+}
+
+// AnswerCandidates lists the accepted normalized answers for a track. The song title alone
+// and "artist song" are accepted, plus the owner's AcceptedAnswers. Items without parsed
+// metadata fall back to splitting a YouTube style "Artist - Song" title.
+func AnswerCandidates(item PlaylistItem) []string {
+	artist, song := item.Artist, item.SongTitle
+	if song == "" {
+		if a, s, ok := strings.Cut(item.Title, " - "); ok {
+			artist, song = a, s
+		} else {
+			artist, song = "", item.Title
+		}
+	}
+
+	a, s := NormalizeAnswer(artist), NormalizeAnswer(song)
+	normalized := []string{s, strings.TrimSpace(a + " " + s)}
+	for _, alt := range item.AcceptedAnswers {
+		normalized = append(normalized, NormalizeAnswer(alt))
+	}
+
+	seen := make(map[string]bool, len(normalized))
+	out := make([]string, 0, len(normalized))
+	for _, n := range normalized {
+		if n == "" || seen[n] {
+			continue
+		}
+		seen[n] = true
+		out = append(out, n)
+	}
+	return out
+}
+
+// MatchAnswer reports whether a typed guess matches one of the track's answers.
+func MatchAnswer(guess string, item PlaylistItem) bool {
+	// Spacing is not significant: "s.o.s", "S O S" and "SOS" are the same answer.
+	g := strings.ReplaceAll(NormalizeAnswer(guess), " ", "")
+	if g == "" {
+		return false
+	}
+	for _, c := range AnswerCandidates(item) {
+		c = strings.ReplaceAll(c, " ", "")
+		if editDistance(g, c) <= typoBudget(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// typoBudget is the number of edits tolerated for a candidate: none for very short
+// answers, then one per five characters, up to three.
+func typoBudget(candidate string) int {
+	n := len([]rune(candidate))
+	switch {
+	case n < 4:
+		return 0
+	case n/5 < 1:
+		return 1
+	case n/5 > 3:
+		return 3
+	default:
+		return n / 5
+	}
+}
+
+// editDistance is the Levenshtein distance between a and b, in runes.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,61 @@
+package namethattune
+
+import "testing"
+
+func TestNormalizeAnswer(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		"Alizée - Moi... Lolita (Official Video) [4K]": "alizee moi lolita",
+		"Get Lucky feat. Pharrell Williams":            "get lucky",
+		"Don’t Stop Me Now":                            "dont stop me now",
+		"  Ça   plane pour moi!! ":                     "ca plane pour moi",
+	}
+	for in, want := range cases {
+		if got := NormalizeAnswer(in); got != want {
+			t.Errorf("NormalizeAnswer(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestMatchAnswer(t *testing.T) {
+	t.Parallel()
+
+	item := PlaylistItem{Title: "Daft Punk feat. Pharrell Williams - Get Lucky (Official Audio)"}
+	accepted := []string{"get lucky", "Get Lucki", "daft punk get lucky", "GET LUCKY!"}
+	for _, g := range accepted {
+		if !MatchAnswer(g, item) {
+			t.Errorf("expected %q to match", g)
+		}
+	}
+	rejected := []string{"", "daft punk", "get", "lucky star"}
+	for _, g := range rejected {
+		if MatchAnswer(g, item) {
+			t.Errorf("expected %q not to match", g)
+		}
+	}
+
+	// Short answers tolerate no typo.
+	if MatchAnswer("Abbo", PlaylistItem{Title: "ABBA - SOS"}) || !MatchAnswer("s.o.s", PlaylistItem{Title: "ABBA - SOS"}) {
+		t.Errorf("unexpected short answer matching")
+	}
+}
+
+func TestMatchAnswer_StructuredMetadata(t *testing.T) {
+	t.Parallel()
+
+	item := PlaylistItem{
+		Title:           "Official Stromae channel upload",
+		Artist:          "Stromae",
+		SongTitle:       "Alors on danse",
+		AcceptedAnswers: []string{"Alors on dance"},
+	}
+	for _, g := range []string{"alors on danse", "Stromae alors on danse", "alors on dance"} {
+		if !MatchAnswer(g, item) {
+			t.Errorf("expected %q to match", g)
+		}
+	}
+	if MatchAnswer("official stromae channel upload", item) {
+		t.Errorf("raw title should not match once the song title is set")
+	}
+}
@@ -0,0 +1,103 @@
+package namethattune
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Playlist items can define a clip window (StartMS, ClipDurationMS): playback of the item
+// starts at StartMS and the server ends the track once ClipDurationMS has been played.
+// Playback positions stay absolute (milliseconds into the video) and are clamped to the
+// window by the repo; speed bonuses are computed relative to the clip start.
+
+// MaxClipMS bounds clip starts and durations (12 hours).
+const MaxClipMS = 12 * 60 * 60 * 1000
+
+// ClipEndMS returns the absolute position where the clip ends, or 0 when it plays until
+// the end of the video.
+func (it PlaylistItem) ClipEndMS() int {
+	if it.ClipDurationMS <= 0 {
+		return 0
+	}
+	return it.StartMS + it.ClipDurationMS
+}
+
+// ClampPosition clamps an absolute playback position to the item's clip window.
+func (it PlaylistItem) ClampPosition(positionMS int) int {
+	if positionMS < it.StartMS {
+		positionMS = it.StartMS
+	}
+	if end := it.ClipEndMS(); end > 0 && positionMS > end {
+		positionMS = end
+	}
+	return positionMS
+}
+
+// CurrentPositionMS extrapolates the playback position at now: while playing, the track
+// runs from the scheduled StartAt (or UpdatedAt) onwards.
+func (p PlaybackView) CurrentPositionMS(now time.Time) int {
+	positionMS := p.PositionMS
+	if p.Paused {
+		return positionMS
+	}
+	anchor := p.UpdatedAt
+	if p.StartAt != nil && p.StartAt.After(anchor) {
+		anchor = *p.StartAt
+	}
+	if now.After(anchor) {
+		positionMS += int(now.Sub(anchor).Milliseconds())
+	}
+	return positionMS
+}
+
+// ClipOffsetMS converts an absolute position of the current track into a position
+// relative to its clip start.
+func (p PlaybackView) ClipOffsetMS(positionMS int) int {
+	if p.Track == nil {
+		return positionMS
+	}
+	return positionMS - p.Track.StartMS
+}
+
+// ClipRemaining returns how long the current track keeps playing before its clip ends.
+// ok is false when nothing is playing or the clip has no end.
+func (p PlaybackView) ClipRemaining(now time.Time) (remaining time.Duration, ok bool) {
+	if p.Paused || p.Track == nil {
+		return 0, false
+	}
+	end := p.Track.ClipEndMS()
+	if end <= 0 {
+		return 0, false
+	}
+	remaining = time.Duration(end-p.CurrentPositionMS(now)) * time.Millisecond
+	if remaining < 0 {
+		remaining = 0
+	}
+	return remaining, true
+}
+
+// currentTrackClipTx returns the clip window of the room's current track. A room without
+// a loaded playlist or with an out-of-range track index yields an unbounded window.
+func (r *Repo) currentTrackClipTx(ctx context.Context, tx pgx.Tx, roomID string) (PlaylistItem, error) {
+	const q = `
+SELECT pi.start_ms, pi.clip_duration_ms
+FROM rooms rm
+JOIN playlist_items pi ON pi.playlist_id = rm.loaded_playlist_id
+WHERE rm.id::uuid = $1
+ORDER BY pi.position ASC
+OFFSET (SELECT playback_track_index FROM rooms WHERE id::uuid = $1)
+LIMIT 1;
+`
+	var it PlaylistItem
+	if err := tx.QueryRow(ctx, q, roomID).Scan(&it.StartMS, &it.ClipDurationMS); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return PlaylistItem{}, nil
+		}
+		return PlaylistItem{}, fmt.Errorf("current track clip: %w", err)
+	}
+	return it, nil
+}
@@ -0,0 +1,68 @@
+package namethattune
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPlaylistItem_ClampPosition(t *testing.T) {
+	t.Parallel()
+
+	clip := PlaylistItem{StartMS: 30_000, ClipDurationMS: 15_000}
+	for _, tc := range []struct{ in, want int }{{0, 30_000}, {35_000, 35_000}, {60_000, 45_000}} {
+		if got := clip.ClampPosition(tc.in); got != tc.want {
+			t.Errorf("ClampPosition(%d) = %d, want %d", tc.in, got, tc.want)
+		}
+	}
+
+	open := PlaylistItem{StartMS: 10_000}
+	if got := open.ClampPosition(600_000); got != 600_000 {
+		t.Errorf("expected no upper bound without a clip duration, got %d", got)
+	}
+}
+
+func TestPlaybackView_ClipRemaining(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	startAt := now.Add(-4 * time.Second)
+	p := PlaybackView{
+		Track:      &PlaylistItem{StartMS: 30_000, ClipDurationMS: 15_000},
+		PositionMS: 30_000,
+		UpdatedAt:  now.Add(-5 * time.Second),
+		StartAt:    &startAt,
+	}
+
+	// Playing since StartAt: 4s into the 15s clip.
+	if got := p.CurrentPositionMS(now); got != 34_000 {
+		t.Fatalf("CurrentPositionMS = %d, want 34000", got)
+	}
+	if got := p.ClipOffsetMS(p.CurrentPositionMS(now)); got != 4_000 {
+		t.Fatalf("ClipOffsetMS = %d, want 4000", got)
+	}
+	if got, ok := p.ClipRemaining(now); !ok || got != 11*time.Second {
+		t.Fatalf("ClipRemaining = %s %v, want 11s", got, ok)
+	}
+
+	p.Paused = true
+	if _, ok := p.ClipRemaining(now); ok {
+		t.Fatalf("expected no remaining time while paused")
+	}
+	p.Paused = false
+	p.Track.ClipDurationMS = 0
+	if _, ok := p.ClipRemaining(now); ok {
+		t.Fatalf("expected no remaining time without a clip end")
+	}
+}
+
+func TestPlaylistItemPatch_Empty(t *testing.T) {
+	t.Parallel()
+
+	if !(PlaylistItemPatch{}).Empty() {
+		t.Fatalf("expected a zero patch to be empty")
+	}
+	start := 30_000
+	if (PlaylistItemPatch{StartMS: &start}).Empty() {
+		t.Fatalf("expected a clip-only patch not to be empty")
+	}
+}
@@ -0,0 +1,554 @@
+package namethattune
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/valentin/bes-games/backend/internal/core"
+)
+
+// ============================
+// Match history
+// ============================
+//
+// Schema expectations (see migrations/0005_matches.sql):
+// - matches(id, room_id nullable FK rooms, room_name, owner_sub nullable FK users, playlist_id, playlist_name, started_at, ended_at)
+// - match_rounds(id, match_id FK matches, round_number, track_index, playlist_item_id, title, youtube_id, started_at)
+// - match_buzzes(id, round_id FK match_rounds, player_id, user_sub, nickname, buzzed_at, correct, resolved_at)
+// - match_standings(match_id FK matches, player_id, user_sub, nickname, picture_url, score, rank)
+//
+// Recording is driven by the HTTP layer (round start, buzz, resolve, room close). The live
+// game state stays on rooms/room_players; these tables are an append-only history.
+
+// StartRound records the room's current track as a round of the open match, opening a
+// match if needed. It is idempotent: if the latest round already covers the current
+// track, nothing is written.
+func (r *Repo) StartRound(ctx context.Context, roomID string) error {
+	if roomID == "" {
+		return core.ErrInvalidInput
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("start round begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := r.currentRoundTx(ctx, tx, roomID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("start round commit: %w", err)
+	}
+	return nil
+}
+
+// RecordBuzz appends a buzz by playerID to the current round.
+func (r *Repo) RecordBuzz(ctx context.Context, roomID, playerID string) error {
+	if roomID == "" || playerID == "" {
+		return core.ErrInvalidInput
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("record buzz begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	roundID, err := r.currentRoundTx(ctx, tx, roomID)
+	if err != nil {
+		return err
+	}
+	if roundID == "" {
+		// No playlist loaded: nothing to attach the buzz to.
+		return nil
+	}
+
+	const q = `
+INSERT INTO match_buzzes (round_id, player_id, user_sub, nickname)
+SELECT $1::uuid, rp.id, rp.user_sub, rp.nickname
+FROM room_players rp
+WHERE rp.id::uuid = $2 AND rp.room_id::uuid = $3;
+`
+	ct, err := tx.Exec(ctx, q, roundID, playerID, roomID)
+	if err != nil {
+		return fmt.Errorf("record buzz: %w", err)
+	}
+	if ct.RowsAffected() == 0 {
+		return core.ErrPlayerNotFound
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("record buzz commit: %w", err)
+	}
+	return nil
+}
+
+// ResolveBuzz marks the latest unresolved buzz of playerID in the room's open match.
+func (r *Repo) ResolveBuzz(ctx context.Context, roomID, playerID string, correct bool) error {
+	if roomID == "" || playerID == "" {
+		return core.ErrInvalidInput
+	}
+
+	const q = `
+UPDATE match_buzzes
+SET correct = $3,
+    resolved_at = now()
+WHERE id = (
+  SELECT b.id
+  FROM match_buzzes b
+  JOIN match_rounds mr ON mr.id = b.round_id
+  JOIN matches m ON m.id = mr.match_id
+  WHERE m.room_id::uuid = $1
+    AND m.ended_at IS NULL
+    AND b.player_id::uuid = $2
+    AND b.correct IS NULL
+  ORDER BY b.buzzed_at DESC
+  LIMIT 1
+);
+`
+	if _, err := r.db.Exec(ctx, q, roomID, playerID, correct); err != nil {
+		return fmt.Errorf("resolve buzz: %w", err)
+	}
+	return nil
+}
+
+// FinishMatch closes the room's open match and stores the final standings.
+// Matches without any round are discarded. It is a no-op when no match is open.
+func (r *Repo) FinishMatch(ctx context.Context, roomID string) error {
+	if roomID == "" {
+		return core.ErrInvalidInput
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("finish match begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var matchID, ownerSub string
+	var rounds int
+	{
+		const q = `
+SELECT m.id::text, COALESCE(m.owner_sub, ''), (SELECT COUNT(1) FROM match_rounds mr WHERE mr.match_id = m.id)::int
+FROM matches m
+WHERE m.room_id::uuid = $1 AND m.ended_at IS NULL
+FOR UPDATE;
+`
+		err := tx.QueryRow(ctx, q, roomID).Scan(&matchID, &ownerSub, &rounds)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("finish match load: %w", err)
+		}
+	}
+
+	if rounds == 0 {
+		const q = `DELETE FROM matches WHERE id::uuid = $1;`
+		if _, err := tx.Exec(ctx, q, matchID); err != nil {
+			return fmt.Errorf("finish match discard: %w", err)
+		}
+	} else {
+		// The owner seat never scores, so it is excluded from standings. Seats removed
+		// during the match already have theirs (see saveDepartedStandingTx); the ranks
+		// are computed once every standing is in.
+		const standingsQ = `
+INSERT INTO match_standings (match_id, player_id, user_sub, nickname, picture_url, score, rank)
+SELECT $1::uuid, rp.id, rp.user_sub, rp.nickname, rp.picture_url, rp.score, 0
+FROM room_players rp
+WHERE rp.room_id = $2::uuid
+  AND ($3 = '' OR COALESCE(rp.user_sub, '') <> $3)
+ON CONFLICT (match_id, player_id) DO NOTHING;
+`
+		if _, err := tx.Exec(ctx, standingsQ, matchID, roomID, ownerSub); err != nil {
+			return fmt.Errorf("finish match standings: %w", err)
+		}
+
+		const rankQ = `
+UPDATE match_standings ms
+SET rank = ranked.rank
+FROM (
+  SELECT player_id, RANK() OVER (ORDER BY score DESC) AS rank
+  FROM match_standings
+  WHERE match_id = $1::uuid
+) ranked
+WHERE ms.match_id = $1::uuid AND ms.player_id = ranked.player_id;
+`
+		if _, err := tx.Exec(ctx, rankQ, matchID); err != nil {
+			return fmt.Errorf("finish match rank: %w", err)
+		}
+
+		const endQ = `UPDATE matches SET ended_at = now() WHERE id::uuid = $1;`
+		if _, err := tx.Exec(ctx, endQ, matchID); err != nil {
+			return fmt.Errorf("finish match end: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("finish match commit: %w", err)
+	}
+	return nil
+}
+
+// departedStandingQ keeps the standing of seats removed from a room while a match is open,
+// so kicked and expired players keep their score in the history. It reads the removed
+// seats from a "departed" relation (room_id, id, user_sub, nickname, picture_url, score)
+// defined by the caller; FinishMatch ranks them with the others.
+const departedStandingQ = `
+INSERT INTO match_standings (match_id, player_id, user_sub, nickname, picture_url, score, rank)
+SELECT m.id, d.id, d.user_sub, d.nickname, d.picture_url, d.score, 0
+FROM departed d
+JOIN matches m ON m.room_id = d.room_id AND m.ended_at IS NULL
+ON CONFLICT (match_id, player_id) DO UPDATE
+SET user_sub = EXCLUDED.user_sub,
+    nickname = EXCLUDED.nickname,
+    picture_url = EXCLUDED.picture_url,
+    score = EXCLUDED.score
+`
+
+// saveDepartedStandingTx keeps the standing of a seat about to be removed from the room.
+func (r *Repo) saveDepartedStandingTx(ctx context.Context, tx pgx.Tx, roomID, playerID string) error {
+	const q = `
+WITH departed AS (
+  SELECT rp.room_id, rp.id, rp.user_sub, rp.nickname, rp.picture_url, rp.score
+  FROM room_players rp
+  WHERE rp.id = $1::uuid AND rp.room_id = $2::uuid
+)` + departedStandingQ + `;`
+	if _, err := tx.Exec(ctx, q, playerID, roomID); err != nil {
+		return fmt.Errorf("save departed standing: %w", err)
+	}
+	return nil
+}
+
+// ListUserMatches returns matches the user hosted or played in, newest first.
+// Rounds are not included; use GetMatch for the timeline.
+func (r *Repo) ListUserMatches(ctx context.Context, sub string) ([]Match, error) {
+	if sub == "" {
+		return nil, core.ErrUnauthorized
+	}
+
+	const q = `
+SELECT m.id::text, COALESCE(m.room_id::text, ''), m.room_name, COALESCE(m.owner_sub, ''),
+       COALESCE(m.playlist_id::text, ''), m.playlist_name, m.started_at, m.ended_at,
+       (SELECT COUNT(1) FROM match_rounds mr WHERE mr.match_id = m.id)::int
+FROM matches m
+WHERE ` + matchParticipantPredicate + `
+ORDER BY m.started_at DESC
+LIMIT 100;
+`
+	rows, err := r.db.Query(ctx, q, sub)
+	if err != nil {
+		return nil, fmt.Errorf("list matches: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]Match, 0, 16)
+	index := make(map[string]int, 16)
+	for rows.Next() {
+		m, err := scanMatch(rows)
+		if err != nil {
+			return nil, fmt.Errorf("list matches scan: %w", err)
+		}
+		index[m.ID] = len(out)
+		out = append(out, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list matches rows: %w", err)
+	}
+
+	// Standings are final once the match ended; open matches have none yet.
+	ids := make([]string, 0, len(out))
+	for _, m := range out {
+		if m.EndedAt != nil {
+			ids = append(ids, m.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return out, nil
+	}
+	const standingsQ = `
+SELECT match_id::text, player_id::text, COALESCE(user_sub, ''), nickname, picture_url, score, rank
+FROM match_standings
+WHERE match_id = ANY($1::uuid[])
+ORDER BY rank ASC, nickname ASC;
+`
+	srows, err := r.db.Query(ctx, standingsQ, ids)
+	if err != nil {
+		return nil, fmt.Errorf("list matches standings: %w", err)
+	}
+	defer srows.Close()
+	for srows.Next() {
+		var matchID string
+		var st MatchStanding
+		if err := srows.Scan(&matchID, &st.PlayerID, &st.Sub, &st.Nickname, &st.PictureURL, &st.Score, &st.Rank); err != nil {
+			return nil, fmt.Errorf("list matches standings scan: %w", err)
+		}
+		if i, ok := index[matchID]; ok {
+			out[i].Standings = append(out[i].Standings, st)
+		}
+	}
+	if err := srows.Err(); err != nil {
+		return nil, fmt.Errorf("list matches standings rows: %w", err)
+	}
+	return out, nil
+}
+
+// GetMatch returns a match with its full round timeline and standings.
+// Only the host and the participants can read it.
+func (r *Repo) GetMatch(ctx context.Context, sub, matchID string) (Match, error) {
+	if sub == "" {
+		return Match{}, core.ErrUnauthorized
+	}
+	if matchID == "" {
+		return Match{}, core.ErrInvalidInput
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return Match{}, fmt.Errorf("get match begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var m Match
+	{
+		const q = `
+SELECT m.id::text, COALESCE(m.room_id::text, ''), m.room_name, COALESCE(m.owner_sub, ''),
+       COALESCE(m.playlist_id::text, ''), m.playlist_name, m.started_at, m.ended_at,
+       (SELECT COUNT(1) FROM match_rounds mr WHERE mr.match_id = m.id)::int
+FROM matches m
+WHERE m.id = $2::uuid AND ` + matchParticipantPredicate + `;
+`
+		var err error
+		m, err = scanMatch(tx.QueryRow(ctx, q, sub, matchID))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Match{}, ErrMatchNotFound
+		}
+		if err != nil {
+			return Match{}, fmt.Errorf("get match: %w", err)
+		}
+	}
+
+	// Rounds
+	roundIndex := make(map[string]int, 16)
+	{
+		const q = `
+SELECT id::text, round_number, track_index, COALESCE(playlist_item_id::text, ''), title, youtube_id, started_at
+FROM match_rounds
+WHERE match_id::uuid = $1
+ORDER BY round_number ASC;
+`
+		rows, err := tx.Query(ctx, q, m.ID)
+		if err != nil {
+			return Match{}, fmt.Errorf("get match rounds: %w", err)
+		}
+		m.Rounds = make([]MatchRound, 0, m.RoundCount)
+		for rows.Next() {
+			var id string
+			var rd MatchRound
+			if err := rows.Scan(&id, &rd.RoundNumber, &rd.TrackIndex, &rd.PlaylistItemID, &rd.Title, &rd.YouTubeID, &rd.StartedAt); err != nil {
+				rows.Close()
+				return Match{}, fmt.Errorf("get match rounds scan: %w", err)
+			}
+			rd.Buzzes = []MatchBuzz{}
+			roundIndex[id] = len(m.Rounds)
+			m.Rounds = append(m.Rounds, rd)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return Match{}, fmt.Errorf("get match rounds rows: %w", err)
+		}
+	}
+
+	// Buzzes
+	{
+		const q = `
+SELECT b.round_id::text, b.player_id::text, COALESCE(b.user_sub, ''), b.nickname, b.buzzed_at, b.correct, b.resolved_at
+FROM match_buzzes b
+JOIN match_rounds mr ON mr.id = b.round_id
+WHERE mr.match_id::uuid = $1
+ORDER BY b.buzzed_at ASC;
+`
+		rows, err := tx.Query(ctx, q, m.ID)
+		if err != nil {
+			return Match{}, fmt.Errorf("get match buzzes: %w", err)
+		}
+		for rows.Next() {
+			var roundID string
+			var b MatchBuzz
+			if err := rows.Scan(&roundID, &b.PlayerID, &b.Sub, &b.Nickname, &b.BuzzedAt, &b.Correct, &b.ResolvedAt); err != nil {
+				rows.Close()
+				return Match{}, fmt.Errorf("get match buzzes scan: %w", err)
+			}
+			if i, ok := roundIndex[roundID]; ok {
+				m.Rounds[i].Buzzes = append(m.Rounds[i].Buzzes, b)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return Match{}, fmt.Errorf("get match buzzes rows: %w", err)
+		}
+	}
+
+	// Standings, once the match ended.
+	if m.EndedAt != nil {
+		const q = `
+SELECT player_id::text, COALESCE(user_sub, ''), nickname, picture_url, score, rank
+FROM match_standings
+WHERE match_id::uuid = $1
+ORDER BY rank ASC, nickname ASC;
+`
+		rows, err := tx.Query(ctx, q, m.ID)
+		if err != nil {
+			return Match{}, fmt.Errorf("get match standings: %w", err)
+		}
+		for rows.Next() {
+			var st MatchStanding
+			if err := rows.Scan(&st.PlayerID, &st.Sub, &st.Nickname, &st.PictureURL, &st.Score, &st.Rank); err != nil {
+				rows.Close()
+				return Match{}, fmt.Errorf("get match standings scan: %w", err)
+			}
+			m.Standings = append(m.Standings, st)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return Match{}, fmt.Errorf("get match standings rows: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Match{}, fmt.Errorf("get match commit: %w", err)
+	}
+	return m, nil
+}
+
+// matchParticipantPredicate restricts matches (aliased m) to those hosted by, or played
+// by, the user bound to $1.
+const matchParticipantPredicate = `(
+  m.owner_sub = $1
+  OR EXISTS (SELECT 1 FROM match_standings ms WHERE ms.match_id = m.id AND ms.user_sub = $1)
+  OR EXISTS (
+    SELECT 1 FROM match_buzzes b JOIN match_rounds mr ON mr.id = b.round_id
+    WHERE mr.match_id = m.id AND b.user_sub = $1
+  )
+)`
+
+func scanMatch(row pgx.Row) (Match, error) {
+	var m Match
+	var endedAt *time.Time
+	if err := row.Scan(
+		&m.ID,
+		&m.RoomID,
+		&m.RoomName,
+		&m.OwnerSub,
+		&m.PlaylistID,
+		&m.PlaylistName,
+		&m.StartedAt,
+		&endedAt,
+		&m.RoundCount,
+	); err != nil {
+		return Match{}, err
+	}
+	m.EndedAt = endedAt
+	m.Standings = []MatchStanding{}
+	return m, nil
+}
+
+// currentRoundTx returns the round matching the room's current track, creating the match
+// and/or round as needed. It returns "" when the room has no playable track.
+func (r *Repo) currentRoundTx(ctx context.Context, tx pgx.Tx, roomID string) (string, error) {
+	var roomName, ownerSub string
+	var loadedPlaylistID *string
+	var trackIndex int
+	{
+		const q = `
+SELECT name, owner_sub, loaded_playlist_id::text, playback_track_index
+FROM rooms
+WHERE id::uuid = $1
+FOR UPDATE;
+`
+		err := tx.QueryRow(ctx, q, roomID).Scan(&roomName, &ownerSub, &loadedPlaylistID, &trackIndex)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", core.ErrRoomNotFound
+		}
+		if err != nil {
+			return "", fmt.Errorf("current round load room: %w", err)
+		}
+	}
+	if loadedPlaylistID == nil || *loadedPlaylistID == "" || trackIndex < 0 {
+		return "", nil
+	}
+
+	var itemID, title, youtubeID, playlistName string
+	{
+		const q = `
+SELECT pi.id::text, pi.title, pi.youtube_id, p.name
+FROM playlist_items pi
+JOIN playlists p ON p.id = pi.playlist_id
+WHERE pi.playlist_id::uuid = $1
+ORDER BY pi.position ASC
+OFFSET $2
+LIMIT 1;
+`
+		err := tx.QueryRow(ctx, q, *loadedPlaylistID, trackIndex).Scan(&itemID, &title, &youtubeID, &playlistName)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("current round load track: %w", err)
+		}
+	}
+
+	var matchID string
+	{
+		const q = `SELECT id::text FROM matches WHERE room_id::uuid = $1 AND ended_at IS NULL;`
+		err := tx.QueryRow(ctx, q, roomID).Scan(&matchID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			const ins = `
+INSERT INTO matches (room_id, room_name, owner_sub, playlist_id, playlist_name)
+VALUES ($1::uuid, $2, $3, $4::uuid, $5)
+RETURNING id::text;
+`
+			if err := tx.QueryRow(ctx, ins, roomID, roomName, ownerSub, *loadedPlaylistID, playlistName).Scan(&matchID); err != nil {
+				return "", fmt.Errorf("current round open match: %w", err)
+			}
+		} else if err != nil {
+			return "", fmt.Errorf("current round load match: %w", err)
+		}
+	}
+
+	var roundID string
+	var lastNumber int
+	{
+		const q = `
+SELECT id::text, round_number, track_index, COALESCE(playlist_item_id::text, '')
+FROM match_rounds
+WHERE match_id::uuid = $1
+ORDER BY round_number DESC
+LIMIT 1;
+`
+		var lastTrack int
+		var lastItem string
+		err := tx.QueryRow(ctx, q, matchID).Scan(&roundID, &lastNumber, &lastTrack, &lastItem)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return "", fmt.Errorf("current round load last: %w", err)
+		}
+		if err == nil && lastTrack == trackIndex && lastItem == itemID {
+			return roundID, nil
+		}
+	}
+
+	const ins = `
+INSERT INTO match_rounds (match_id, round_number, track_index, playlist_item_id, title, youtube_id)
+VALUES ($1::uuid, $2, $3, $4::uuid, $5, $6)
+RETURNING id::text;
+`
+	if err := tx.QueryRow(ctx, ins, matchID, lastNumber+1, trackIndex, itemID, title, youtubeID).Scan(&roundID); err != nil {
+		return "", fmt.Errorf("current round insert: %w", err)
+	}
+	return roundID, nil
+}
@@ -7,12 +7,16 @@ import "time"
 // ============================
 
 type Playlist struct {
-	ID        string         `json:"id"`
-	OwnerSub  string         `json:"ownerSub"`
-	Name      string         `json:"name"`
-	Items     []PlaylistItem `json:"items"`
-	CreatedAt time.Time      `json:"createdAt"`
-	UpdatedAt time.Time      `json:"updatedAt"`
+	ID         string         `json:"id"`
+	OwnerSub   string         `json:"ownerSub"`
+	Name       string         `json:"name"`
+	Visibility string         `json:"visibility"`
+	Items      []PlaylistItem `json:"items"`
+	CreatedAt  time.Time      `json:"createdAt"`
+	UpdatedAt  time.Time      `json:"updatedAt"`
+	// Role is the caller's role on the playlist (owner, editor, viewer), empty for a
+	// public or unlisted playlist read by someone else.
+	Role string `json:"role,omitempty"`
 }
 
 type PlaylistItem struct {
@@ -23,6 +27,33 @@ type PlaylistItem struct {
 	ThumbnailURL string    `json:"thumbnailUrl"`
 	DurationSec  int       `json:"durationSec"`
 	AddedAt      time.Time `json:"addedAt"`
+	// Structured metadata, parsed from the video title on add and editable by the owner.
+	Artist    string `json:"artist"`
+	SongTitle string `json:"songTitle"`
+	Year      *int   `json:"year,omitempty"`
+	// AcceptedAnswers are alternative answers accepted in typed-answer mode.
+	AcceptedAnswers []string `json:"acceptedAnswers"`
+	// Clip window: playback starts at StartMS and stops after ClipDurationMS
+	// (0 = until the end of the video).
+	StartMS        int `json:"startMs"`
+	ClipDurationMS int `json:"clipDurationMs"`
+}
+
+// PlaylistItemPatch is a partial item update; nil fields are left unchanged.
+// A Year of 0 clears the year.
+type PlaylistItemPatch struct {
+	Title           *string   `json:"title,omitempty"`
+	Artist          *string   `json:"artist,omitempty"`
+	SongTitle       *string   `json:"songTitle,omitempty"`
+	Year            *int      `json:"year,omitempty"`
+	AcceptedAnswers *[]string `json:"acceptedAnswers,omitempty"`
+	StartMS         *int      `json:"startMs,omitempty"`
+	ClipDurationMS  *int      `json:"clipDurationMs,omitempty"`
+}
+
+// Empty reports whether the patch changes nothing.
+func (p PlaylistItemPatch) Empty() bool {
+	return p == PlaylistItemPatch{}
 }
 
 // PlaylistView is the denormalized playlist payload embedded in a room snapshot.
@@ -46,11 +77,24 @@ type PlayerView struct {
 	PictureURL string `json:"pictureUrl,omitempty"`
 	Score      int    `json:"score"`
 	Connected  bool   `json:"connected"`
+	// TeamID is the player's team, if any.
+	TeamID string `json:"teamId,omitempty"`
+	// Latency is the connection's measured clock offset and round-trip time, when the
+	// client takes part in the time.sync exchange.
+	Latency *PlayerLatency `json:"latency,omitempty"`
+}
+
+// PlayerLatency is a client's clock offset (server minus client) and RTT, in milliseconds.
+type PlayerLatency struct {
+	OffsetMS int64 `json:"offsetMs"`
+	RTTMS    int64 `json:"rttMs"`
 }
 
 // PlaybackView is the client-visible playback state.
 // The "track" is resolved from the loaded playlist items.
 type PlaybackView struct {
+	// Phase is the round phase of the current track (RoundPhase*).
+	Phase      string        `json:"phase"`
 	PlaylistID string        `json:"playlistId,omitempty"`
 	TrackIndex int           `json:"trackIndex"`
 	Track      *PlaylistItem `json:"track,omitempty"`
@@ -66,6 +110,8 @@ type PlaybackView struct {
 	WaitingForReady bool `json:"waitingForReady,omitempty"`
 	// WaitingForReadyPlayers is a list of player IDs not yet ready to play.
 	WaitingForReadyPlayers []string `json:"waitingForReadyPlayers,omitempty"`
+	// RoundEndsAt is when the round timer expires, while it is running.
+	RoundEndsAt *time.Time `json:"roundEndsAt,omitempty"`
 }
 
 // RoomSnapshot is the main read model for room state (roster + loaded playlist + playback).
@@ -78,9 +124,81 @@ type RoomSnapshot struct {
 	Players     []PlayerView  `json:"players"`
 	Playlist    *PlaylistView `json:"playlist,omitempty"`
 	Playback    PlaybackView  `json:"playback"`
+	Rules       RoomRules     `json:"rules"`
+	// Teams lists the room's teams with their aggregated scores (empty unless the owner
+	// created teams).
+	Teams []TeamView `json:"teams,omitempty"`
+}
+
+// TeamView is a team of a room. Its score is the sum of its players' scores.
+type TeamView struct {
+	TeamID    string   `json:"teamId"`
+	Name      string   `json:"name"`
+	Score     int      `json:"score"`
+	PlayerIDs []string `json:"playerIds"`
+}
+
+// ============================
+// Match history
+// ============================
+
+// Match is a persisted game played in a room, from the first round until the room
+// closed (or another playlist was loaded).
+type Match struct {
+	ID           string          `json:"id"`
+	RoomID       string          `json:"roomId,omitempty"`
+	RoomName     string          `json:"roomName"`
+	OwnerSub     string          `json:"ownerSub"`
+	PlaylistID   string          `json:"playlistId,omitempty"`
+	PlaylistName string          `json:"playlistName"`
+	StartedAt    time.Time       `json:"startedAt"`
+	EndedAt      *time.Time      `json:"endedAt,omitempty"`
+	RoundCount   int             `json:"roundCount"`
+	Standings    []MatchStanding `json:"standings"`
+	// Rounds is only populated when fetching a single match (timeline).
+	Rounds []MatchRound `json:"rounds,omitempty"`
+}
+
+// MatchRound is one track played during a match.
+type MatchRound struct {
+	RoundNumber    int         `json:"roundNumber"`
+	TrackIndex     int         `json:"trackIndex"`
+	PlaylistItemID string      `json:"playlistItemId,omitempty"`
+	Title          string      `json:"title"`
+	YouTubeID      string      `json:"youTubeID"`
+	StartedAt      time.Time   `json:"startedAt"`
+	Buzzes         []MatchBuzz `json:"buzzes"`
+}
+
+// MatchBuzz records who buzzed during a round and how the owner resolved it.
+// Correct is nil while the buzz is unresolved.
+type MatchBuzz struct {
+	PlayerID   string     `json:"playerId"`
+	Sub        string     `json:"sub,omitempty"`
+	Nickname   string     `json:"nickname"`
+	BuzzedAt   time.Time  `json:"buzzedAt"`
+	Correct    *bool      `json:"correct,omitempty"`
+	ResolvedAt *time.Time `json:"resolvedAt,omitempty"`
+}
+
+// MatchStanding is a player's final score and rank (1 = winner, ties share a rank).
+type MatchStanding struct {
+	PlayerID   string `json:"playerId"`
+	Sub        string `json:"sub,omitempty"`
+	Nickname   string `json:"nickname"`
+	PictureURL string `json:"pictureUrl,omitempty"`
+	Score      int    `json:"score"`
+	Rank       int    `json:"rank"`
 }
 
-var ErrPlaylistNotFound = errorString("playlist not found")
+var (
+	ErrPlaylistNotFound       = errorString("playlist not found")
+	ErrMatchNotFound          = errorString("match not found")
+	ErrPlaylistSourceNotFound = errorString("remote playlist not found")
+	ErrPlaylistForbidden      = errorString("playlist access denied")
+	ErrUserNotFound           = errorString("user not found")
+	ErrTeamNotFound           = errorString("team not found")
+)
 
 // errorString is a tiny internal error type to avoid importing "errors" here.
 // It behaves like errors.New(...) but keeps this file dependency-light.
@@ -0,0 +1,365 @@
+package namethattune
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/valentin/bes-games/backend/internal/core"
+)
+
+// Bulk playlist operations. Items keep their ID and metadata: they are renumbered or
+// re-parented, never deleted and re-added.
+//
+// playlist_items(playlist_id, position) is unique and not deferrable, so positions are
+// rewritten in two phases: first moved out of the way (negated), then set to their final,
+// gap-free 0..n-1 value.
+
+// maxBulkItems caps the number of item IDs accepted by a bulk operation.
+const maxBulkItems = MaxPlaylistFileItems
+
+// renumberPlaylistItemsTx sets the positions of a playlist's items to the order of ids,
+// which must list every item of the playlist exactly once.
+func renumberPlaylistItemsTx(ctx context.Context, tx pgx.Tx, playlistID string, ids []string) error {
+	const parkQ = `UPDATE playlist_items SET position = -1 - position WHERE playlist_id::uuid = $1;`
+	if _, err := tx.Exec(ctx, parkQ, playlistID); err != nil {
+		return fmt.Errorf("renumber playlist items park: %w", err)
+	}
+
+	const q = `
+UPDATE playlist_items pi
+SET position = v.ord - 1
+FROM unnest($2::uuid[]) WITH ORDINALITY AS v(id, ord)
+WHERE pi.id = v.id AND pi.playlist_id::uuid = $1;
+`
+	if _, err := tx.Exec(ctx, q, playlistID, ids); err != nil {
+		return fmt.Errorf("renumber playlist items: %w", err)
+	}
+	return nil
+}
+
+// compactPlaylistItemsTx closes the position gaps left by removed items.
+func compactPlaylistItemsTx(ctx context.Context, tx pgx.Tx, playlistID string) error {
+	const q = `SELECT id::text FROM playlist_items WHERE playlist_id::uuid = $1 ORDER BY position ASC;`
+	rows, err := tx.Query(ctx, q, playlistID)
+	if err != nil {
+		return fmt.Errorf("compact playlist items: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return fmt.Errorf("compact playlist items scan: %w", err)
+	}
+	return renumberPlaylistItemsTx(ctx, tx, playlistID, ids)
+}
+
+// itemIDSet validates a list of item IDs (non-empty, bounded, no duplicates) against a
+// playlist's items. It returns ErrPlaylistNotFound if an ID is not in the playlist.
+func itemIDSet(items []PlaylistItem, ids []string) (map[string]bool, error) {
+	if len(ids) == 0 || len(ids) > maxBulkItems {
+		return nil, core.ErrInvalidInput
+	}
+	present := make(map[string]bool, len(items))
+	for _, it := range items {
+		present[it.ID] = true
+	}
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if set[id] {
+			return nil, core.ErrInvalidInput
+		}
+		if !present[id] {
+			return nil, ErrPlaylistNotFound
+		}
+		set[id] = true
+	}
+	return set, nil
+}
+
+// finishPlaylistTx reloads a playlist's items and bumps its updated_at.
+func (r *Repo) finishPlaylistTx(ctx context.Context, tx pgx.Tx, pl Playlist) (Playlist, error) {
+	const touchQ = `UPDATE playlists SET name = name WHERE id::uuid = $1 RETURNING updated_at;`
+	if err := tx.QueryRow(ctx, touchQ, pl.ID).Scan(&pl.UpdatedAt); err != nil {
+		return Playlist{}, fmt.Errorf("touch playlist: %w", err)
+	}
+	items, err := r.listPlaylistItemsTx(ctx, tx, pl.ID)
+	if err != nil {
+		return Playlist{}, err
+	}
+	pl.Items = items
+	return pl, nil
+}
+
+// ReorderPlaylistItems sets the order of a playlist's items. itemIDs must list every item
+// exactly once.
+func (r *Repo) ReorderPlaylistItems(ctx context.Context, sub, playlistID string, itemIDs []string) (Playlist, error) {
+	if sub == "" {
+		return Playlist{}, core.ErrUnauthorized
+	}
+	if playlistID == "" {
+		return Playlist{}, core.ErrInvalidInput
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return Playlist{}, fmt.Errorf("reorder playlist items begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	pl, err := r.playlistEditAccessTx(ctx, tx, playlistID, sub, true)
+	if err != nil {
+		return Playlist{}, err
+	}
+	items, err := r.listPlaylistItemsTx(ctx, tx, playlistID)
+	if err != nil {
+		return Playlist{}, err
+	}
+	if len(itemIDs) != len(items) {
+		return Playlist{}, core.ErrInvalidInput
+	}
+	if _, err := itemIDSet(items, itemIDs); err != nil {
+		return Playlist{}, err
+	}
+
+	if err := renumberPlaylistItemsTx(ctx, tx, playlistID, itemIDs); err != nil {
+		return Playlist{}, err
+	}
+	pl, err = r.finishPlaylistTx(ctx, tx, pl)
+	if err != nil {
+		return Playlist{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Playlist{}, fmt.Errorf("reorder playlist items commit: %w", err)
+	}
+	return pl, nil
+}
+
+// ShufflePlaylistItems randomizes and persists the order of a playlist's items.
+func (r *Repo) ShufflePlaylistItems(ctx context.Context, sub, playlistID string) (Playlist, error) {
+	if sub == "" {
+		return Playlist{}, core.ErrUnauthorized
+	}
+	if playlistID == "" {
+		return Playlist{}, core.ErrInvalidInput
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return Playlist{}, fmt.Errorf("shuffle playlist items begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	pl, err := r.playlistEditAccessTx(ctx, tx, playlistID, sub, true)
+	if err != nil {
+		return Playlist{}, err
+	}
+	items, err := r.listPlaylistItemsTx(ctx, tx, playlistID)
+	if err != nil {
+		return Playlist{}, err
+	}
+
+	ids := make([]string, len(items))
+	for i, it := range items {
+		ids[i] = it.ID
+	}
+	rand.Shuffle(len(ids), func(i, j int) { ids[i], ids[j] = ids[j], ids[i] })
+
+	if err := renumberPlaylistItemsTx(ctx, tx, playlistID, ids); err != nil {
+		return Playlist{}, err
+	}
+	pl, err = r.finishPlaylistTx(ctx, tx, pl)
+	if err != nil {
+		return Playlist{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Playlist{}, fmt.Errorf("shuffle playlist items commit: %w", err)
+	}
+	return pl, nil
+}
+
+// DeletePlaylistItems removes several items at once and compacts the remaining positions.
+func (r *Repo) DeletePlaylistItems(ctx context.Context, sub, playlistID string, itemIDs []string) (Playlist, error) {
+	if sub == "" {
+		return Playlist{}, core.ErrUnauthorized
+	}
+	if playlistID == "" {
+		return Playlist{}, core.ErrInvalidInput
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return Playlist{}, fmt.Errorf("delete playlist items begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	pl, err := r.playlistEditAccessTx(ctx, tx, playlistID, sub, true)
+	if err != nil {
+		return Playlist{}, err
+	}
+	items, err := r.listPlaylistItemsTx(ctx, tx, playlistID)
+	if err != nil {
+		return Playlist{}, err
+	}
+	if _, err := itemIDSet(items, itemIDs); err != nil {
+		return Playlist{}, err
+	}
+
+	const q = `DELETE FROM playlist_items WHERE playlist_id::uuid = $1 AND id = ANY($2::uuid[]);`
+	if _, err := tx.Exec(ctx, q, playlistID, itemIDs); err != nil {
+		return Playlist{}, fmt.Errorf("delete playlist items: %w", err)
+	}
+	if err := compactPlaylistItemsTx(ctx, tx, playlistID); err != nil {
+		return Playlist{}, err
+	}
+	pl, err = r.finishPlaylistTx(ctx, tx, pl)
+	if err != nil {
+		return Playlist{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Playlist{}, fmt.Errorf("delete playlist items commit: %w", err)
+	}
+	return pl, nil
+}
+
+// MovePlaylistItems moves items to the end of another playlist, keeping their relative
+// order and metadata. The caller must be able to edit both playlists.
+func (r *Repo) MovePlaylistItems(ctx context.Context, sub, fromID, toID string, itemIDs []string) (from, to Playlist, err error) {
+	if sub == "" {
+		return Playlist{}, Playlist{}, core.ErrUnauthorized
+	}
+	if fromID == "" || toID == "" || fromID == toID {
+		return Playlist{}, Playlist{}, core.ErrInvalidInput
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return Playlist{}, Playlist{}, fmt.Errorf("move playlist items begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// Lock both playlists in a stable order so concurrent moves cannot deadlock.
+	locked := map[string]Playlist{}
+	order := []string{fromID, toID}
+	sort.Strings(order)
+	for _, id := range order {
+		pl, err := r.playlistEditAccessTx(ctx, tx, id, sub, true)
+		if err != nil {
+			return Playlist{}, Playlist{}, err
+		}
+		locked[id] = pl
+	}
+	from, to = locked[fromID], locked[toID]
+
+	items, err := r.listPlaylistItemsTx(ctx, tx, fromID)
+	if err != nil {
+		return Playlist{}, Playlist{}, err
+	}
+	set, err := itemIDSet(items, itemIDs)
+	if err != nil {
+		return Playlist{}, Playlist{}, err
+	}
+	moved := make([]string, 0, len(set))
+	for _, it := range items {
+		if set[it.ID] {
+			moved = append(moved, it.ID)
+		}
+	}
+
+	const q = `
+WITH base AS (
+  SELECT COALESCE(MAX(position), -1) + 1 AS pos FROM playlist_items WHERE playlist_id::uuid = $2
+)
+UPDATE playlist_items pi
+SET playlist_id = $2::uuid,
+    position = base.pos + v.ord - 1
+FROM base, unnest($3::uuid[]) WITH ORDINALITY AS v(id, ord)
+WHERE pi.id = v.id AND pi.playlist_id::uuid = $1;
+`
+	if _, err := tx.Exec(ctx, q, fromID, toID, moved); err != nil {
+		return Playlist{}, Playlist{}, fmt.Errorf("move playlist items: %w", err)
+	}
+	if err := compactPlaylistItemsTx(ctx, tx, fromID); err != nil {
+		return Playlist{}, Playlist{}, err
+	}
+
+	if from, err = r.finishPlaylistTx(ctx, tx, from); err != nil {
+		return Playlist{}, Playlist{}, err
+	}
+	if to, err = r.finishPlaylistTx(ctx, tx, to); err != nil {
+		return Playlist{}, Playlist{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Playlist{}, Playlist{}, fmt.Errorf("move playlist items commit: %w", err)
+	}
+	return from, to, nil
+}
+
+// DuplicatePlaylist copies a playlist the caller can read into a new private playlist they
+// own. name defaults to "<original> (copy)".
+func (r *Repo) DuplicatePlaylist(ctx context.Context, sub, playlistID, name string) (Playlist, error) {
+	if sub == "" {
+		return Playlist{}, core.ErrUnauthorized
+	}
+	if playlistID == "" {
+		return Playlist{}, core.ErrInvalidInput
+	}
+
+	if err := r.ensureUserExists(ctx, sub); err != nil {
+		return Playlist{}, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return Playlist{}, fmt.Errorf("duplicate playlist begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	src, err := r.playlistAccessTx(ctx, tx, playlistID, sub, false)
+	if err != nil {
+		return Playlist{}, err
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = src.Name + " (copy)"
+	}
+
+	var pl Playlist
+	{
+		const q = `
+INSERT INTO playlists (owner_sub, name, deleted_at)
+VALUES ($1, $2, NULL)
+RETURNING id::text, owner_sub, name, visibility, created_at, updated_at;
+`
+		if err := tx.QueryRow(ctx, q, sub, name).Scan(&pl.ID, &pl.OwnerSub, &pl.Name, &pl.Visibility, &pl.CreatedAt, &pl.UpdatedAt); err != nil {
+			return Playlist{}, fmt.Errorf("duplicate playlist create: %w", err)
+		}
+		pl.Role = PlaylistRoleOwner
+	}
+
+	const q = `
+INSERT INTO playlist_items (playlist_id, position, title, youtube_url, youtube_id, thumbnail_url, duration_sec, artist, song_title, year, accepted_answers, start_ms, clip_duration_ms)
+SELECT $2::uuid, position, title, youtube_url, youtube_id, thumbnail_url, duration_sec, artist, song_title, year, accepted_answers, start_ms, clip_duration_ms
+FROM playlist_items
+WHERE playlist_id::uuid = $1;
+`
+	if _, err := tx.Exec(ctx, q, playlistID, pl.ID); err != nil {
+		return Playlist{}, fmt.Errorf("duplicate playlist items: %w", err)
+	}
+
+	items, err := r.listPlaylistItemsTx(ctx, tx, pl.ID)
+	if err != nil {
+		return Playlist{}, err
+	}
+	pl.Items = items
+
+	if err := tx.Commit(ctx); err != nil {
+		return Playlist{}, fmt.Errorf("duplicate playlist commit: %w", err)
+	}
+	return pl, nil
+}
@@ -0,0 +1,313 @@
+package namethattune
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/valentin/bes-games/backend/internal/core"
+)
+
+// Playlists can be exported to (and imported from) a portable file, to share them between
+// hosts or keep backups outside the database. Two formats are supported:
+//
+//   - JSON: a PlaylistFile document, versioned by PlaylistFileVersion.
+//   - CSV: one item per row under a header row (playlistCSVHeader). Columns are matched by
+//     name, so extra or reordered columns are fine; accepted answers are "|"-separated.
+
+// PlaylistFileVersion is the current playlist file schema version.
+const PlaylistFileVersion = 1
+
+// MaxPlaylistFileItems caps the number of items a playlist file can carry.
+const MaxPlaylistFileItems = 1000
+
+// PlaylistFile is the portable JSON representation of a playlist.
+type PlaylistFile struct {
+	Version    int                `json:"version"`
+	Name       string             `json:"name"`
+	ExportedAt time.Time          `json:"exportedAt"`
+	Items      []PlaylistFileItem `json:"items"`
+}
+
+// PlaylistFileItem is a playlist item in a PlaylistFile. Either YouTubeURL or YouTubeID
+// must be set on import.
+type PlaylistFileItem struct {
+	Title           string   `json:"title"`
+	YouTubeURL      string   `json:"youtubeUrl,omitempty"`
+	YouTubeID       string   `json:"youtubeId,omitempty"`
+	ThumbnailURL    string   `json:"thumbnailUrl,omitempty"`
+	DurationSec     int      `json:"durationSec,omitempty"`
+	Artist          string   `json:"artist,omitempty"`
+	SongTitle       string   `json:"songTitle,omitempty"`
+	Year            *int     `json:"year,omitempty"`
+	AcceptedAnswers []string `json:"acceptedAnswers,omitempty"`
+	StartMS         int      `json:"startMs,omitempty"`
+	ClipDurationMS  int      `json:"clipDurationMs,omitempty"`
+}
+
+var playlistCSVHeader = []string{"title", "youtube_url", "youtube_id", "thumbnail_url", "duration_sec", "artist", "song_title", "year", "accepted_answers", "start_ms", "clip_duration_ms"}
+
+// NewPlaylistFile converts a playlist (with its items) to its portable form.
+func NewPlaylistFile(pl Playlist, now time.Time) PlaylistFile {
+	items := make([]PlaylistFileItem, 0, len(pl.Items))
+	for _, it := range pl.Items {
+		items = append(items, PlaylistFileItem{
+			Title:           it.Title,
+			YouTubeURL:      it.YouTubeURL,
+			YouTubeID:       it.YouTubeID,
+			ThumbnailURL:    it.ThumbnailURL,
+			DurationSec:     it.DurationSec,
+			Artist:          it.Artist,
+			SongTitle:       it.SongTitle,
+			Year:            it.Year,
+			AcceptedAnswers: it.AcceptedAnswers,
+			StartMS:         it.StartMS,
+			ClipDurationMS:  it.ClipDurationMS,
+		})
+	}
+	return PlaylistFile{
+		Version:    PlaylistFileVersion,
+		Name:       pl.Name,
+		ExportedAt: now.UTC(),
+		Items:      items,
+	}
+}
+
+// WritePlaylistCSV writes the items of f as CSV, header first.
+func WritePlaylistCSV(w io.Writer, f PlaylistFile) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(playlistCSVHeader); err != nil {
+		return err
+	}
+	for _, it := range f.Items {
+		year := ""
+		if it.Year != nil {
+			year = strconv.Itoa(*it.Year)
+		}
+		row := []string{it.Title, it.YouTubeURL, it.YouTubeID, it.ThumbnailURL, csvInt(it.DurationSec), it.Artist, it.SongTitle, year, strings.Join(it.AcceptedAnswers, "|"), csvInt(it.StartMS), csvInt(it.ClipDurationMS)}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvInt formats a positive number, leaving zero (unset) cells empty.
+func csvInt(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// ParsePlaylistJSON decodes a JSON playlist file. Files from a newer schema version are
+// rejected.
+func ParsePlaylistJSON(raw []byte) (PlaylistFile, error) {
+	var f PlaylistFile
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&f); err != nil {
+		return PlaylistFile{}, fmt.Errorf("%w: invalid playlist json", core.ErrInvalidInput)
+	}
+	if f.Version < 1 || f.Version > PlaylistFileVersion {
+		return PlaylistFile{}, fmt.Errorf("%w: unsupported playlist file version %d", core.ErrInvalidInput, f.Version)
+	}
+	return f, nil
+}
+
+// ParsePlaylistCSV decodes a CSV playlist file. The name is not part of the CSV format.
+func ParsePlaylistCSV(r io.Reader) (PlaylistFile, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return PlaylistFile{}, fmt.Errorf("%w: missing csv header", core.ErrInvalidInput)
+	}
+	cols := make(map[string]int, len(header))
+	for i, h := range header {
+		cols[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))] = i
+	}
+	if _, ok := cols["youtube_url"]; !ok {
+		if _, ok := cols["youtube_id"]; !ok {
+			return PlaylistFile{}, fmt.Errorf("%w: csv needs a youtube_url or youtube_id column", core.ErrInvalidInput)
+		}
+	}
+
+	f := PlaylistFile{Version: PlaylistFileVersion, Items: []PlaylistFileItem{}}
+	for line := 2; ; line++ {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return PlaylistFile{}, fmt.Errorf("%w: csv line %d: %s", core.ErrInvalidInput, line, err.Error())
+		}
+		get := func(name string) string {
+			if i, ok := cols[name]; ok && i < len(rec) {
+				return strings.TrimSpace(rec[i])
+			}
+			return ""
+		}
+
+		it := PlaylistFileItem{
+			Title:        get("title"),
+			YouTubeURL:   get("youtube_url"),
+			YouTubeID:    get("youtube_id"),
+			ThumbnailURL: get("thumbnail_url"),
+			Artist:       get("artist"),
+			SongTitle:    get("song_title"),
+		}
+		if it.Title == "" && it.YouTubeURL == "" && it.YouTubeID == "" {
+			continue // blank line
+		}
+		for _, col := range []struct {
+			name string
+			dst  *int
+		}{{"duration_sec", &it.DurationSec}, {"start_ms", &it.StartMS}, {"clip_duration_ms", &it.ClipDurationMS}} {
+			if v := get(col.name); v != "" {
+				n, err := strconv.Atoi(v)
+				if err != nil {
+					return PlaylistFile{}, fmt.Errorf("%w: csv line %d: invalid %s", core.ErrInvalidInput, line, col.name)
+				}
+				*col.dst = n
+			}
+		}
+		if v := get("year"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return PlaylistFile{}, fmt.Errorf("%w: csv line %d: invalid year", core.ErrInvalidInput, line)
+			}
+			it.Year = &n
+		}
+		if v := get("accepted_answers"); v != "" {
+			for _, a := range strings.Split(v, "|") {
+				if a = strings.TrimSpace(a); a != "" {
+					it.AcceptedAnswers = append(it.AcceptedAnswers, a)
+				}
+			}
+		}
+		f.Items = append(f.Items, it)
+	}
+	return f, nil
+}
+
+// normalizePlaylistFileItem validates an imported item and fills its YouTube ID and URL.
+func normalizePlaylistFileItem(it PlaylistFileItem) (PlaylistFileItem, error) {
+	it.Title = strings.TrimSpace(it.Title)
+	it.YouTubeURL = strings.TrimSpace(it.YouTubeURL)
+	it.YouTubeID = strings.TrimSpace(it.YouTubeID)
+
+	if it.YouTubeURL == "" && it.YouTubeID != "" {
+		it.YouTubeURL = "https://www.youtube.com/watch?v=" + it.YouTubeID
+	}
+	yid, err := ExtractYouTubeID(it.YouTubeURL)
+	if err != nil {
+		return PlaylistFileItem{}, err
+	}
+	if it.YouTubeID != "" && it.YouTubeID != yid {
+		return PlaylistFileItem{}, fmt.Errorf("youtube id does not match url")
+	}
+	it.YouTubeID = yid
+
+	if it.Title == "" {
+		it.Title = "Unknown title"
+	}
+	if it.Artist == "" && it.SongTitle == "" {
+		it.Artist, it.SongTitle = ParseVideoTitle(it.Title)
+	}
+	if it.DurationSec < 0 {
+		return PlaylistFileItem{}, fmt.Errorf("invalid duration")
+	}
+	if it.StartMS < 0 || it.StartMS > MaxClipMS || it.ClipDurationMS < 0 || it.ClipDurationMS > MaxClipMS {
+		return PlaylistFileItem{}, fmt.Errorf("invalid clip window")
+	}
+	if it.Year != nil && (*it.Year < 1000 || *it.Year > 9999) {
+		return PlaylistFileItem{}, fmt.Errorf("invalid year")
+	}
+	if len(it.AcceptedAnswers) > maxAcceptedAnswers {
+		return PlaylistFileItem{}, fmt.Errorf("too many accepted answers")
+	}
+	if it.AcceptedAnswers == nil {
+		it.AcceptedAnswers = []string{}
+	}
+	return it, nil
+}
+
+// CreatePlaylistFromFile creates a new playlist owned by ownerSub from a playlist file.
+// Every item is validated first; the playlist and its items are then inserted in a single
+// transaction, so an import either fully succeeds or leaves nothing behind.
+func (r *Repo) CreatePlaylistFromFile(ctx context.Context, ownerSub string, f PlaylistFile) (Playlist, error) {
+	if ownerSub == "" {
+		return Playlist{}, core.ErrUnauthorized
+	}
+	name := strings.TrimSpace(f.Name)
+	if name == "" || len(f.Items) > MaxPlaylistFileItems {
+		return Playlist{}, core.ErrInvalidInput
+	}
+
+	items := make([]PlaylistFileItem, 0, len(f.Items))
+	for i, it := range f.Items {
+		it, err := normalizePlaylistFileItem(it)
+		if err != nil {
+			return Playlist{}, fmt.Errorf("%w: item %d: %s", core.ErrInvalidInput, i+1, err.Error())
+		}
+		items = append(items, it)
+	}
+
+	if err := r.ensureUserExists(ctx, ownerSub); err != nil {
+		return Playlist{}, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return Playlist{}, fmt.Errorf("import playlist begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var pl Playlist
+	{
+		const q = `
+INSERT INTO playlists (owner_sub, name, deleted_at)
+VALUES ($1, $2, NULL)
+RETURNING id::text, owner_sub, name, visibility, created_at, updated_at;
+`
+		if err := tx.QueryRow(ctx, q, ownerSub, name).Scan(&pl.ID, &pl.OwnerSub, &pl.Name, &pl.Visibility, &pl.CreatedAt, &pl.UpdatedAt); err != nil {
+			return Playlist{}, fmt.Errorf("import playlist create: %w", err)
+		}
+		pl.Role = PlaylistRoleOwner
+	}
+
+	const q = `
+INSERT INTO playlist_items (playlist_id, position, title, youtube_url, youtube_id, thumbnail_url, duration_sec, artist, song_title, year, accepted_answers, start_ms, clip_duration_ms)
+VALUES ($1::uuid, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13);
+`
+	batch := &pgx.Batch{}
+	for i, it := range items {
+		batch.Queue(q, pl.ID, i, it.Title, it.YouTubeURL, it.YouTubeID, it.ThumbnailURL, it.DurationSec, it.Artist, it.SongTitle, it.Year, it.AcceptedAnswers, it.StartMS, it.ClipDurationMS)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return Playlist{}, fmt.Errorf("import playlist items: %w", err)
+	}
+
+	listed, err := r.listPlaylistItemsTx(ctx, tx, pl.ID)
+	if err != nil {
+		return Playlist{}, err
+	}
+	pl.Items = listed
+
+	if err := tx.Commit(ctx); err != nil {
+		return Playlist{}, fmt.Errorf("import playlist commit: %w", err)
+	}
+	return pl, nil
+}
@@ -0,0 +1,88 @@
+package namethattune
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/valentin/bes-games/backend/internal/core"
+)
+
+func TestPlaylistCSV_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	year := 1975
+	f := NewPlaylistFile(Playlist{Name: "Hits", Items: []PlaylistItem{
+		{Title: "Queen - Bohemian Rhapsody", YouTubeURL: "https://www.youtube.com/watch?v=fJ9rUzIMcZQ", YouTubeID: "fJ9rUzIMcZQ", Artist: "Queen", SongTitle: "Bohemian Rhapsody", Year: &year, AcceptedAnswers: []string{"Bohemian", "Rhapsody, Bohemian"}, StartMS: 45_000, ClipDurationMS: 20_000},
+		{Title: "ABBA - SOS", YouTubeURL: "https://youtu.be/cvChjHcABPA", YouTubeID: "cvChjHcABPA", DurationSec: 200},
+	}}, time.Now())
+
+	var buf bytes.Buffer
+	if err := WritePlaylistCSV(&buf, f); err != nil {
+		t.Fatalf("WritePlaylistCSV: %v", err)
+	}
+	got, err := ParsePlaylistCSV(&buf)
+	if err != nil {
+		t.Fatalf("ParsePlaylistCSV: %v", err)
+	}
+	if !reflect.DeepEqual(got.Items, f.Items) {
+		t.Fatalf("round trip mismatch:\n got %+v\nwant %+v", got.Items, f.Items)
+	}
+}
+
+func TestParsePlaylistCSV_ReorderedColumnsAndErrors(t *testing.T) {
+	t.Parallel()
+
+	got, err := ParsePlaylistCSV(strings.NewReader("youtube_id,title\ndQw4w9WgXcQ,Never Gonna Give You Up\n\n"))
+	if err != nil {
+		t.Fatalf("ParsePlaylistCSV: %v", err)
+	}
+	if len(got.Items) != 1 || got.Items[0].YouTubeID != "dQw4w9WgXcQ" || got.Items[0].Title != "Never Gonna Give You Up" {
+		t.Fatalf("unexpected items: %+v", got.Items)
+	}
+
+	for _, in := range []string{"", "title,artist\nfoo,bar\n", "youtube_id,year\ndQw4w9WgXcQ,nineteen\n"} {
+		if _, err := ParsePlaylistCSV(strings.NewReader(in)); !errors.Is(err, core.ErrInvalidInput) {
+			t.Errorf("ParsePlaylistCSV(%q): expected ErrInvalidInput, got %v", in, err)
+		}
+	}
+}
+
+func TestParsePlaylistJSON_Version(t *testing.T) {
+	t.Parallel()
+
+	if _, err := ParsePlaylistJSON([]byte(`{"version":1,"name":"Hits","items":[{"title":"x","youtubeId":"dQw4w9WgXcQ"}]}`)); err != nil {
+		t.Fatalf("expected v1 file to parse: %v", err)
+	}
+	for _, raw := range []string{`{"version":2,"name":"Hits","items":[]}`, `{"name":"Hits","items":[]}`, `{"version":1,"nope":true}`} {
+		if _, err := ParsePlaylistJSON([]byte(raw)); !errors.Is(err, core.ErrInvalidInput) {
+			t.Errorf("ParsePlaylistJSON(%s): expected ErrInvalidInput, got %v", raw, err)
+		}
+	}
+}
+
+func TestNormalizePlaylistFileItem(t *testing.T) {
+	t.Parallel()
+
+	it, err := normalizePlaylistFileItem(PlaylistFileItem{Title: "Queen - Bohemian Rhapsody", YouTubeID: "fJ9rUzIMcZQ"})
+	if err != nil {
+		t.Fatalf("normalize: %v", err)
+	}
+	if it.YouTubeURL != "https://www.youtube.com/watch?v=fJ9rUzIMcZQ" || it.Artist != "Queen" || it.SongTitle != "Bohemian Rhapsody" {
+		t.Fatalf("unexpected normalized item: %+v", it)
+	}
+
+	bad := []PlaylistFileItem{
+		{Title: "no video"},
+		{Title: "bad id", YouTubeID: "!!"},
+		{Title: "mismatch", YouTubeURL: "https://youtu.be/fJ9rUzIMcZQ", YouTubeID: "dQw4w9WgXcQ"},
+	}
+	for _, b := range bad {
+		if _, err := normalizePlaylistFileItem(b); err == nil {
+			t.Errorf("expected %+v to be rejected", b)
+		}
+	}
+}
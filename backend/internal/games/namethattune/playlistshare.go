@@ -0,0 +1,334 @@
+package namethattune
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/valentin/bes-games/backend/internal/core"
+)
+
+// Playlist visibility.
+const (
+	// PlaylistVisibilityPrivate: owner and collaborators only.
+	PlaylistVisibilityPrivate = "private"
+	// PlaylistVisibilityUnlisted: readable by anyone with the playlist ID.
+	PlaylistVisibilityUnlisted = "unlisted"
+	// PlaylistVisibilityPublic: unlisted, plus listed by DiscoverPlaylists.
+	PlaylistVisibilityPublic = "public"
+)
+
+// Playlist roles. Editors can rename the playlist and edit its items; viewers can only read
+// it. Visibility and collaborators are managed by the owner.
+const (
+	PlaylistRoleOwner  = "owner"
+	PlaylistRoleEditor = "editor"
+	PlaylistRoleViewer = "viewer"
+)
+
+// PlaylistCollaborator is a user the owner shared a playlist with.
+type PlaylistCollaborator struct {
+	UserSub    string    `json:"userSub"`
+	Nickname   string    `json:"nickname"`
+	PictureURL string    `json:"pictureUrl,omitempty"`
+	Role       string    `json:"role"`
+	AddedAt    time.Time `json:"addedAt"`
+}
+
+// PublicPlaylist is a playlist listed by DiscoverPlaylists.
+type PublicPlaylist struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	OwnerNickname string    `json:"ownerNickname"`
+	ItemCount     int       `json:"itemCount"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+func isPlaylistVisibility(v string) bool {
+	return v == PlaylistVisibilityPrivate || v == PlaylistVisibilityUnlisted || v == PlaylistVisibilityPublic
+}
+
+func canReadPlaylist(pl Playlist) bool {
+	return pl.Role != "" || pl.Visibility != PlaylistVisibilityPrivate
+}
+
+func canEditPlaylist(pl Playlist) bool {
+	return pl.Role == PlaylistRoleOwner || pl.Role == PlaylistRoleEditor
+}
+
+// playlistAccessTx loads a live playlist (without items) along with sub's role on it.
+// Playlists sub cannot read are reported as ErrPlaylistNotFound so their existence does not
+// leak. lock takes a row lock, for callers about to change the playlist's items.
+func (r *Repo) playlistAccessTx(ctx context.Context, tx pgx.Tx, playlistID, sub string, lock bool) (Playlist, error) {
+	q := `
+SELECT p.id::text, p.owner_sub, p.name, p.visibility, p.created_at, p.updated_at,
+       CASE WHEN p.owner_sub = $2 THEN 'owner' ELSE COALESCE(pc.role, '') END
+FROM playlists p
+LEFT JOIN playlist_collaborators pc ON pc.playlist_id = p.id AND pc.user_sub = $2
+WHERE p.id::uuid = $1 AND p.deleted_at IS NULL`
+	if lock {
+		q += `
+FOR UPDATE OF p`
+	}
+
+	var pl Playlist
+	err := tx.QueryRow(ctx, q, playlistID, sub).Scan(&pl.ID, &pl.OwnerSub, &pl.Name, &pl.Visibility, &pl.CreatedAt, &pl.UpdatedAt, &pl.Role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Playlist{}, ErrPlaylistNotFound
+	}
+	if err != nil {
+		return Playlist{}, fmt.Errorf("playlist access: %w", err)
+	}
+	if !canReadPlaylist(pl) {
+		return Playlist{}, ErrPlaylistNotFound
+	}
+	pl.Items = []PlaylistItem{}
+	return pl, nil
+}
+
+// playlistEditAccessTx is playlistAccessTx for writes: readers without edit rights get
+// ErrPlaylistForbidden.
+func (r *Repo) playlistEditAccessTx(ctx context.Context, tx pgx.Tx, playlistID, sub string, lock bool) (Playlist, error) {
+	pl, err := r.playlistAccessTx(ctx, tx, playlistID, sub, lock)
+	if err != nil {
+		return Playlist{}, err
+	}
+	if !canEditPlaylist(pl) {
+		return Playlist{}, ErrPlaylistForbidden
+	}
+	return pl, nil
+}
+
+// playlistOwnerAccessTx is playlistAccessTx for sharing settings: only the owner passes.
+func (r *Repo) playlistOwnerAccessTx(ctx context.Context, tx pgx.Tx, playlistID, sub string) (Playlist, error) {
+	pl, err := r.playlistAccessTx(ctx, tx, playlistID, sub, true)
+	if err != nil {
+		return Playlist{}, err
+	}
+	if pl.Role != PlaylistRoleOwner {
+		return Playlist{}, ErrPlaylistForbidden
+	}
+	return pl, nil
+}
+
+// SetPlaylistVisibility changes who can read a playlist (owner only).
+func (r *Repo) SetPlaylistVisibility(ctx context.Context, ownerSub, playlistID, visibility string) (Playlist, error) {
+	if ownerSub == "" {
+		return Playlist{}, core.ErrUnauthorized
+	}
+	visibility = strings.ToLower(strings.TrimSpace(visibility))
+	if playlistID == "" || !isPlaylistVisibility(visibility) {
+		return Playlist{}, core.ErrInvalidInput
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return Playlist{}, fmt.Errorf("set playlist visibility begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	pl, err := r.playlistOwnerAccessTx(ctx, tx, playlistID, ownerSub)
+	if err != nil {
+		return Playlist{}, err
+	}
+
+	const q = `UPDATE playlists SET visibility = $2 WHERE id::uuid = $1 RETURNING updated_at;`
+	if err := tx.QueryRow(ctx, q, playlistID, visibility).Scan(&pl.UpdatedAt); err != nil {
+		return Playlist{}, fmt.Errorf("set playlist visibility: %w", err)
+	}
+	pl.Visibility = visibility
+
+	items, err := r.listPlaylistItemsTx(ctx, tx, playlistID)
+	if err != nil {
+		return Playlist{}, err
+	}
+	pl.Items = items
+
+	if err := tx.Commit(ctx); err != nil {
+		return Playlist{}, fmt.Errorf("set playlist visibility commit: %w", err)
+	}
+	return pl, nil
+}
+
+// ListPlaylistCollaborators lists who a playlist is shared with. Visible to the owner and
+// the collaborators themselves.
+func (r *Repo) ListPlaylistCollaborators(ctx context.Context, sub, playlistID string) ([]PlaylistCollaborator, error) {
+	if sub == "" {
+		return nil, core.ErrUnauthorized
+	}
+	if playlistID == "" {
+		return nil, core.ErrInvalidInput
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("list playlist collaborators begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	pl, err := r.playlistAccessTx(ctx, tx, playlistID, sub, false)
+	if err != nil {
+		return nil, err
+	}
+	if pl.Role == "" {
+		return nil, ErrPlaylistForbidden
+	}
+
+	const q = `
+SELECT pc.user_sub, u.nickname, u.picture_url, pc.role, pc.added_at
+FROM playlist_collaborators pc
+JOIN users u ON u.sub = pc.user_sub
+WHERE pc.playlist_id::uuid = $1 AND u.deleted_at IS NULL
+ORDER BY pc.added_at ASC;
+`
+	rows, err := tx.Query(ctx, q, playlistID)
+	if err != nil {
+		return nil, fmt.Errorf("list playlist collaborators: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]PlaylistCollaborator, 0, 4)
+	for rows.Next() {
+		var c PlaylistCollaborator
+		if err := rows.Scan(&c.UserSub, &c.Nickname, &c.PictureURL, &c.Role, &c.AddedAt); err != nil {
+			return nil, fmt.Errorf("list playlist collaborators scan: %w", err)
+		}
+		out = append(out, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list playlist collaborators rows: %w", err)
+	}
+	return out, nil
+}
+
+// SetPlaylistCollaborator shares a playlist with a user, or changes their role (owner only).
+func (r *Repo) SetPlaylistCollaborator(ctx context.Context, ownerSub, playlistID, userSub, role string) error {
+	if ownerSub == "" {
+		return core.ErrUnauthorized
+	}
+	userSub = strings.TrimSpace(userSub)
+	if playlistID == "" || userSub == "" || userSub == ownerSub {
+		return core.ErrInvalidInput
+	}
+	if role != PlaylistRoleEditor && role != PlaylistRoleViewer {
+		return core.ErrInvalidInput
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("set playlist collaborator begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := r.playlistOwnerAccessTx(ctx, tx, playlistID, ownerSub); err != nil {
+		return err
+	}
+
+	const userQ = `SELECT 1 FROM users WHERE sub = $1 AND deleted_at IS NULL;`
+	var one int
+	if err := tx.QueryRow(ctx, userQ, userSub).Scan(&one); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("set playlist collaborator verify user: %w", err)
+	}
+
+	const q = `
+INSERT INTO playlist_collaborators (playlist_id, user_sub, role)
+VALUES ($1::uuid, $2, $3)
+ON CONFLICT (playlist_id, user_sub) DO UPDATE SET role = EXCLUDED.role;
+`
+	if _, err := tx.Exec(ctx, q, playlistID, userSub, role); err != nil {
+		return fmt.Errorf("set playlist collaborator: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("set playlist collaborator commit: %w", err)
+	}
+	return nil
+}
+
+// RemovePlaylistCollaborator revokes a user's access. The owner can remove anyone;
+// collaborators can remove themselves.
+func (r *Repo) RemovePlaylistCollaborator(ctx context.Context, sub, playlistID, userSub string) error {
+	if sub == "" {
+		return core.ErrUnauthorized
+	}
+	userSub = strings.TrimSpace(userSub)
+	if playlistID == "" || userSub == "" {
+		return core.ErrInvalidInput
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("remove playlist collaborator begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	pl, err := r.playlistAccessTx(ctx, tx, playlistID, sub, true)
+	if err != nil {
+		return err
+	}
+	if pl.Role != PlaylistRoleOwner && userSub != sub {
+		return ErrPlaylistForbidden
+	}
+
+	const q = `DELETE FROM playlist_collaborators WHERE playlist_id::uuid = $1 AND user_sub = $2;`
+	ct, err := tx.Exec(ctx, q, playlistID, userSub)
+	if err != nil {
+		return fmt.Errorf("remove playlist collaborator: %w", err)
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("remove playlist collaborator commit: %w", err)
+	}
+	return nil
+}
+
+// DiscoverPlaylists lists public playlists, most recently updated first. query filters on
+// the playlist name (case-insensitive substring).
+func (r *Repo) DiscoverPlaylists(ctx context.Context, query string, limit, offset int) ([]PublicPlaylist, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	const q = `
+SELECT p.id::text, p.name, u.nickname,
+       (SELECT COUNT(1) FROM playlist_items pi WHERE pi.playlist_id = p.id)::int,
+       p.updated_at
+FROM playlists p
+JOIN users u ON u.sub = p.owner_sub
+WHERE p.visibility = 'public'
+  AND p.deleted_at IS NULL
+  AND ($1 = '' OR p.name ILIKE '%' || $1 || '%')
+ORDER BY p.updated_at DESC
+LIMIT $2 OFFSET $3;
+`
+	rows, err := r.db.Query(ctx, q, strings.TrimSpace(query), limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("discover playlists: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]PublicPlaylist, 0, limit)
+	for rows.Next() {
+		var pl PublicPlaylist
+		if err := rows.Scan(&pl.ID, &pl.Name, &pl.OwnerNickname, &pl.ItemCount, &pl.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("discover playlists scan: %w", err)
+		}
+		out = append(out, pl)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("discover playlists rows: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,161 @@
+package namethattune
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// MaxPlaylistImportItems caps how many videos a single playlist import fetches.
+const MaxPlaylistImportItems = 200
+
+// PlaylistEntry is a video listed by a remote playlist, in playlist order.
+type PlaylistEntry struct {
+	VideoID      string
+	Title        string
+	ThumbnailURL string
+	// Unavailable marks deleted or private videos; they are reported, not imported.
+	Unavailable bool
+}
+
+// PlaylistSource lists the videos of a remote playlist.
+type PlaylistSource interface {
+	FetchPlaylist(ctx context.Context, playlistID string) ([]PlaylistEntry, error)
+}
+
+// YouTubeDataAPISource lists playlists through the YouTube Data API v3.
+type YouTubeDataAPISource struct {
+	apiKey   string
+	endpoint string
+	client   *http.Client
+}
+
+// NewYouTubeDataAPISource returns a source authenticated with an API key.
+func NewYouTubeDataAPISource(apiKey string) *YouTubeDataAPISource {
+	return &YouTubeDataAPISource{
+		apiKey:   apiKey,
+		endpoint: "https://www.googleapis.com/youtube/v3/playlistItems",
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *YouTubeDataAPISource) FetchPlaylist(ctx context.Context, playlistID string) ([]PlaylistEntry, error) {
+	out := make([]PlaylistEntry, 0, 50)
+	pageToken := ""
+	for len(out) < MaxPlaylistImportItems {
+		q := url.Values{}
+		q.Set("part", "snippet,status")
+		q.Set("maxResults", "50")
+		q.Set("playlistId", playlistID)
+		if pageToken != "" {
+			q.Set("pageToken", pageToken)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.endpoint+"?"+q.Encode(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("build playlist request: %w", err)
+		}
+		// The key goes in a header: request URLs end up in client errors, and those in logs.
+		req.Header.Set("X-Goog-Api-Key", s.apiKey)
+		res, err := s.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("fetch playlist: %w", err)
+		}
+
+		var payload struct {
+			NextPageToken string `json:"nextPageToken"`
+			Items         []struct {
+				Snippet struct {
+					Title      string `json:"title"`
+					ResourceID struct {
+						VideoID string `json:"videoId"`
+					} `json:"resourceId"`
+					Thumbnails map[string]struct {
+						URL string `json:"url"`
+					} `json:"thumbnails"`
+				} `json:"snippet"`
+				Status struct {
+					PrivacyStatus string `json:"privacyStatus"`
+				} `json:"status"`
+			} `json:"items"`
+		}
+		err = decodePlaylistResponse(res, &payload)
+		_ = res.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, it := range payload.Items {
+			thumb := it.Snippet.Thumbnails["high"].URL
+			if thumb == "" {
+				thumb = it.Snippet.Thumbnails["default"].URL
+			}
+			privacy := it.Status.PrivacyStatus
+			out = append(out, PlaylistEntry{
+				VideoID:      it.Snippet.ResourceID.VideoID,
+				Title:        strings.TrimSpace(it.Snippet.Title),
+				ThumbnailURL: thumb,
+				Unavailable:  privacy == "private" || privacy == "privacyStatusUnspecified",
+			})
+		}
+
+		pageToken = payload.NextPageToken
+		if pageToken == "" {
+			break
+		}
+	}
+	if len(out) > MaxPlaylistImportItems {
+		out = out[:MaxPlaylistImportItems]
+	}
+	return out, nil
+}
+
+func decodePlaylistResponse(res *http.Response, dst any) error {
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		return ErrPlaylistSourceNotFound
+	case res.StatusCode != http.StatusOK:
+		return fmt.Errorf("playlist lookup failed with status %d", res.StatusCode)
+	}
+	if err := json.NewDecoder(res.Body).Decode(dst); err != nil {
+		return fmt.Errorf("decode playlist: %w", err)
+	}
+	return nil
+}
+
+// StaticPlaylistSource serves fixed playlists keyed by playlist ID. It is meant for tests
+// and local development without an API key.
+type StaticPlaylistSource map[string][]PlaylistEntry
+
+func (s StaticPlaylistSource) FetchPlaylist(_ context.Context, playlistID string) ([]PlaylistEntry, error) {
+	entries, ok := s[playlistID]
+	if !ok {
+		return nil, ErrPlaylistSourceNotFound
+	}
+	return append([]PlaylistEntry(nil), entries...), nil
+}
+
+// PlaylistImportResult reports what an import added and what it skipped.
+type PlaylistImportResult struct {
+	Added   []PlaylistItem       `json:"added"`
+	Skipped []PlaylistImportSkip `json:"skipped"`
+}
+
+// PlaylistImportSkip is a remote entry that was not imported, with the reason.
+type PlaylistImportSkip struct {
+	Position int    `json:"position"`
+	VideoID  string `json:"videoId,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Reason   string `json:"reason"`
+}
+
+// Import skip reasons.
+const (
+	ImportSkipDuplicate   = "duplicate"
+	ImportSkipUnavailable = "unavailable"
+	ImportSkipInvalid     = "invalid video id"
+)
@@ -0,0 +1,79 @@
+package namethattune
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestExtractYouTubePlaylistID(t *testing.T) {
+	t.Parallel()
+
+	ok := map[string]string{
+		"https://www.youtube.com/playlist?list=PLx0sYbCqOb8TBPRdmBHs5Iftvv9TPboYG": "PLx0sYbCqOb8TBPRdmBHs5Iftvv9TPboYG",
+		"https://music.youtube.com/playlist?list=PLabcdef123":                      "PLabcdef123",
+		"https://www.youtube.com/watch?v=dQw4w9WgXcQ&list=PLabcdef123&index=2":     "PLabcdef123",
+	}
+	for in, want := range ok {
+		got, err := ExtractYouTubePlaylistID(in)
+		if err != nil || got != want {
+			t.Errorf("ExtractYouTubePlaylistID(%q) = (%q, %v), want %q", in, got, err, want)
+		}
+	}
+
+	for _, in := range []string{"", "https://youtu.be/dQw4w9WgXcQ", "https://www.youtube.com/watch?v=dQw4w9WgXcQ", "https://example.com/playlist?list=PLabcdef123"} {
+		if _, err := ExtractYouTubePlaylistID(in); err == nil {
+			t.Errorf("ExtractYouTubePlaylistID(%q): expected error", in)
+		}
+	}
+}
+
+func TestYouTubeDataAPISource_PagesAndFlagsUnavailable(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Goog-Api-Key") != "test-key" || r.URL.Query().Has("key") {
+			http.Error(w, "expected the API key in the header only", http.StatusForbidden)
+			return
+		}
+		if r.URL.Query().Get("playlistId") != "PLabcdef123" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("pageToken") == "" {
+			_, _ = w.Write([]byte(`{"nextPageToken":"p2","items":[
+				{"snippet":{"title":"Queen - Bohemian Rhapsody","resourceId":{"videoId":"fJ9rUzIMcZQ"},"thumbnails":{"high":{"url":"https://i.ytimg.com/hq.jpg"}}},"status":{"privacyStatus":"public"}},
+				{"snippet":{"title":"Private video","resourceId":{"videoId":"aaaaaaaaaaa"}},"status":{"privacyStatus":"private"}}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"items":[{"snippet":{"title":"ABBA - SOS","resourceId":{"videoId":"cvChjHcABPA"}},"status":{"privacyStatus":"unlisted"}}]}`))
+	}))
+	defer srv.Close()
+
+	src := NewYouTubeDataAPISource("test-key")
+	src.endpoint = srv.URL
+
+	entries, err := src.FetchPlaylist(context.Background(), "PLabcdef123")
+	if err != nil {
+		t.Fatalf("FetchPlaylist: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries across pages, got %d", len(entries))
+	}
+	if entries[0].VideoID != "fJ9rUzIMcZQ" || entries[0].ThumbnailURL == "" || entries[0].Unavailable {
+		t.Errorf("unexpected first entry: %+v", entries[0])
+	}
+	if !entries[1].Unavailable {
+		t.Errorf("expected private video to be flagged unavailable")
+	}
+	if entries[2].VideoID != "cvChjHcABPA" || entries[2].Unavailable {
+		t.Errorf("unexpected last entry: %+v", entries[2])
+	}
+
+	if _, err := src.FetchPlaylist(context.Background(), "PLmissing000"); !errors.Is(err, ErrPlaylistSourceNotFound) {
+		t.Errorf("expected ErrPlaylistSourceNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+package namethattune
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/valentin/bes-games/backend/internal/core"
+)
+
+// Deleted playlists go to the trash (playlists.deleted_at is set) and can be restored by
+// their owner during a retention window. Past it, PurgeDeletedPlaylists removes them for
+// good; rooms still pointing at a purged playlist are unloaded by the ON DELETE SET NULL
+// foreign key.
+
+// DefaultPlaylistRetention is how long deleted playlists stay restorable.
+const DefaultPlaylistRetention = 30 * 24 * time.Hour
+
+// TrashedPlaylist is a deleted playlist that can still be restored.
+type TrashedPlaylist struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	ItemCount int       `json:"itemCount"`
+	DeletedAt time.Time `json:"deletedAt"`
+	PurgeAt   time.Time `json:"purgeAt"`
+}
+
+// DeletePlaylist moves a playlist to the trash (owner only). Rooms that had it loaded are
+// unloaded right away, as the foreign key would on a hard delete; their IDs are returned so
+// the caller can notify them.
+func (r *Repo) DeletePlaylist(ctx context.Context, ownerSub, playlistID string) ([]string, error) {
+	if ownerSub == "" {
+		return nil, core.ErrUnauthorized
+	}
+	if playlistID == "" {
+		return nil, core.ErrInvalidInput
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("delete playlist begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := r.playlistOwnerAccessTx(ctx, tx, playlistID, ownerSub); err != nil {
+		return nil, err
+	}
+
+	const q = `UPDATE playlists SET deleted_at = now() WHERE id::uuid = $1;`
+	if _, err := tx.Exec(ctx, q, playlistID); err != nil {
+		return nil, fmt.Errorf("delete playlist: %w", err)
+	}
+
+	const roomsQ = `
+UPDATE rooms
+SET loaded_playlist_id = NULL,
+    playback_track_index = 0,
+    playback_paused = TRUE,
+    playback_position_ms = 0,
+    playback_updated_at = now()
+WHERE loaded_playlist_id = $1::uuid
+RETURNING id::text;
+`
+	rows, err := tx.Query(ctx, roomsQ, playlistID)
+	if err != nil {
+		return nil, fmt.Errorf("delete playlist unload rooms: %w", err)
+	}
+	roomIDs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("delete playlist unload rooms scan: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("delete playlist commit: %w", err)
+	}
+	return roomIDs, nil
+}
+
+// ListTrashedPlaylists lists the owner's deleted playlists that are still restorable,
+// most recently deleted first.
+func (r *Repo) ListTrashedPlaylists(ctx context.Context, ownerSub string, retention time.Duration) ([]TrashedPlaylist, error) {
+	if ownerSub == "" {
+		return nil, core.ErrUnauthorized
+	}
+
+	const q = `
+SELECT p.id::text, p.name,
+       (SELECT COUNT(1) FROM playlist_items pi WHERE pi.playlist_id = p.id)::int,
+       p.deleted_at
+FROM playlists p
+WHERE p.owner_sub = $1 AND p.deleted_at IS NOT NULL AND p.deleted_at > $2
+ORDER BY p.deleted_at DESC;
+`
+	rows, err := r.db.Query(ctx, q, ownerSub, time.Now().Add(-retention))
+	if err != nil {
+		return nil, fmt.Errorf("list trashed playlists: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]TrashedPlaylist, 0, 4)
+	for rows.Next() {
+		var pl TrashedPlaylist
+		if err := rows.Scan(&pl.ID, &pl.Name, &pl.ItemCount, &pl.DeletedAt); err != nil {
+			return nil, fmt.Errorf("list trashed playlists scan: %w", err)
+		}
+		pl.PurgeAt = pl.DeletedAt.Add(retention)
+		out = append(out, pl)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list trashed playlists rows: %w", err)
+	}
+	return out, nil
+}
+
+// RestorePlaylist takes a playlist out of the trash (owner only). Playlists deleted more
+// than retention ago are reported as not found. Rooms are not reloaded.
+func (r *Repo) RestorePlaylist(ctx context.Context, ownerSub, playlistID string, retention time.Duration) (Playlist, error) {
+	if ownerSub == "" {
+		return Playlist{}, core.ErrUnauthorized
+	}
+	if playlistID == "" {
+		return Playlist{}, core.ErrInvalidInput
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return Playlist{}, fmt.Errorf("restore playlist begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	const q = `
+UPDATE playlists
+SET deleted_at = NULL
+WHERE id::uuid = $1 AND owner_sub = $2 AND deleted_at IS NOT NULL AND deleted_at > $3
+RETURNING id::text, owner_sub, name, visibility, created_at, updated_at;
+`
+	var pl Playlist
+	if err := tx.QueryRow(ctx, q, playlistID, ownerSub, time.Now().Add(-retention)).Scan(&pl.ID, &pl.OwnerSub, &pl.Name, &pl.Visibility, &pl.CreatedAt, &pl.UpdatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Playlist{}, ErrPlaylistNotFound
+		}
+		return Playlist{}, fmt.Errorf("restore playlist: %w", err)
+	}
+	pl.Role = PlaylistRoleOwner
+
+	items, err := r.listPlaylistItemsTx(ctx, tx, pl.ID)
+	if err != nil {
+		return Playlist{}, err
+	}
+	pl.Items = items
+
+	if err := tx.Commit(ctx); err != nil {
+		return Playlist{}, fmt.Errorf("restore playlist commit: %w", err)
+	}
+	return pl, nil
+}
+
+// PurgeDeletedPlaylists permanently removes playlists deleted more than retention ago,
+// with their items and collaborators. It returns the number of playlists removed.
+func (r *Repo) PurgeDeletedPlaylists(ctx context.Context, retention time.Duration) (int64, error) {
+	const q = `DELETE FROM playlists WHERE deleted_at IS NOT NULL AND deleted_at <= $1;`
+	ct, err := r.db.Exec(ctx, q, time.Now().Add(-retention))
+	if err != nil {
+		return 0, fmt.Errorf("purge deleted playlists: %w", err)
+	}
+	return ct.RowsAffected(), nil
+}
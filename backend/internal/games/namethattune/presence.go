@@ -0,0 +1,36 @@
+package namethattune
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/valentin/bes-games/backend/internal/core"
+)
+
+// ReconnectPlayer marks a disconnected seat connected again, e.g. when its player's
+// WebSocket comes back. It reports whether the seat was disconnected and whether it is the
+// owner's seat; an unknown or already connected seat is left as is.
+func (r *Repo) ReconnectPlayer(ctx context.Context, roomID, playerID string) (reconnected, isOwner bool, err error) {
+	if roomID == "" || playerID == "" {
+		return false, false, core.ErrInvalidInput
+	}
+
+	const q = `
+UPDATE room_players rp
+SET connected = TRUE,
+    left_at = NULL,
+    updated_at = now()
+FROM rooms rm
+WHERE rm.id = rp.room_id AND rp.id::uuid = $1 AND rp.room_id::uuid = $2 AND NOT rp.connected
+RETURNING COALESCE(rp.user_sub, '') = rm.owner_sub;
+`
+	if err := r.db.QueryRow(ctx, q, playerID, roomID).Scan(&isOwner); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, false, nil
+		}
+		return false, false, fmt.Errorf("reconnect player: %w", err)
+	}
+	return true, isOwner, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
@@ -23,7 +24,8 @@ import (
 //
 // Schema expectations (see migrations/0001_init.sql):
 // - users(sub PK, nickname, picture_url, deleted_at, ...)
-// - playlists(id UUID PK, owner_sub FK users, name, deleted_at, ...)
+// - playlists(id UUID PK, owner_sub FK users, name, visibility, deleted_at, ...)
+// - playlist_collaborators(playlist_id FK playlists, user_sub FK users, role)
 // - playlist_items(id UUID PK, playlist_id FK playlists, position, title, youtube_url, youtube_id, ...)
 // - rooms(id UUID PK, name, owner_sub FK users, loaded_playlist_id, playback_* ...)
 // - room_players(id UUID PK, room_id FK rooms, user_sub nullable FK users, nickname, picture_url, score, connected, left_at ...)
@@ -50,6 +52,10 @@ type JoinResult struct {
 	OwnerConnected  bool
 	OwnerPlayerID   string
 	OwnerWasOffline bool
+	// Resumed is true when the seat was reclaimed with a resume claim.
+	Resumed bool
+	// ResumeNonce is the seat's nonce, to be signed into the player's resume token.
+	ResumeNonce string
 }
 
 type LeaveResult struct {
@@ -106,6 +112,14 @@ func (r *Repo) CleanupUserData(ctx context.Context, sub string) error {
 		}
 	}
 
+	// Drop playlists shared with user.
+	{
+		const q = `DELETE FROM playlist_collaborators WHERE user_sub = $1;`
+		if _, err := tx.Exec(ctx, q, sub); err != nil {
+			return fmt.Errorf("cleanup user playlist collaborations: %w", err)
+		}
+	}
+
 	// Scrub room_players with this sub: mark disconnected + anonymize.
 	{
 		const q = `
@@ -122,6 +136,33 @@ WHERE user_sub = $1;
 		}
 	}
 
+	// Scrub match history the same way. Matches hosted by the user are kept for the other
+	// players, without the host.
+	{
+		const q = `UPDATE match_buzzes SET nickname = 'Deleted User', user_sub = NULL WHERE user_sub = $1;`
+		if _, err := tx.Exec(ctx, q, sub); err != nil {
+			return fmt.Errorf("cleanup user scrub match_buzzes: %w", err)
+		}
+	}
+	{
+		const q = `
+UPDATE match_standings
+SET nickname = 'Deleted User',
+    picture_url = '',
+    user_sub = NULL
+WHERE user_sub = $1;
+`
+		if _, err := tx.Exec(ctx, q, sub); err != nil {
+			return fmt.Errorf("cleanup user scrub match_standings: %w", err)
+		}
+	}
+	{
+		const q = `UPDATE matches SET owner_sub = NULL WHERE owner_sub = $1;`
+		if _, err := tx.Exec(ctx, q, sub); err != nil {
+			return fmt.Errorf("cleanup user scrub matches: %w", err)
+		}
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("cleanup user commit: %w", err)
 	}
@@ -153,25 +194,29 @@ func (r *Repo) CreatePlaylist(ctx context.Context, ownerSub, name string) (Playl
 	const q = `
 INSERT INTO playlists (owner_sub, name, deleted_at)
 VALUES ($1, $2, NULL)
-RETURNING id::text, owner_sub, name, created_at, updated_at;
+RETURNING id::text, owner_sub, name, visibility, created_at, updated_at;
 `
 	var pl Playlist
-	if err := r.db.QueryRow(ctx, q, ownerSub, name).Scan(&pl.ID, &pl.OwnerSub, &pl.Name, &pl.CreatedAt, &pl.UpdatedAt); err != nil {
+	if err := r.db.QueryRow(ctx, q, ownerSub, name).Scan(&pl.ID, &pl.OwnerSub, &pl.Name, &pl.Visibility, &pl.CreatedAt, &pl.UpdatedAt); err != nil {
 		return Playlist{}, fmt.Errorf("create playlist: %w", err)
 	}
+	pl.Role = PlaylistRoleOwner
 	pl.Items = []PlaylistItem{}
 	return pl, nil
 }
 
+// ListPlaylists lists the playlists ownerSub owns or collaborates on.
 func (r *Repo) ListPlaylists(ctx context.Context, ownerSub string) ([]Playlist, error) {
 	if ownerSub == "" {
 		return nil, core.ErrUnauthorized
 	}
 
 	const q = `
-SELECT p.id::text, p.owner_sub, p.name, p.created_at, p.updated_at
+SELECT p.id::text, p.owner_sub, p.name, p.visibility, p.created_at, p.updated_at,
+       CASE WHEN p.owner_sub = $1 THEN 'owner' ELSE pc.role END
 FROM playlists p
-WHERE p.owner_sub = $1 AND p.deleted_at IS NULL
+LEFT JOIN playlist_collaborators pc ON pc.playlist_id = p.id AND pc.user_sub = $1
+WHERE p.deleted_at IS NULL AND (p.owner_sub = $1 OR pc.user_sub IS NOT NULL)
 ORDER BY p.updated_at DESC;
 `
 	rows, err := r.db.Query(ctx, q, ownerSub)
@@ -183,7 +228,7 @@ ORDER BY p.updated_at DESC;
 	out := make([]Playlist, 0, 8)
 	for rows.Next() {
 		var pl Playlist
-		if err := rows.Scan(&pl.ID, &pl.OwnerSub, &pl.Name, &pl.CreatedAt, &pl.UpdatedAt); err != nil {
+		if err := rows.Scan(&pl.ID, &pl.OwnerSub, &pl.Name, &pl.Visibility, &pl.CreatedAt, &pl.UpdatedAt, &pl.Role); err != nil {
 			return nil, fmt.Errorf("list playlists scan: %w", err)
 		}
 		// Load items lazily? For now return empty; callers can fetch with GetPlaylist.
@@ -196,6 +241,7 @@ ORDER BY p.updated_at DESC;
 	return out, nil
 }
 
+// GetPlaylist returns a playlist ownerSub can read: owned, shared with them, or not private.
 func (r *Repo) GetPlaylist(ctx context.Context, ownerSub, playlistID string) (Playlist, error) {
 	if ownerSub == "" {
 		return Playlist{}, core.ErrUnauthorized
@@ -210,20 +256,9 @@ func (r *Repo) GetPlaylist(ctx context.Context, ownerSub, playlistID string) (Pl
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 
-	var pl Playlist
-	{
-		const q = `
-SELECT id::text, owner_sub, name, created_at, updated_at
-FROM playlists
-WHERE id::uuid = $1 AND owner_sub = $2 AND deleted_at IS NULL;
-`
-		err := tx.QueryRow(ctx, q, playlistID, ownerSub).Scan(&pl.ID, &pl.OwnerSub, &pl.Name, &pl.CreatedAt, &pl.UpdatedAt)
-		if errors.Is(err, pgx.ErrNoRows) {
-			return Playlist{}, ErrPlaylistNotFound
-		}
-		if err != nil {
-			return Playlist{}, fmt.Errorf("get playlist: %w", err)
-		}
+	pl, err := r.playlistAccessTx(ctx, tx, playlistID, ownerSub, false)
+	if err != nil {
+		return Playlist{}, err
 	}
 
 	items, err := r.listPlaylistItemsTx(ctx, tx, playlistID)
@@ -247,29 +282,64 @@ func (r *Repo) UpdatePlaylistName(ctx context.Context, ownerSub, playlistID, nam
 		return Playlist{}, core.ErrInvalidInput
 	}
 
-	const q = `
-UPDATE playlists
-SET name = $3
-WHERE id::uuid = $1 AND owner_sub = $2 AND deleted_at IS NULL
-RETURNING id::text, owner_sub, name, created_at, updated_at;
-`
-	var pl Playlist
-	if err := r.db.QueryRow(ctx, q, playlistID, ownerSub, name).Scan(&pl.ID, &pl.OwnerSub, &pl.Name, &pl.CreatedAt, &pl.UpdatedAt); err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return Playlist{}, ErrPlaylistNotFound
-		}
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return Playlist{}, fmt.Errorf("update playlist begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	pl, err := r.playlistEditAccessTx(ctx, tx, playlistID, ownerSub, true)
+	if err != nil {
+		return Playlist{}, err
+	}
+
+	const q = `UPDATE playlists SET name = $2 WHERE id::uuid = $1 RETURNING name, updated_at;`
+	if err := tx.QueryRow(ctx, q, playlistID, name).Scan(&pl.Name, &pl.UpdatedAt); err != nil {
 		return Playlist{}, fmt.Errorf("update playlist: %w", err)
 	}
 
 	// Include items for convenience.
-	items, err := r.ListPlaylistItems(ctx, ownerSub, playlistID)
+	items, err := r.listPlaylistItemsTx(ctx, tx, playlistID)
 	if err != nil {
 		return Playlist{}, err
 	}
 	pl.Items = items
+
+	if err := tx.Commit(ctx); err != nil {
+		return Playlist{}, fmt.Errorf("update playlist commit: %w", err)
+	}
 	return pl, nil
 }
 
+// playlistItemColumns is the select list matching scanPlaylistItem.
+const playlistItemColumns = `id::text, title, youtube_url, youtube_id, thumbnail_url, duration_sec, created_at,
+       artist, song_title, year, accepted_answers, start_ms, clip_duration_ms`
+
+func scanPlaylistItem(row pgx.Row) (PlaylistItem, error) {
+	var it PlaylistItem
+	if err := row.Scan(
+		&it.ID,
+		&it.Title,
+		&it.YouTubeURL,
+		&it.YouTubeID,
+		&it.ThumbnailURL,
+		&it.DurationSec,
+		&it.AddedAt,
+		&it.Artist,
+		&it.SongTitle,
+		&it.Year,
+		&it.AcceptedAnswers,
+		&it.StartMS,
+		&it.ClipDurationMS,
+	); err != nil {
+		return PlaylistItem{}, err
+	}
+	if it.AcceptedAnswers == nil {
+		it.AcceptedAnswers = []string{}
+	}
+	return it, nil
+}
+
 func (r *Repo) AddPlaylistItem(ctx context.Context, ownerSub, playlistID, title, youtubeURL, thumbnailURL string) (PlaylistItem, Playlist, error) {
 	if ownerSub == "" {
 		return PlaylistItem{}, Playlist{}, core.ErrUnauthorized
@@ -289,22 +359,10 @@ func (r *Repo) AddPlaylistItem(ctx context.Context, ownerSub, playlistID, title,
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 
-	// Ensure playlist exists and belongs to user; lock row for concurrent inserts.
-	var pl Playlist
-	{
-		const q = `
-SELECT id::text, owner_sub, name, created_at, updated_at
-FROM playlists
-WHERE id::uuid = $1 AND owner_sub = $2 AND deleted_at IS NULL
-FOR UPDATE;
-`
-		err := tx.QueryRow(ctx, q, playlistID, ownerSub).Scan(&pl.ID, &pl.OwnerSub, &pl.Name, &pl.CreatedAt, &pl.UpdatedAt)
-		if errors.Is(err, pgx.ErrNoRows) {
-			return PlaylistItem{}, Playlist{}, ErrPlaylistNotFound
-		}
-		if err != nil {
-			return PlaylistItem{}, Playlist{}, fmt.Errorf("add playlist item load playlist: %w", err)
-		}
+	// Ensure playlist exists and user can edit it; lock row for concurrent inserts.
+	pl, err := r.playlistEditAccessTx(ctx, tx, playlistID, ownerSub, true)
+	if err != nil {
+		return PlaylistItem{}, Playlist{}, err
 	}
 
 	// Determine next position.
@@ -318,12 +376,14 @@ FOR UPDATE;
 
 	var item PlaylistItem
 	{
+		artist, song := ParseVideoTitle(title)
 		const q = `
-INSERT INTO playlist_items (playlist_id, position, title, youtube_url, youtube_id, thumbnail_url, duration_sec)
-VALUES ($1::uuid, $2, $3, $4, $5, $6, 0)
-RETURNING id::text, title, youtube_url, youtube_id, thumbnail_url, duration_sec, created_at;
+INSERT INTO playlist_items (playlist_id, position, title, youtube_url, youtube_id, thumbnail_url, duration_sec, artist, song_title)
+VALUES ($1::uuid, $2, $3, $4, $5, $6, 0, $7, $8)
+RETURNING ` + playlistItemColumns + `;
 `
-		if err := tx.QueryRow(ctx, q, playlistID, pos, title, youtubeURL, yid, thumbnailURL).Scan(&item.ID, &item.Title, &item.YouTubeURL, &item.YouTubeID, &item.ThumbnailURL, &item.DurationSec, &item.AddedAt); err != nil {
+		item, err = scanPlaylistItem(tx.QueryRow(ctx, q, playlistID, pos, title, youtubeURL, yid, thumbnailURL, artist, song))
+		if err != nil {
 			return PlaylistItem{}, Playlist{}, fmt.Errorf("add playlist item insert: %w", err)
 		}
 	}
@@ -350,13 +410,173 @@ RETURNING id::text, title, youtube_url, youtube_id, thumbnail_url, duration_sec,
 	return item, pl, nil
 }
 
-func (r *Repo) UpdatePlaylistItemTitle(ctx context.Context, ownerSub, playlistID, itemID, title string) (PlaylistItem, error) {
+// ImportPlaylistItems appends remote playlist entries to a playlist (owner only), in order.
+// Videos already in the playlist (or repeated in the import), unavailable videos and
+// invalid IDs are skipped and reported rather than failing the whole import.
+func (r *Repo) ImportPlaylistItems(ctx context.Context, ownerSub, playlistID string, entries []PlaylistEntry) (PlaylistImportResult, Playlist, error) {
+	if ownerSub == "" {
+		return PlaylistImportResult{}, Playlist{}, core.ErrUnauthorized
+	}
+	if playlistID == "" {
+		return PlaylistImportResult{}, Playlist{}, core.ErrInvalidInput
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return PlaylistImportResult{}, Playlist{}, fmt.Errorf("import playlist items begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// Ensure playlist exists and user can edit it; lock row for concurrent inserts.
+	pl, err := r.playlistEditAccessTx(ctx, tx, playlistID, ownerSub, true)
+	if err != nil {
+		return PlaylistImportResult{}, Playlist{}, err
+	}
+
+	existing, err := r.listPlaylistItemsTx(ctx, tx, playlistID)
+	if err != nil {
+		return PlaylistImportResult{}, Playlist{}, err
+	}
+	seen := make(map[string]bool, len(existing)+len(entries))
+	for _, it := range existing {
+		seen[it.YouTubeID] = true
+	}
+
+	res := PlaylistImportResult{Added: []PlaylistItem{}, Skipped: []PlaylistImportSkip{}}
+	var titles, urls, ids, thumbs, artists, songs []string
+	for i, e := range entries {
+		skip := PlaylistImportSkip{Position: i, VideoID: e.VideoID, Title: e.Title}
+		switch {
+		case !isYouTubeID(e.VideoID):
+			skip.Reason = ImportSkipInvalid
+		case e.Unavailable:
+			skip.Reason = ImportSkipUnavailable
+		case seen[e.VideoID]:
+			skip.Reason = ImportSkipDuplicate
+		}
+		if skip.Reason != "" {
+			res.Skipped = append(res.Skipped, skip)
+			continue
+		}
+		seen[e.VideoID] = true
+
+		title := strings.TrimSpace(e.Title)
+		if title == "" {
+			title = "Unknown title"
+		}
+		artist, song := ParseVideoTitle(title)
+		titles = append(titles, title)
+		urls = append(urls, "https://www.youtube.com/watch?v="+e.VideoID)
+		ids = append(ids, e.VideoID)
+		thumbs = append(thumbs, e.ThumbnailURL)
+		artists = append(artists, artist)
+		songs = append(songs, song)
+	}
+
+	if len(ids) > 0 {
+		// One statement for the whole batch; WITH ORDINALITY keeps the remote order.
+		const q = `
+WITH base AS (
+  SELECT COALESCE(MAX(position), -1) + 1 AS pos FROM playlist_items WHERE playlist_id::uuid = $1
+)
+INSERT INTO playlist_items (playlist_id, position, title, youtube_url, youtube_id, thumbnail_url, duration_sec, artist, song_title)
+SELECT $1::uuid, base.pos + v.ord - 1, v.title, v.url, v.yid, v.thumb, 0, v.artist, v.song
+FROM base, unnest($2::text[], $3::text[], $4::text[], $5::text[], $6::text[], $7::text[])
+  WITH ORDINALITY AS v(title, url, yid, thumb, artist, song, ord)
+ORDER BY v.ord
+RETURNING ` + playlistItemColumns + `;
+`
+		rows, err := tx.Query(ctx, q, playlistID, titles, urls, ids, thumbs, artists, songs)
+		if err != nil {
+			return PlaylistImportResult{}, Playlist{}, fmt.Errorf("import playlist items insert: %w", err)
+		}
+		for rows.Next() {
+			it, err := scanPlaylistItem(rows)
+			if err != nil {
+				rows.Close()
+				return PlaylistImportResult{}, Playlist{}, fmt.Errorf("import playlist items scan: %w", err)
+			}
+			res.Added = append(res.Added, it)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return PlaylistImportResult{}, Playlist{}, fmt.Errorf("import playlist items rows: %w", err)
+		}
+
+		const touchQ = `UPDATE playlists SET name = name WHERE id::uuid = $1;`
+		if _, err := tx.Exec(ctx, touchQ, playlistID); err != nil {
+			return PlaylistImportResult{}, Playlist{}, fmt.Errorf("import playlist items touch playlist: %w", err)
+		}
+	}
+
+	items, err := r.listPlaylistItemsTx(ctx, tx, playlistID)
+	if err != nil {
+		return PlaylistImportResult{}, Playlist{}, err
+	}
+	pl.Items = items
+
+	if err := tx.Commit(ctx); err != nil {
+		return PlaylistImportResult{}, Playlist{}, fmt.Errorf("import playlist items commit: %w", err)
+	}
+
+	// RETURNING order is not guaranteed; report additions in playlist order.
+	byID := make(map[string]PlaylistItem, len(res.Added))
+	for _, it := range res.Added {
+		byID[it.ID] = it
+	}
+	res.Added = res.Added[:0]
+	for _, it := range items {
+		if _, ok := byID[it.ID]; ok {
+			res.Added = append(res.Added, it)
+		}
+	}
+
+	return res, pl, nil
+}
+
+// maxAcceptedAnswers bounds the alternative answers of an item.
+const maxAcceptedAnswers = 20
+
+// UpdatePlaylistItem applies a partial update to an item (owner only).
+func (r *Repo) UpdatePlaylistItem(ctx context.Context, ownerSub, playlistID, itemID string, patch PlaylistItemPatch) (PlaylistItem, error) {
 	if ownerSub == "" {
 		return PlaylistItem{}, core.ErrUnauthorized
 	}
-	if playlistID == "" || itemID == "" || strings.TrimSpace(title) == "" {
+	if playlistID == "" || itemID == "" {
 		return PlaylistItem{}, core.ErrInvalidInput
 	}
+	if patch.Title != nil {
+		t := strings.TrimSpace(*patch.Title)
+		if t == "" {
+			return PlaylistItem{}, core.ErrInvalidInput
+		}
+		patch.Title = &t
+	}
+	for _, f := range []*string{patch.Artist, patch.SongTitle} {
+		if f != nil {
+			*f = strings.TrimSpace(*f)
+		}
+	}
+	if patch.Year != nil && *patch.Year != 0 && (*patch.Year < 1000 || *patch.Year > 9999) {
+		return PlaylistItem{}, core.ErrInvalidInput
+	}
+	var accepted []string
+	if patch.AcceptedAnswers != nil {
+		accepted = make([]string, 0, len(*patch.AcceptedAnswers))
+		for _, a := range *patch.AcceptedAnswers {
+			if a = strings.TrimSpace(a); a != "" {
+				accepted = append(accepted, a)
+			}
+		}
+		if len(accepted) > maxAcceptedAnswers {
+			return PlaylistItem{}, core.ErrInvalidInput
+		}
+	}
+	for _, f := range []*int{patch.StartMS, patch.ClipDurationMS} {
+		if f != nil && (*f < 0 || *f > MaxClipMS) {
+			return PlaylistItem{}, core.ErrInvalidInput
+		}
+	}
 
 	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
@@ -364,27 +584,29 @@ func (r *Repo) UpdatePlaylistItemTitle(ctx context.Context, ownerSub, playlistID
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 
+	if _, err := r.playlistEditAccessTx(ctx, tx, playlistID, ownerSub, false); err != nil {
+		return PlaylistItem{}, err
+	}
+
 	const q = `
-UPDATE playlist_items pi
-SET title = $4
-FROM playlists p
-WHERE pi.id::uuid = $1
-  AND pi.playlist_id = p.id
-  AND p.id::uuid = $2
-  AND p.owner_sub = $3
-  AND p.deleted_at IS NULL
-RETURNING pi.id::text, pi.title, pi.youtube_url, pi.youtube_id, pi.thumbnail_url, pi.duration_sec, pi.created_at;
+UPDATE playlist_items
+SET title = COALESCE($3::text, title),
+    artist = COALESCE($4::text, artist),
+    song_title = COALESCE($5::text, song_title),
+    year = CASE WHEN $6::int IS NULL THEN year WHEN $6::int = 0 THEN NULL ELSE $6::int END,
+    accepted_answers = CASE WHEN $7::bool THEN $8::text[] ELSE accepted_answers END,
+    start_ms = COALESCE($9::int, start_ms),
+    clip_duration_ms = COALESCE($10::int, clip_duration_ms)
+WHERE id::uuid = $1 AND playlist_id::uuid = $2
+RETURNING ` + playlistItemColumns + `;
 `
-	var item PlaylistItem
-	if err := tx.QueryRow(ctx, q, itemID, playlistID, ownerSub, strings.TrimSpace(title)).Scan(
-		&item.ID,
-		&item.Title,
-		&item.YouTubeURL,
-		&item.YouTubeID,
-		&item.ThumbnailURL,
-		&item.DurationSec,
-		&item.AddedAt,
-	); err != nil {
+	item, err := scanPlaylistItem(tx.QueryRow(ctx, q,
+		itemID, playlistID,
+		patch.Title, patch.Artist, patch.SongTitle, patch.Year,
+		patch.AcceptedAnswers != nil, accepted,
+		patch.StartMS, patch.ClipDurationMS,
+	))
+	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return PlaylistItem{}, ErrPlaylistNotFound
 		}
@@ -417,22 +639,21 @@ func (r *Repo) DeletePlaylistItem(ctx context.Context, ownerSub, playlistID, ite
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 
-	const q = `
-DELETE FROM playlist_items pi
-USING playlists p
-WHERE pi.id::uuid = $1
-  AND pi.playlist_id = p.id
-  AND p.id::uuid = $2
-  AND p.owner_sub = $3
-  AND p.deleted_at IS NULL;
-`
-	ct, err := tx.Exec(ctx, q, itemID, playlistID, ownerSub)
+	if _, err := r.playlistEditAccessTx(ctx, tx, playlistID, ownerSub, false); err != nil {
+		return err
+	}
+
+	const q = `DELETE FROM playlist_items WHERE id::uuid = $1 AND playlist_id::uuid = $2;`
+	ct, err := tx.Exec(ctx, q, itemID, playlistID)
 	if err != nil {
 		return fmt.Errorf("delete playlist item: %w", err)
 	}
 	if ct.RowsAffected() == 0 {
 		return ErrPlaylistNotFound
 	}
+	if err := compactPlaylistItemsTx(ctx, tx, playlistID); err != nil {
+		return err
+	}
 
 	const touchQ = `UPDATE playlists SET name = name WHERE id::uuid = $1;`
 	if _, err := tx.Exec(ctx, touchQ, playlistID); err != nil {
@@ -454,49 +675,31 @@ func (r *Repo) ListPlaylistItems(ctx context.Context, ownerSub, playlistID strin
 		return nil, core.ErrInvalidInput
 	}
 
-	// Authorization: ensure playlist belongs to owner.
-	const authQ = `
-SELECT 1
-FROM playlists
-WHERE id::uuid = $1 AND owner_sub = $2 AND deleted_at IS NULL;
-`
-	var one int
-	if err := r.db.QueryRow(ctx, authQ, playlistID, ownerSub).Scan(&one); err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, ErrPlaylistNotFound
-		}
-		return nil, fmt.Errorf("list playlist items auth: %w", err)
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("list playlist items begin: %w", err)
 	}
+	defer func() { _ = tx.Rollback(ctx) }()
 
-	const q = `
-SELECT id::text, title, youtube_url, youtube_id, thumbnail_url, duration_sec, created_at
-FROM playlist_items
-WHERE playlist_id::uuid = $1
-ORDER BY position ASC;
-`
-	rows, err := r.db.Query(ctx, q, playlistID)
-	if err != nil {
-		return nil, fmt.Errorf("list playlist items: %w", err)
+	// Authorization: ensure playlist is readable by the caller.
+	if _, err := r.playlistAccessTx(ctx, tx, playlistID, ownerSub, false); err != nil {
+		return nil, err
 	}
-	defer rows.Close()
 
-	out := make([]PlaylistItem, 0, 16)
-	for rows.Next() {
-		var it PlaylistItem
-		if err := rows.Scan(&it.ID, &it.Title, &it.YouTubeURL, &it.YouTubeID, &it.ThumbnailURL, &it.DurationSec, &it.AddedAt); err != nil {
-			return nil, fmt.Errorf("list playlist items scan: %w", err)
-		}
-		out = append(out, it)
+	out, err := r.listPlaylistItemsTx(ctx, tx, playlistID)
+	if err != nil {
+		return nil, err
 	}
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("list playlist items rows: %w", err)
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("list playlist items commit: %w", err)
 	}
 	return out, nil
 }
 
 func (r *Repo) listPlaylistItemsTx(ctx context.Context, tx pgx.Tx, playlistID string) ([]PlaylistItem, error) {
 	const q = `
-SELECT id::text, title, youtube_url, youtube_id, thumbnail_url, duration_sec, created_at
+SELECT ` + playlistItemColumns + `
 FROM playlist_items
 WHERE playlist_id::uuid = $1
 ORDER BY position ASC;
@@ -509,8 +712,8 @@ ORDER BY position ASC;
 
 	out := make([]PlaylistItem, 0, 16)
 	for rows.Next() {
-		var it PlaylistItem
-		if err := rows.Scan(&it.ID, &it.Title, &it.YouTubeURL, &it.YouTubeID, &it.ThumbnailURL, &it.DurationSec, &it.AddedAt); err != nil {
+		it, err := scanPlaylistItem(rows)
+		if err != nil {
 			return nil, fmt.Errorf("list playlist items (tx) scan: %w", err)
 		}
 		out = append(out, it)
@@ -536,10 +739,17 @@ type DBRoomInfo struct {
 }
 
 // CreateRoom creates a room and ensures the owner exists in users.
-func (r *Repo) CreateRoom(ctx context.Context, ownerSub, name, playlistID, visibility, password string) (string, error) {
+func (r *Repo) CreateRoom(ctx context.Context, ownerSub, name, playlistID, visibility, password string, rules RoomRules) (string, error) {
 	if ownerSub == "" {
 		return "", core.ErrUnauthorized
 	}
+	if err := rules.Validate(); err != nil {
+		return "", err
+	}
+	encodedRules, err := json.Marshal(rules)
+	if err != nil {
+		return "", fmt.Errorf("create room encode rules: %w", err)
+	}
 	if name == "" {
 		name = "Room"
 	}
@@ -559,13 +769,8 @@ func (r *Repo) CreateRoom(ctx context.Context, ownerSub, name, playlistID, visib
 	defer func() { _ = tx.Rollback(ctx) }()
 
 	if playlistID != "" {
-		const q = `SELECT 1 FROM playlists WHERE id::uuid = $1 AND owner_sub = $2 AND deleted_at IS NULL;`
-		var one int
-		if err := tx.QueryRow(ctx, q, playlistID, ownerSub).Scan(&one); err != nil {
-			if errors.Is(err, pgx.ErrNoRows) {
-				return "", ErrPlaylistNotFound
-			}
-			return "", fmt.Errorf("create room verify playlist: %w", err)
+		if _, err := r.playlistAccessTx(ctx, tx, playlistID, ownerSub, false); err != nil {
+			return "", err
 		}
 	}
 
@@ -575,12 +780,12 @@ func (r *Repo) CreateRoom(ctx context.Context, ownerSub, name, playlistID, visib
 	}
 
 	const roomQ = `
-INSERT INTO rooms (name, owner_sub, loaded_playlist_id, playback_track_index, playback_paused, playback_position_ms, playback_updated_at, visibility, password_hash)
-VALUES ($1, $2, NULLIF($3, '')::uuid, 0, TRUE, 0, now(), $4, $5)
+INSERT INTO rooms (name, owner_sub, loaded_playlist_id, playback_track_index, playback_paused, playback_position_ms, playback_updated_at, visibility, password_hash, rules)
+VALUES ($1, $2, NULLIF($3, '')::uuid, 0, TRUE, 0, now(), $4, $5, $6::jsonb)
 RETURNING id::text;
 `
 	var roomID string
-	if err := tx.QueryRow(ctx, roomQ, name, ownerSub, playlistID, visibility, passwordHash).Scan(&roomID); err != nil {
+	if err := tx.QueryRow(ctx, roomQ, name, ownerSub, playlistID, visibility, passwordHash, string(encodedRules)).Scan(&roomID); err != nil {
 		return "", fmt.Errorf("create room: %w", err)
 	}
 
@@ -642,6 +847,23 @@ ORDER BY rm.updated_at DESC;
 	return out, nil
 }
 
+// GetRoomOwner returns the sub of a room's owner.
+func (r *Repo) GetRoomOwner(ctx context.Context, roomID string) (string, error) {
+	if roomID == "" {
+		return "", core.ErrInvalidInput
+	}
+
+	const q = `SELECT owner_sub FROM rooms WHERE id::uuid = $1;`
+	var ownerSub string
+	if err := r.db.QueryRow(ctx, q, roomID).Scan(&ownerSub); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", core.ErrRoomNotFound
+		}
+		return "", fmt.Errorf("get room owner: %w", err)
+	}
+	return ownerSub, nil
+}
+
 func (r *Repo) GetRoomSnapshot(ctx context.Context, roomID string) (RoomSnapshot, error) {
 	if roomID == "" {
 		return RoomSnapshot{}, core.ErrInvalidInput
@@ -659,12 +881,14 @@ func (r *Repo) GetRoomSnapshot(ctx context.Context, roomID string) (RoomSnapshot
 		const q = `
 SELECT id::text, name, owner_sub, loaded_playlist_id::text,
        visibility, password_hash,
-       playback_track_index, playback_paused, playback_position_ms, playback_updated_at
+       playback_track_index, playback_paused, playback_position_ms, playback_updated_at,
+       rules
 FROM rooms
 WHERE id::uuid = $1;
 `
 		var passwordHash string
 		var visibility string
+		var rules []byte
 		err := tx.QueryRow(ctx, q, roomID).Scan(
 			&snap.RoomID,
 			&snap.Name,
@@ -676,6 +900,7 @@ WHERE id::uuid = $1;
 			&snap.Playback.Paused,
 			&snap.Playback.PositionMS,
 			&snap.Playback.UpdatedAt,
+			&rules,
 		)
 		if errors.Is(err, pgx.ErrNoRows) {
 			return RoomSnapshot{}, core.ErrRoomNotFound
@@ -685,6 +910,9 @@ WHERE id::uuid = $1;
 		}
 		snap.Visibility = visibility
 		snap.HasPassword = passwordHash != ""
+		if snap.Rules, err = decodeRoomRules(rules); err != nil {
+			return RoomSnapshot{}, err
+		}
 	}
 
 	// Players
@@ -692,7 +920,7 @@ WHERE id::uuid = $1;
 		const q = `
 SELECT id::text, COALESCE(user_sub, '') AS user_sub, nickname, picture_url,
        CASE WHEN COALESCE(user_sub, '') = $2 THEN 0 ELSE score END AS score,
-       connected
+       connected, COALESCE(team_id::text, '')
 FROM room_players
 WHERE room_id::uuid = $1
 ORDER BY (COALESCE(user_sub, '') = $2) DESC, connected DESC, score DESC, nickname ASC;
@@ -706,7 +934,7 @@ ORDER BY (COALESCE(user_sub, '') = $2) DESC, connected DESC, score DESC, nicknam
 		players := make([]PlayerView, 0, 16)
 		for rows.Next() {
 			var pv PlayerView
-			if err := rows.Scan(&pv.PlayerID, &pv.Sub, &pv.Nickname, &pv.PictureURL, &pv.Score, &pv.Connected); err != nil {
+			if err := rows.Scan(&pv.PlayerID, &pv.Sub, &pv.Nickname, &pv.PictureURL, &pv.Score, &pv.Connected, &pv.TeamID); err != nil {
 				return RoomSnapshot{}, fmt.Errorf("get room players scan: %w", err)
 			}
 			players = append(players, pv)
@@ -717,6 +945,12 @@ ORDER BY (COALESCE(user_sub, '') = $2) DESC, connected DESC, score DESC, nicknam
 		snap.Players = players
 	}
 
+	teams, err := r.listTeamsTx(ctx, tx, roomID)
+	if err != nil {
+		return RoomSnapshot{}, err
+	}
+	snap.Teams = AggregateTeams(teams, snap.Players)
+
 	// Loaded playlist (optional)
 	if loadedPlaylistID != nil && *loadedPlaylistID != "" {
 		pl, err := r.getPlaylistByIDTx(ctx, tx, *loadedPlaylistID)
@@ -759,12 +993,12 @@ ORDER BY (COALESCE(user_sub, '') = $2) DESC, connected DESC, score DESC, nicknam
 
 func (r *Repo) getPlaylistByIDTx(ctx context.Context, tx pgx.Tx, playlistID string) (Playlist, error) {
 	const q = `
-SELECT id::text, owner_sub, name, created_at, updated_at
+SELECT id::text, owner_sub, name, visibility, created_at, updated_at
 FROM playlists
 WHERE id::uuid = $1 AND deleted_at IS NULL;
 `
 	var pl Playlist
-	if err := tx.QueryRow(ctx, q, playlistID).Scan(&pl.ID, &pl.OwnerSub, &pl.Name, &pl.CreatedAt, &pl.UpdatedAt); err != nil {
+	if err := tx.QueryRow(ctx, q, playlistID).Scan(&pl.ID, &pl.OwnerSub, &pl.Name, &pl.Visibility, &pl.CreatedAt, &pl.UpdatedAt); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return Playlist{}, ErrPlaylistNotFound
 		}
@@ -774,7 +1008,11 @@ WHERE id::uuid = $1 AND deleted_at IS NULL;
 }
 
 // JoinRoom inserts or reactivates a room_players row and returns join metadata.
-func (r *Repo) JoinRoom(ctx context.Context, roomID, userSub, nickname, pictureURL, password string) (JoinResult, error) {
+//
+// A valid resume claim reactivates the exact seat it names, without a password; an
+// invalid or expired one, or one naming a connected seat, is ignored and the join
+// proceeds as usual.
+func (r *Repo) JoinRoom(ctx context.Context, roomID, userSub, nickname, pictureURL, password string, resume *ResumeClaim) (JoinResult, error) {
 	if roomID == "" {
 		return JoinResult{}, core.ErrInvalidInput
 	}
@@ -795,7 +1033,14 @@ func (r *Repo) JoinRoom(ctx context.Context, roomID, userSub, nickname, pictureU
 			}
 			return JoinResult{}, fmt.Errorf("join room load: %w", err)
 		}
-		if userSub != "" && userSub == ownerSub {
+		if resume != nil {
+			if resume, err = r.checkResumeClaimTx(ctx, tx, roomID, *resume); err != nil {
+				return JoinResult{}, err
+			}
+		}
+		if resume != nil {
+			// The seat was already granted: no password needed to take it back.
+		} else if userSub != "" && userSub == ownerSub {
 			// Owner can always rejoin their own room without a password.
 		} else if passwordHash != "" && hashRoomPassword(password) != passwordHash {
 			return JoinResult{}, fmt.Errorf("%w: invalid room password", core.ErrUnauthorized)
@@ -813,21 +1058,38 @@ func (r *Repo) JoinRoom(ctx context.Context, roomID, userSub, nickname, pictureU
 			return JoinResult{}, err
 		}
 
-		if nickname == "" {
+		// A reclaimed seat keeps its own nickname and picture.
+		if nickname == "" && resume == nil {
 			nickname = profNick
 		}
-		if pictureURL == "" {
+		if pictureURL == "" && resume == nil {
 			pictureURL = profPic
 		}
-	} else if nickname == "" {
+	} else if nickname == "" && resume == nil {
 		nickname = "Anonymous"
 	}
 
 	isOwner := userSub != "" && userSub == ownerSub
 
 	var playerID string
-	// If the user already has a row in this room, flip it back to connected.
-	if userSub != "" {
+	if resume != nil {
+		// Reclaim the seat; keep its nickname and picture unless new ones are given.
+		const upd = `
+UPDATE room_players
+SET nickname = COALESCE(NULLIF($2, ''), nickname),
+    picture_url = COALESCE(NULLIF($3, ''), picture_url),
+    connected = TRUE,
+    left_at = NULL,
+    updated_at = now()
+WHERE id::uuid = $1;
+`
+		if _, err := tx.Exec(ctx, upd, resume.PlayerID, nickname, pictureURL); err != nil {
+			return JoinResult{}, fmt.Errorf("join room resume: %w", err)
+		}
+		playerID = resume.PlayerID
+		isOwner = false
+	} else if userSub != "" {
+		// If the user already has a row in this room, flip it back to connected.
 		const reactivateQ = `
 SELECT id::text FROM room_players
 WHERE room_id::uuid = $1 AND user_sub = $2
@@ -871,6 +1133,11 @@ RETURNING id::text;
 		}
 	}
 
+	resumeNonce, err := r.ensureResumeNonceTx(ctx, tx, playerID)
+	if err != nil {
+		return JoinResult{}, err
+	}
+
 	// Count connected players (including owner).
 	var connected int
 	{
@@ -918,6 +1185,8 @@ LIMIT 1;
 		OwnerConnected:  ownerConnected,
 		OwnerPlayerID:   ownerPlayerID,
 		OwnerWasOffline: ownerWasOffline,
+		Resumed:         resume != nil,
+		ResumeNonce:     resumeNonce,
 	}, nil
 }
 
@@ -1065,6 +1334,10 @@ WHERE rp.id::uuid = $1 AND rp.room_id::uuid = $2;
 		}
 	}
 
+	if err := r.saveDepartedStandingTx(ctx, tx, roomID, playerID); err != nil {
+		return err
+	}
+
 	const q = `DELETE FROM room_players WHERE id::uuid = $1 AND room_id::uuid = $2;`
 	ct, err := tx.Exec(ctx, q, playerID, roomID)
 	if err != nil {
@@ -1209,18 +1482,9 @@ func (r *Repo) LoadPlaylistToRoom(ctx context.Context, roomID, ownerSub, playlis
 		return core.ErrNotOwner
 	}
 
-	// Ensure playlist belongs to owner and isn't deleted.
-	const pQ = `
-SELECT 1
-FROM playlists
-WHERE id::uuid = $1 AND owner_sub = $2 AND deleted_at IS NULL;
-`
-	var one int
-	if err := tx.QueryRow(ctx, pQ, playlistID, ownerSub).Scan(&one); err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return ErrPlaylistNotFound
-		}
-		return fmt.Errorf("load playlist verify: %w", err)
+	// Ensure the owner can read the playlist (owned, shared or not private) and it isn't deleted.
+	if _, err := r.playlistAccessTx(ctx, tx, playlistID, ownerSub, false); err != nil {
+		return err
 	}
 
 	const q = `
@@ -1266,9 +1530,10 @@ func (r *Repo) SetPlayback(ctx context.Context, roomID, ownerSub string, trackIn
 
 	// Ensure there is a loaded playlist, and validate track index within range.
 	var loadedPlaylistID *string
+	var prevTrackIndex int
 	{
-		const q = `SELECT loaded_playlist_id::text FROM rooms WHERE id::uuid = $1 FOR UPDATE;`
-		if err := tx.QueryRow(ctx, q, roomID).Scan(&loadedPlaylistID); err != nil {
+		const q = `SELECT loaded_playlist_id::text, playback_track_index FROM rooms WHERE id::uuid = $1 FOR UPDATE;`
+		if err := tx.QueryRow(ctx, q, roomID).Scan(&loadedPlaylistID, &prevTrackIndex); err != nil {
 			if errors.Is(err, pgx.ErrNoRows) {
 				return core.ErrRoomNotFound
 			}
@@ -1278,14 +1543,25 @@ func (r *Repo) SetPlayback(ctx context.Context, roomID, ownerSub string, trackIn
 			return core.ErrInvalidInput
 		}
 	}
+	if trackIndex < 0 {
+		return core.ErrInvalidInput
+	}
+	// The target track, for its clip window.
+	var track PlaylistItem
 	{
-		const q = `SELECT COUNT(1) FROM playlist_items WHERE playlist_id::uuid = $1;`
-		var cnt int
-		if err := tx.QueryRow(ctx, q, *loadedPlaylistID).Scan(&cnt); err != nil {
-			return fmt.Errorf("set playback count tracks: %w", err)
-		}
-		if trackIndex < 0 || trackIndex >= cnt {
-			return core.ErrInvalidInput
+		const q = `
+SELECT start_ms, clip_duration_ms
+FROM playlist_items
+WHERE playlist_id::uuid = $1
+ORDER BY position ASC
+OFFSET $2
+LIMIT 1;
+`
+		if err := tx.QueryRow(ctx, q, *loadedPlaylistID, trackIndex).Scan(&track.StartMS, &track.ClipDurationMS); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return core.ErrInvalidInput
+			}
+			return fmt.Errorf("set playback load track: %w", err)
 		}
 	}
 
@@ -1322,12 +1598,14 @@ func (r *Repo) SetPlayback(ctx context.Context, roomID, ownerSub string, trackIn
 	newPos := 0
 	if positionMS != nil {
 		newPos = *positionMS
-	} else {
+	} else if trackIndex == prevTrackIndex {
 		const q = `SELECT playback_position_ms FROM rooms WHERE id::uuid = $1;`
 		if err := tx.QueryRow(ctx, q, roomID).Scan(&newPos); err != nil {
 			return fmt.Errorf("set playback read pos: %w", err)
 		}
 	}
+	// Playback begins at the clip start and never runs past its end.
+	newPos = track.ClampPosition(newPos)
 
 	_ = pausedVal
 	_ = posVal
@@ -1410,13 +1688,18 @@ func (r *Repo) Seek(ctx context.Context, roomID, ownerSub string, positionMS int
 		return core.ErrNotOwner
 	}
 
+	track, err := r.currentTrackClipTx(ctx, tx, roomID)
+	if err != nil {
+		return err
+	}
+
 	const q = `
 UPDATE rooms
 SET playback_position_ms = $3,
     playback_updated_at = now()
 WHERE id::uuid = $1 AND owner_sub = $2;
 `
-	ct, err := tx.Exec(ctx, q, roomID, ownerSub, positionMS)
+	ct, err := tx.Exec(ctx, q, roomID, ownerSub, track.ClampPosition(positionMS))
 	if err != nil {
 		return fmt.Errorf("seek: %w", err)
 	}
@@ -1514,6 +1797,11 @@ FOR UPDATE;
 				positionMS += elapsed
 			}
 		}
+		track, err := r.currentTrackClipTx(ctx, tx, roomID)
+		if err != nil {
+			return PlayerView{}, err
+		}
+		positionMS = track.ClampPosition(positionMS)
 
 		const upd = `
 UPDATE rooms
@@ -1572,6 +1860,11 @@ FOR UPDATE;
 	if elapsed > 0 {
 		positionMS += elapsed
 	}
+	track, err := r.currentTrackClipTx(ctx, tx, roomID)
+	if err != nil {
+		return err
+	}
+	positionMS = track.ClampPosition(positionMS)
 
 	const upd = `
 UPDATE rooms
@@ -0,0 +1,108 @@
+package namethattune
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Seats (room_players rows) carry a random resume nonce. The HTTP layer signs it into a
+// resume token for the seat's player; presenting the token on join reclaims the exact
+// seat, score included, whoever the caller is (guests too). Seats left unclaimed for
+// longer than a grace period are removed by ExpireSeats.
+
+// DefaultSeatGracePeriod is how long a seat left by its player can be reclaimed.
+const DefaultSeatGracePeriod = 15 * time.Minute
+
+// ResumeClaim names the seat a resume token was issued for.
+type ResumeClaim struct {
+	PlayerID string
+	Nonce    string
+}
+
+// ExpiredSeat is a seat removed by ExpireSeats.
+type ExpiredSeat struct {
+	RoomID   string
+	PlayerID string
+}
+
+// checkResumeClaimTx returns the claim when it matches a seat of the room, locking that
+// seat, or nil when it does not. Only a seat its player left can be resumed, so a leaked
+// token cannot take over a seat in use. The owner seat cannot be resumed either: it would
+// hand out the owner controls, and the owner gets it back with their account.
+func (r *Repo) checkResumeClaimTx(ctx context.Context, tx pgx.Tx, roomID string, claim ResumeClaim) (*ResumeClaim, error) {
+	if claim.PlayerID == "" || claim.Nonce == "" {
+		return nil, nil
+	}
+	const q = `
+SELECT 1
+FROM room_players rp
+JOIN rooms rm ON rm.id = rp.room_id
+WHERE rp.id::uuid = $1 AND rp.room_id::uuid = $2 AND rp.resume_nonce = $3
+  AND NOT rp.connected
+  AND COALESCE(rp.user_sub, '') <> rm.owner_sub
+FOR UPDATE OF rp;
+`
+	var one int
+	if err := tx.QueryRow(ctx, q, claim.PlayerID, roomID, claim.Nonce).Scan(&one); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("check resume claim: %w", err)
+	}
+	return &claim, nil
+}
+
+// ensureResumeNonceTx returns the seat's resume nonce, generating it on first use.
+func (r *Repo) ensureResumeNonceTx(ctx context.Context, tx pgx.Tx, playerID string) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("resume nonce: %w", err)
+	}
+	const q = `
+UPDATE room_players
+SET resume_nonce = CASE WHEN resume_nonce = '' THEN $2 ELSE resume_nonce END
+WHERE id::uuid = $1
+RETURNING resume_nonce;
+`
+	var nonce string
+	if err := tx.QueryRow(ctx, q, playerID, base64.RawURLEncoding.EncodeToString(buf)).Scan(&nonce); err != nil {
+		return "", fmt.Errorf("resume nonce: %w", err)
+	}
+	return nonce, nil
+}
+
+// ExpireSeats removes the seats left more than grace ago, keeping their standing in the
+// room's open match. Owner seats are kept: the owner reclaims theirs with their account.
+func (r *Repo) ExpireSeats(ctx context.Context, grace time.Duration) ([]ExpiredSeat, error) {
+	const q = `
+WITH departed AS (
+  DELETE FROM room_players rp
+  USING rooms rm
+  WHERE rm.id = rp.room_id
+    AND NOT rp.connected
+    AND rp.left_at IS NOT NULL AND rp.left_at <= $1
+    AND COALESCE(rp.user_sub, '') <> rm.owner_sub
+  RETURNING rp.room_id, rp.id, rp.user_sub, rp.nickname, rp.picture_url, rp.score
+), standings AS (` + departedStandingQ + `)
+SELECT room_id::text, id::text FROM departed;
+`
+	rows, err := r.db.Query(ctx, q, time.Now().Add(-grace))
+	if err != nil {
+		return nil, fmt.Errorf("expire seats: %w", err)
+	}
+	seats, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (ExpiredSeat, error) {
+		var seat ExpiredSeat
+		err := row.Scan(&seat.RoomID, &seat.PlayerID)
+		return seat, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("expire seats scan: %w", err)
+	}
+	return seats, nil
+}
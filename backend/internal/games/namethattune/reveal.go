@@ -0,0 +1,101 @@
+package namethattune
+
+// Each track is a round that goes through phases: waiting (not started or paused),
+// playing, buzzed (a player holds the buzzer) and revealed (the answer was given, the
+// round timed out or the owner revealed it). Until the revealed phase, everything but
+// the owner sees a redacted view of the track: the YouTube ID stays, since clients need it
+// to play the video, but the title, URL, thumbnail and answer metadata are removed.
+
+// Round phases.
+const (
+	RoundPhaseWaiting  = "waiting"
+	RoundPhasePlaying  = "playing"
+	RoundPhaseBuzzed   = "buzzed"
+	RoundPhaseRevealed = "revealed"
+)
+
+// Phase returns the round phase of the current track given the playback pause state.
+func (p PlaybackState) Phase(paused bool) string {
+	switch {
+	case p.Revealed:
+		return RoundPhaseRevealed
+	case p.BuzzedBy != "":
+		return RoundPhaseBuzzed
+	case !paused:
+		return RoundPhasePlaying
+	default:
+		return RoundPhaseWaiting
+	}
+}
+
+// TrackReveal is the answer of a track, revealed to everyone when its round ends.
+type TrackReveal struct {
+	TrackIndex int    `json:"trackIndex"`
+	Title      string `json:"title"`
+	Artist     string `json:"artist,omitempty"`
+	SongTitle  string `json:"songTitle,omitempty"`
+	Year       *int   `json:"year,omitempty"`
+	YouTubeID  string `json:"youTubeID"`
+}
+
+// NewTrackReveal returns the reveal of the track at trackIndex.
+func NewTrackReveal(trackIndex int, it PlaylistItem) TrackReveal {
+	return TrackReveal{
+		TrackIndex: trackIndex,
+		Title:      it.Title,
+		Artist:     it.Artist,
+		SongTitle:  it.SongTitle,
+		Year:       it.Year,
+		YouTubeID:  it.YouTubeID,
+	}
+}
+
+// Redacted returns the item without anything that gives the answer away.
+func (it PlaylistItem) Redacted() PlaylistItem {
+	return PlaylistItem{
+		ID:              it.ID,
+		YouTubeID:       it.YouTubeID,
+		DurationSec:     it.DurationSec,
+		AddedAt:         it.AddedAt,
+		AcceptedAnswers: []string{},
+		StartMS:         it.StartMS,
+		ClipDurationMS:  it.ClipDurationMS,
+	}
+}
+
+// Redacted returns the snapshot as seen by players: every playlist item is redacted,
+// except the current track once revealed.
+func (s RoomSnapshot) Redacted() RoomSnapshot {
+	revealed := s.Playback.Phase == RoundPhaseRevealed
+	if s.Playlist != nil {
+		pv := *s.Playlist
+		pv.Items = make([]PlaylistItem, len(s.Playlist.Items))
+		for i, it := range s.Playlist.Items {
+			if revealed && i == s.Playback.TrackIndex {
+				pv.Items[i] = it
+				continue
+			}
+			pv.Items[i] = it.Redacted()
+		}
+		s.Playlist = &pv
+	}
+	if s.Playback.Track != nil && !revealed {
+		track := s.Playback.Track.Redacted()
+		s.Playback.Track = &track
+	}
+	return s
+}
+
+// Anonymized returns the snapshot without player subs, except the one of keepPlayerID
+// (the viewer's own entry). The owner sub is left to the caller.
+func (s RoomSnapshot) Anonymized(keepPlayerID string) RoomSnapshot {
+	players := make([]PlayerView, len(s.Players))
+	for i, p := range s.Players {
+		if p.PlayerID != keepPlayerID {
+			p.Sub = ""
+		}
+		players[i] = p
+	}
+	s.Players = players
+	return s
+}
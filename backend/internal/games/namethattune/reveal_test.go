@@ -0,0 +1,82 @@
+package namethattune
+
+import "testing"
+
+func TestPlaybackState_Phase(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		state  PlaybackState
+		paused bool
+		want   string
+	}{
+		{PlaybackState{}, true, RoundPhaseWaiting},
+		{PlaybackState{}, false, RoundPhasePlaying},
+		{PlaybackState{BuzzedBy: "p1"}, true, RoundPhaseBuzzed},
+		{PlaybackState{BuzzedBy: "p1", Revealed: true}, true, RoundPhaseRevealed},
+		{PlaybackState{Revealed: true}, false, RoundPhaseRevealed},
+	} {
+		if got := tc.state.Phase(tc.paused); got != tc.want {
+			t.Errorf("Phase(%+v, paused=%v) = %q, want %q", tc.state, tc.paused, got, tc.want)
+		}
+	}
+}
+
+func TestRoomSnapshot_Redacted(t *testing.T) {
+	t.Parallel()
+
+	items := []PlaylistItem{
+		{ID: "a", Title: "Queen - Bohemian Rhapsody", YouTubeURL: "https://youtu.be/fJ9rUzIMcZQ", YouTubeID: "fJ9rUzIMcZQ", ThumbnailURL: "https://i.ytimg.com/x.jpg", Artist: "Queen", SongTitle: "Bohemian Rhapsody", AcceptedAnswers: []string{"Bohemian"}, StartMS: 1000},
+		{ID: "b", Title: "ABBA - SOS", YouTubeID: "cvChjHcABPA"},
+	}
+	track := items[0]
+	snap := RoomSnapshot{
+		Playlist: &PlaylistView{Items: items},
+		Playback: PlaybackView{Phase: RoundPhasePlaying, TrackIndex: 0, Track: &track},
+	}
+
+	red := snap.Redacted()
+	if got := red.Playback.Track; got.Title != "" || got.YouTubeURL != "" || got.ThumbnailURL != "" || got.Artist != "" || len(got.AcceptedAnswers) != 0 {
+		t.Fatalf("expected current track to be redacted, got %+v", got)
+	}
+	if red.Playback.Track.YouTubeID != "fJ9rUzIMcZQ" || red.Playback.Track.StartMS != 1000 {
+		t.Fatalf("expected playback fields to be kept, got %+v", red.Playback.Track)
+	}
+	for _, it := range red.Playlist.Items {
+		if it.Title != "" {
+			t.Fatalf("expected every playlist item to be redacted, got %+v", it)
+		}
+	}
+	if snap.Playlist.Items[0].Title == "" || snap.Playback.Track.Title == "" {
+		t.Fatalf("expected Redacted not to modify the original snapshot")
+	}
+
+	snap.Playback.Phase = RoundPhaseRevealed
+	red = snap.Redacted()
+	if red.Playback.Track.Title != track.Title || red.Playlist.Items[0].Title != track.Title {
+		t.Fatalf("expected revealed track to be visible, got %+v", red.Playback.Track)
+	}
+	if red.Playlist.Items[1].Title != "" {
+		t.Fatalf("expected other tracks to stay redacted, got %+v", red.Playlist.Items[1])
+	}
+}
+
+func TestRoomSnapshot_Anonymized(t *testing.T) {
+	t.Parallel()
+
+	snap := RoomSnapshot{
+		OwnerSub: "owner",
+		Players:  []PlayerView{{PlayerID: "p1", Sub: "sub-1"}, {PlayerID: "p2", Sub: "sub-2"}},
+	}
+
+	anon := snap.Anonymized("p2")
+	if anon.Players[0].Sub != "" || anon.Players[1].Sub != "sub-2" {
+		t.Fatalf("expected only the kept player's sub, got %+v", anon.Players)
+	}
+	if anon.OwnerSub != "owner" {
+		t.Fatalf("expected the owner sub to be left alone, got %q", anon.OwnerSub)
+	}
+	if snap.Players[0].Sub != "sub-1" {
+		t.Fatalf("expected Anonymized not to modify the original snapshot")
+	}
+}
@@ -0,0 +1,340 @@
+package namethattune
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/valentin/bes-games/backend/internal/core"
+)
+
+// RoomState is the volatile, per-room state that is not part of the room snapshot tables:
+// access tokens, buzz cooldowns and the playback synchronization flags.
+//
+// It is owned by the HTTP/room layer and persisted through a RoomStateStore so it can
+// survive restarts and be shared by replicas.
+type RoomState struct {
+	OwnerToken     string               `json:"ownerToken,omitempty"`
+	DisplayToken   string               `json:"displayToken,omitempty"`
+	SpectatorToken string               `json:"spectatorToken,omitempty"`
+	PlayerTokens   map[string]string    `json:"playerTokens,omitempty"`
+	BuzzCooldowns  map[string]time.Time `json:"buzzCooldowns,omitempty"`
+	Playback       PlaybackState        `json:"playback"`
+}
+
+// PlaybackState tracks client readiness for the current track. It is reset whenever the
+// track changes.
+type PlaybackState struct {
+	// Buffering holds the player IDs currently reporting a stalled player.
+	Buffering map[string]bool `json:"buffering,omitempty"`
+	// Ready holds the player IDs that preloaded the current track.
+	Ready map[string]bool `json:"ready,omitempty"`
+	// WaitingReady is true when a start was requested but some players are not ready yet.
+	WaitingReady bool `json:"waitingReady,omitempty"`
+	// WaitingBuffer is true when playback is held until buffering players recover.
+	WaitingBuffer bool `json:"waitingBuffer,omitempty"`
+	// StartAt is the synchronized (server clock) start time of the current playback run.
+	StartAt *time.Time `json:"startAt,omitempty"`
+	// AutoPause is true when the server paused playback on its own (buffering).
+	AutoPause bool `json:"autoPause,omitempty"`
+	// Buzzes counts how many times each player buzzed (or answered) on the current track.
+	Buzzes map[string]int `json:"buzzes,omitempty"`
+	// SolvedBy is the player who typed the correct answer for the current track.
+	SolvedBy string `json:"solvedBy,omitempty"`
+	// RoundPlayedMS is how long the current round played before its last suspension.
+	RoundPlayedMS int64 `json:"roundPlayedMs,omitempty"`
+	// RoundResumedAt is when the round timer last resumed; nil while it is suspended.
+	RoundResumedAt *time.Time `json:"roundResumedAt,omitempty"`
+	// BuzzedBy is the player holding the buzzer, until the owner resolves the buzz.
+	BuzzedBy string `json:"buzzedBy,omitempty"`
+	// Revealed is true once the answer of the current track was revealed.
+	Revealed bool `json:"revealed,omitempty"`
+}
+
+// RoomStateStore persists RoomState per room.
+//
+// Update must apply fn atomically with respect to other updates of the same room.
+// A missing room state behaves as the zero RoomState.
+type RoomStateStore interface {
+	Load(ctx context.Context, roomID string) (RoomState, error)
+	Update(ctx context.Context, roomID string, fn func(*RoomState)) (RoomState, error)
+	Delete(ctx context.Context, roomID string) error
+}
+
+// Clone returns a deep copy of the state.
+func (st RoomState) Clone() RoomState {
+	out := st
+	out.PlayerTokens = cloneMap(st.PlayerTokens)
+	out.BuzzCooldowns = cloneMap(st.BuzzCooldowns)
+	out.Playback.Buffering = cloneMap(st.Playback.Buffering)
+	out.Playback.Ready = cloneMap(st.Playback.Ready)
+	out.Playback.Buzzes = cloneMap(st.Playback.Buzzes)
+	if st.Playback.StartAt != nil {
+		t := *st.Playback.StartAt
+		out.Playback.StartAt = &t
+	}
+	if st.Playback.RoundResumedAt != nil {
+		t := *st.Playback.RoundResumedAt
+		out.Playback.RoundResumedAt = &t
+	}
+	return out
+}
+
+// SetBuffering records a player's buffering state and reports whether it changed.
+func (p *PlaybackState) SetBuffering(playerID string, buffering bool) bool {
+	prev := p.Buffering[playerID]
+	if !buffering {
+		delete(p.Buffering, playerID)
+		return prev
+	}
+	if p.Buffering == nil {
+		p.Buffering = make(map[string]bool)
+	}
+	p.Buffering[playerID] = true
+	return !prev
+}
+
+// SetReady records whether a player preloaded the current track.
+func (p *PlaybackState) SetReady(playerID string, ready bool) {
+	if !ready {
+		delete(p.Ready, playerID)
+		return
+	}
+	if p.Ready == nil {
+		p.Ready = make(map[string]bool)
+	}
+	p.Ready[playerID] = true
+}
+
+// ResumeRound starts counting round time from at (a no-op if already running). Playing a
+// track whose answer was revealed starts a new round: time, answer and solver are reset.
+func (p *PlaybackState) ResumeRound(at time.Time) {
+	if p.RoundResumedAt != nil {
+		return
+	}
+	if p.Revealed {
+		p.Revealed = false
+		p.SolvedBy = ""
+		p.RoundPlayedMS = 0
+	}
+	at = at.UTC()
+	p.RoundResumedAt = &at
+}
+
+// SuspendRound stops counting round time at at, keeping the time played so far.
+func (p *PlaybackState) SuspendRound(at time.Time) {
+	if p.RoundResumedAt == nil {
+		return
+	}
+	if played := at.Sub(*p.RoundResumedAt); played > 0 {
+		p.RoundPlayedMS += played.Milliseconds()
+	}
+	p.RoundResumedAt = nil
+}
+
+// RoundRemaining returns how much of a round of the given duration is left at now.
+func (p PlaybackState) RoundRemaining(duration time.Duration, now time.Time) time.Duration {
+	played := time.Duration(p.RoundPlayedMS) * time.Millisecond
+	if p.RoundResumedAt != nil && now.After(*p.RoundResumedAt) {
+		played += now.Sub(*p.RoundResumedAt)
+	}
+	if remaining := duration - played; remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// BufferingPlayers returns the connected players currently buffering.
+func (p PlaybackState) BufferingPlayers(players []PlayerView) []string {
+	if len(p.Buffering) == 0 {
+		return nil
+	}
+	out := make([]string, 0, len(p.Buffering))
+	for _, pl := range players {
+		if pl.Connected && p.Buffering[pl.PlayerID] {
+			out = append(out, pl.PlayerID)
+		}
+	}
+	return out
+}
+
+// NotReadyPlayers returns the connected players that have not preloaded the current track.
+func (p PlaybackState) NotReadyPlayers(players []PlayerView) []string {
+	out := make([]string, 0, len(players))
+	for _, pl := range players {
+		if pl.Connected && !p.Ready[pl.PlayerID] {
+			out = append(out, pl.PlayerID)
+		}
+	}
+	return out
+}
+
+// AllPlayersReady reports whether every connected player preloaded the current track.
+func (p PlaybackState) AllPlayersReady(players []PlayerView) bool {
+	return len(p.NotReadyPlayers(players)) == 0
+}
+
+func cloneMap[K comparable, V any](m map[K]V) map[K]V {
+	if m == nil {
+		return nil
+	}
+	out := make(map[K]V, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// ============================
+// In-memory store
+// ============================
+
+// MemoryRoomStateStore keeps room state in process memory (single instance deployments).
+type MemoryRoomStateStore struct {
+	mu    sync.Mutex
+	rooms map[string]*RoomState
+}
+
+func NewMemoryRoomStateStore() *MemoryRoomStateStore {
+	return &MemoryRoomStateStore{rooms: make(map[string]*RoomState)}
+}
+
+func (m *MemoryRoomStateStore) Load(_ context.Context, roomID string) (RoomState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if st := m.rooms[roomID]; st != nil {
+		return st.Clone(), nil
+	}
+	return RoomState{}, nil
+}
+
+func (m *MemoryRoomStateStore) Update(_ context.Context, roomID string, fn func(*RoomState)) (RoomState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st := m.rooms[roomID]
+	if st == nil {
+		st = &RoomState{}
+		m.rooms[roomID] = st
+	}
+	fn(st)
+	return st.Clone(), nil
+}
+
+func (m *MemoryRoomStateStore) Delete(_ context.Context, roomID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.rooms, roomID)
+	return nil
+}
+
+// ============================
+// Postgres store
+// ============================
+
+// PostgresRoomStateStore persists room state as JSONB so it survives restarts and can be
+// shared by replicas.
+//
+// Schema expectations (see migrations/0007_room_state.sql):
+// - room_state(room_id UUID PK FK rooms ON DELETE CASCADE, state JSONB, updated_at)
+type PostgresRoomStateStore struct {
+	db *pgxpool.Pool
+}
+
+func NewPostgresRoomStateStore(db *pgxpool.Pool) *PostgresRoomStateStore {
+	return &PostgresRoomStateStore{db: db}
+}
+
+func (p *PostgresRoomStateStore) Load(ctx context.Context, roomID string) (RoomState, error) {
+	if roomID == "" {
+		return RoomState{}, core.ErrInvalidInput
+	}
+
+	const q = `SELECT state FROM room_state WHERE room_id::uuid = $1;`
+	var raw []byte
+	err := p.db.QueryRow(ctx, q, roomID).Scan(&raw)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return RoomState{}, nil
+	}
+	if err != nil {
+		return RoomState{}, fmt.Errorf("load room state: %w", err)
+	}
+
+	var st RoomState
+	if err := json.Unmarshal(raw, &st); err != nil {
+		return RoomState{}, fmt.Errorf("decode room state: %w", err)
+	}
+	return st, nil
+}
+
+func (p *PostgresRoomStateStore) Update(ctx context.Context, roomID string, fn func(*RoomState)) (RoomState, error) {
+	if roomID == "" {
+		return RoomState{}, core.ErrInvalidInput
+	}
+
+	tx, err := p.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return RoomState{}, fmt.Errorf("update room state begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// Make sure a row exists so it can be locked.
+	{
+		const q = `
+INSERT INTO room_state (room_id, state)
+SELECT id, '{}'::jsonb FROM rooms WHERE id::uuid = $1
+ON CONFLICT (room_id) DO NOTHING;
+`
+		if _, err := tx.Exec(ctx, q, roomID); err != nil {
+			return RoomState{}, fmt.Errorf("update room state ensure: %w", err)
+		}
+	}
+
+	var raw []byte
+	{
+		const q = `SELECT state FROM room_state WHERE room_id::uuid = $1 FOR UPDATE;`
+		err := tx.QueryRow(ctx, q, roomID).Scan(&raw)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return RoomState{}, core.ErrRoomNotFound
+		}
+		if err != nil {
+			return RoomState{}, fmt.Errorf("update room state load: %w", err)
+		}
+	}
+
+	var st RoomState
+	if err := json.Unmarshal(raw, &st); err != nil {
+		return RoomState{}, fmt.Errorf("update room state decode: %w", err)
+	}
+	fn(&st)
+
+	encoded, err := json.Marshal(st)
+	if err != nil {
+		return RoomState{}, fmt.Errorf("update room state encode: %w", err)
+	}
+
+	const upd = `UPDATE room_state SET state = $2::jsonb, updated_at = now() WHERE room_id::uuid = $1;`
+	if _, err := tx.Exec(ctx, upd, roomID, string(encoded)); err != nil {
+		return RoomState{}, fmt.Errorf("update room state: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return RoomState{}, fmt.Errorf("update room state commit: %w", err)
+	}
+	return st, nil
+}
+
+func (p *PostgresRoomStateStore) Delete(ctx context.Context, roomID string) error {
+	if roomID == "" {
+		return core.ErrInvalidInput
+	}
+	const q = `DELETE FROM room_state WHERE room_id::uuid = $1;`
+	if _, err := p.db.Exec(ctx, q, roomID); err != nil {
+		return fmt.Errorf("delete room state: %w", err)
+	}
+	return nil
+}
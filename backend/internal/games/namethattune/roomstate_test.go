@@ -0,0 +1,72 @@
+package namethattune
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPlaybackState_RoundTimerSuspends(t *testing.T) {
+	t.Parallel()
+
+	t0 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	round := 20 * time.Second
+	var p PlaybackState
+
+	if got := p.RoundRemaining(round, t0); got != round {
+		t.Fatalf("expected a full round before it starts, got %s", got)
+	}
+
+	// Scheduled start in the future: nothing is counted until then.
+	p.ResumeRound(t0.Add(time.Second))
+	if got := p.RoundRemaining(round, t0); got != round {
+		t.Fatalf("expected a full round before the scheduled start, got %s", got)
+	}
+
+	// 5s played, then a buzz suspends the round for a while.
+	p.SuspendRound(t0.Add(6 * time.Second))
+	if got := p.RoundRemaining(round, t0.Add(time.Minute)); got != 15*time.Second {
+		t.Fatalf("expected suspended round to keep 15s, got %s", got)
+	}
+	p.SuspendRound(t0.Add(time.Minute))
+	if p.RoundPlayedMS != 5000 {
+		t.Fatalf("expected a second suspension to be a no-op, got %dms played", p.RoundPlayedMS)
+	}
+
+	p.ResumeRound(t0.Add(time.Minute))
+	p.ResumeRound(t0.Add(2 * time.Minute))
+	if got := p.RoundRemaining(round, t0.Add(time.Minute+10*time.Second)); got != 5*time.Second {
+		t.Fatalf("expected 5s left, got %s", got)
+	}
+	if got := p.RoundRemaining(round, t0.Add(2*time.Minute)); got != 0 {
+		t.Fatalf("expected an expired round, got %s", got)
+	}
+
+	clone := RoomState{Playback: p}.Clone()
+	*clone.Playback.RoundResumedAt = t0
+	if p.RoundResumedAt.Equal(t0) {
+		t.Fatalf("expected Clone to copy RoundResumedAt")
+	}
+}
+
+func TestPlaybackState_ReplayAfterRevealStartsNewRound(t *testing.T) {
+	t.Parallel()
+
+	t0 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	round := 20 * time.Second
+	p := PlaybackState{RoundPlayedMS: 20000, Revealed: true, SolvedBy: "p1"}
+
+	p.ResumeRound(t0)
+	if p.Revealed || p.SolvedBy != "" {
+		t.Fatalf("expected a new round to hide the answer again, got %+v", p)
+	}
+	if got := p.RoundRemaining(round, t0); got != round {
+		t.Fatalf("expected a full new round, got %s", got)
+	}
+
+	// Revealed while playing: the running round is left as is.
+	p.Revealed = true
+	p.ResumeRound(t0.Add(time.Second))
+	if !p.Revealed {
+		t.Fatalf("expected a running round to stay revealed")
+	}
+}
@@ -0,0 +1,178 @@
+package namethattune
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/valentin/bes-games/backend/internal/core"
+)
+
+// Answer modes.
+const (
+	// AnswerModeBuzzer: players buzz and the owner judges the answer (buzz.resolve).
+	AnswerModeBuzzer = "buzzer"
+	// AnswerModeTyped: players type answers (answer.submit), checked and scored by the server.
+	AnswerModeTyped = "typed"
+)
+
+// RoomRules are the house rules of a room: how buzzes are scored and what happens after
+// an answer. They are stored as JSONB on rooms; keys missing from the stored document
+// fall back to DefaultRoomRules.
+type RoomRules struct {
+	// PointsCorrect is awarded for a correct answer.
+	PointsCorrect int `json:"pointsCorrect"`
+	// PenaltyWrong is subtracted for a wrong answer.
+	PenaltyWrong int `json:"penaltyWrong"`
+	// CooldownMS is how long a player who answered wrong cannot buzz (0 disables it).
+	CooldownMS int `json:"cooldownMs"`
+	// SpeedBonusMax is the extra score for a correct answer buzzed at the very start of
+	// the track, decreasing linearly to 0 at SpeedBonusWindowMS.
+	SpeedBonusMax      int `json:"speedBonusMax"`
+	SpeedBonusWindowMS int `json:"speedBonusWindowMs"`
+	// MaxBuzzesPerTrack limits how many times each player can buzz (or answer, in typed
+	// mode) on a track (0 = unlimited).
+	MaxBuzzesPerTrack int `json:"maxBuzzesPerTrack"`
+	// AutoAdvance moves to the next track after a correct answer, a round timeout or the
+	// end of a clip.
+	AutoAdvance bool `json:"autoAdvance"`
+	// RoundDurationMS ends a track nobody found after that much playing time, pauses
+	// excluded (0 disables the round timer).
+	RoundDurationMS int `json:"roundDurationMs"`
+	// AnswerMode is AnswerModeBuzzer or AnswerModeTyped.
+	AnswerMode string `json:"answerMode"`
+	// TeamBuzzLock allows a single buzz (or typed answer) per team and track, and puts the
+	// whole team on cooldown after a wrong answer. Players without a team are unaffected.
+	TeamBuzzLock bool `json:"teamBuzzLock"`
+}
+
+// DefaultRoomRules are the historical rules: +1 per correct answer, a 5s cooldown after
+// a wrong one, and auto-advance.
+func DefaultRoomRules() RoomRules {
+	return RoomRules{
+		PointsCorrect: 1,
+		CooldownMS:    5000,
+		AutoAdvance:   true,
+		AnswerMode:    AnswerModeBuzzer,
+	}
+}
+
+// Validate checks the rules are within sane bounds.
+func (r RoomRules) Validate() error {
+	switch {
+	case r.PointsCorrect < 0 || r.PointsCorrect > 100:
+		return core.ErrInvalidInput
+	case r.PenaltyWrong < 0 || r.PenaltyWrong > 100:
+		return core.ErrInvalidInput
+	case r.CooldownMS < 0 || r.CooldownMS > 60_000:
+		return core.ErrInvalidInput
+	case r.SpeedBonusMax < 0 || r.SpeedBonusMax > 100:
+		return core.ErrInvalidInput
+	case r.SpeedBonusWindowMS < 0 || r.SpeedBonusWindowMS > 600_000:
+		return core.ErrInvalidInput
+	case r.SpeedBonusMax > 0 && r.SpeedBonusWindowMS == 0:
+		return core.ErrInvalidInput
+	case r.MaxBuzzesPerTrack < 0 || r.MaxBuzzesPerTrack > 100:
+		return core.ErrInvalidInput
+	case r.RoundDurationMS < 0 || r.RoundDurationMS > 600_000:
+		return core.ErrInvalidInput
+	case r.AnswerMode != AnswerModeBuzzer && r.AnswerMode != AnswerModeTyped:
+		return core.ErrInvalidInput
+	}
+	return nil
+}
+
+// SpeedBonus returns the bonus for a correct answer buzzed at positionMS into the track.
+func (r RoomRules) SpeedBonus(positionMS int) int {
+	if r.SpeedBonusMax <= 0 || r.SpeedBonusWindowMS <= 0 || positionMS >= r.SpeedBonusWindowMS {
+		return 0
+	}
+	if positionMS < 0 {
+		positionMS = 0
+	}
+	return r.SpeedBonusMax * (r.SpeedBonusWindowMS - positionMS) / r.SpeedBonusWindowMS
+}
+
+// MergeRoomRules applies a partial JSON document onto base. Unknown keys are rejected.
+func MergeRoomRules(base RoomRules, raw []byte) (RoomRules, error) {
+	if len(raw) == 0 {
+		return base, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&base); err != nil {
+		return RoomRules{}, core.ErrInvalidInput
+	}
+	if err := base.Validate(); err != nil {
+		return RoomRules{}, err
+	}
+	return base, nil
+}
+
+func decodeRoomRules(raw []byte) (RoomRules, error) {
+	rules := DefaultRoomRules()
+	if len(raw) == 0 {
+		return rules, nil
+	}
+	if err := json.Unmarshal(raw, &rules); err != nil {
+		return RoomRules{}, fmt.Errorf("decode room rules: %w", err)
+	}
+	return rules, nil
+}
+
+// GetRoomRules returns the rules of a room.
+func (r *Repo) GetRoomRules(ctx context.Context, roomID string) (RoomRules, error) {
+	if roomID == "" {
+		return RoomRules{}, core.ErrInvalidInput
+	}
+
+	const q = `SELECT rules FROM rooms WHERE id::uuid = $1;`
+	var raw []byte
+	if err := r.db.QueryRow(ctx, q, roomID).Scan(&raw); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return RoomRules{}, core.ErrRoomNotFound
+		}
+		return RoomRules{}, fmt.Errorf("get room rules: %w", err)
+	}
+	return decodeRoomRules(raw)
+}
+
+// SetRoomRules replaces the rules of a room (owner only).
+func (r *Repo) SetRoomRules(ctx context.Context, roomID, ownerSub string, rules RoomRules) error {
+	if roomID == "" || ownerSub == "" {
+		return core.ErrInvalidInput
+	}
+	if err := rules.Validate(); err != nil {
+		return err
+	}
+
+	encoded, err := json.Marshal(rules)
+	if err != nil {
+		return fmt.Errorf("encode room rules: %w", err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("set room rules begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if ok, err := r.isRoomOwnerTx(ctx, tx, roomID, ownerSub); err != nil {
+		return err
+	} else if !ok {
+		return core.ErrNotOwner
+	}
+
+	const q = `UPDATE rooms SET rules = $2::jsonb WHERE id::uuid = $1;`
+	if _, err := tx.Exec(ctx, q, roomID, string(encoded)); err != nil {
+		return fmt.Errorf("set room rules: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("set room rules commit: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package namethattune
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/valentin/bes-games/backend/internal/core"
+)
+
+// CheckRoomAccess checks that userSub may watch the room: the room exists and, when it has
+// a password, password matches (the owner needs none). Unlike JoinRoom, nothing is written:
+// spectators do not take a seat in room_players.
+func (r *Repo) CheckRoomAccess(ctx context.Context, roomID, userSub, password string) error {
+	if roomID == "" {
+		return core.ErrInvalidInput
+	}
+
+	const q = `SELECT owner_sub, password_hash FROM rooms WHERE id::uuid = $1;`
+	var ownerSub, passwordHash string
+	if err := r.db.QueryRow(ctx, q, roomID).Scan(&ownerSub, &passwordHash); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return core.ErrRoomNotFound
+		}
+		return fmt.Errorf("check room access: %w", err)
+	}
+	if userSub != "" && userSub == ownerSub {
+		return nil
+	}
+	if passwordHash != "" && hashRoomPassword(password) != passwordHash {
+		return fmt.Errorf("%w: invalid room password", core.ErrUnauthorized)
+	}
+	return nil
+}
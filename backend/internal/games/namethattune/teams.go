@@ -0,0 +1,234 @@
+package namethattune
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/valentin/bes-games/backend/internal/core"
+)
+
+// Rooms can split their players into teams (owner managed). Scores stay per player; team
+// scores are aggregated when the snapshot is built.
+
+// Team limits.
+const (
+	MaxTeamsPerRoom   = 16
+	MaxTeamNameLength = 40
+)
+
+// AggregateTeams fills in the score and player IDs of teams from the roster.
+func AggregateTeams(teams []TeamView, players []PlayerView) []TeamView {
+	if len(teams) == 0 {
+		return nil
+	}
+	index := make(map[string]int, len(teams))
+	out := make([]TeamView, len(teams))
+	for i, t := range teams {
+		t.Score = 0
+		t.PlayerIDs = []string{}
+		out[i] = t
+		index[t.TeamID] = i
+	}
+	for _, p := range players {
+		i, ok := index[p.TeamID]
+		if !ok {
+			continue
+		}
+		out[i].Score += p.Score
+		out[i].PlayerIDs = append(out[i].PlayerIDs, p.PlayerID)
+	}
+	return out
+}
+
+func (r *Repo) listTeamsTx(ctx context.Context, tx pgx.Tx, roomID string) ([]TeamView, error) {
+	const q = `
+SELECT id::text, name
+FROM room_teams
+WHERE room_id::uuid = $1
+ORDER BY created_at ASC, name ASC;
+`
+	rows, err := tx.Query(ctx, q, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("list teams: %w", err)
+	}
+	defer rows.Close()
+
+	var teams []TeamView
+	for rows.Next() {
+		var t TeamView
+		if err := rows.Scan(&t.TeamID, &t.Name); err != nil {
+			return nil, fmt.Errorf("list teams scan: %w", err)
+		}
+		teams = append(teams, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list teams rows: %w", err)
+	}
+	return teams, nil
+}
+
+// CreateTeam adds a team to the room (owner only). Team names are unique per room.
+func (r *Repo) CreateTeam(ctx context.Context, roomID, ownerSub, name string) (TeamView, error) {
+	name = strings.TrimSpace(name)
+	if roomID == "" || ownerSub == "" || name == "" || len(name) > MaxTeamNameLength {
+		return TeamView{}, core.ErrInvalidInput
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return TeamView{}, fmt.Errorf("create team begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if ok, err := r.isRoomOwnerTx(ctx, tx, roomID, ownerSub); err != nil {
+		return TeamView{}, err
+	} else if !ok {
+		return TeamView{}, core.ErrNotOwner
+	}
+
+	// Serialize team creation per room so the limit holds.
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM rooms WHERE id::uuid = $1 FOR UPDATE;`, roomID); err != nil {
+		return TeamView{}, fmt.Errorf("create team lock room: %w", err)
+	}
+	var count int
+	if err := tx.QueryRow(ctx, `SELECT COUNT(1) FROM room_teams WHERE room_id::uuid = $1;`, roomID).Scan(&count); err != nil {
+		return TeamView{}, fmt.Errorf("create team count: %w", err)
+	}
+	if count >= MaxTeamsPerRoom {
+		return TeamView{}, fmt.Errorf("%w: too many teams", core.ErrInvalidInput)
+	}
+
+	const q = `
+INSERT INTO room_teams (room_id, name)
+VALUES ($1::uuid, $2)
+ON CONFLICT (room_id, name) DO NOTHING
+RETURNING id::text;
+`
+	team := TeamView{Name: name, PlayerIDs: []string{}}
+	if err := tx.QueryRow(ctx, q, roomID, name).Scan(&team.TeamID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return TeamView{}, fmt.Errorf("%w: team name already taken", core.ErrInvalidInput)
+		}
+		return TeamView{}, fmt.Errorf("create team: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return TeamView{}, fmt.Errorf("create team commit: %w", err)
+	}
+	return team, nil
+}
+
+// DeleteTeam removes a team (owner only); its players become teamless and keep their
+// scores.
+func (r *Repo) DeleteTeam(ctx context.Context, roomID, ownerSub, teamID string) error {
+	if roomID == "" || ownerSub == "" || teamID == "" {
+		return core.ErrInvalidInput
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("delete team begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if ok, err := r.isRoomOwnerTx(ctx, tx, roomID, ownerSub); err != nil {
+		return err
+	} else if !ok {
+		return core.ErrNotOwner
+	}
+
+	const q = `DELETE FROM room_teams WHERE id::uuid = $1 AND room_id::uuid = $2;`
+	ct, err := tx.Exec(ctx, q, teamID, roomID)
+	if err != nil {
+		return fmt.Errorf("delete team: %w", err)
+	}
+	if ct.RowsAffected() == 0 {
+		return ErrTeamNotFound
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("delete team commit: %w", err)
+	}
+	return nil
+}
+
+// AssignTeam moves a player into a team (owner only). An empty teamID removes the player
+// from their team. The owner seat cannot join a team.
+func (r *Repo) AssignTeam(ctx context.Context, roomID, ownerSub, playerID, teamID string) error {
+	if roomID == "" || ownerSub == "" || playerID == "" {
+		return core.ErrInvalidInput
+	}
+
+	tx, err := r.db.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("assign team begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if ok, err := r.isRoomOwnerTx(ctx, tx, roomID, ownerSub); err != nil {
+		return err
+	} else if !ok {
+		return core.ErrNotOwner
+	}
+
+	if teamID != "" {
+		const q = `SELECT 1 FROM room_teams WHERE id::uuid = $1 AND room_id::uuid = $2;`
+		var one int
+		if err := tx.QueryRow(ctx, q, teamID, roomID).Scan(&one); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrTeamNotFound
+			}
+			return fmt.Errorf("assign team load team: %w", err)
+		}
+	}
+
+	const q = `
+UPDATE room_players
+SET team_id = NULLIF($3, '')::uuid
+WHERE id::uuid = $1 AND room_id::uuid = $2
+RETURNING COALESCE(user_sub, '');
+`
+	var playerSub string
+	if err := tx.QueryRow(ctx, q, playerID, roomID, teamID).Scan(&playerSub); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return core.ErrPlayerNotFound
+		}
+		return fmt.Errorf("assign team: %w", err)
+	}
+	if playerSub != "" && playerSub == ownerSub {
+		return core.ErrInvalidInput
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("assign team commit: %w", err)
+	}
+	return nil
+}
+
+// TeamMates returns the IDs of the players in playerID's team, playerID included, or nil
+// when the player has no team.
+func (r *Repo) TeamMates(ctx context.Context, roomID, playerID string) ([]string, error) {
+	if roomID == "" || playerID == "" {
+		return nil, core.ErrInvalidInput
+	}
+
+	const q = `
+SELECT mate.id::text
+FROM room_players me
+JOIN room_players mate ON mate.team_id = me.team_id
+WHERE me.id::uuid = $1 AND me.room_id::uuid = $2
+ORDER BY mate.id;
+`
+	rows, err := r.db.Query(ctx, q, playerID, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("team mates: %w", err)
+	}
+	mates, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("team mates scan: %w", err)
+	}
+	return mates, nil
+}
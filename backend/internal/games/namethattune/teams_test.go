@@ -0,0 +1,30 @@
+package namethattune
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAggregateTeams(t *testing.T) {
+	t.Parallel()
+
+	teams := []TeamView{{TeamID: "t1", Name: "Red"}, {TeamID: "t2", Name: "Blue"}}
+	players := []PlayerView{
+		{PlayerID: "p1", Score: 3, TeamID: "t1"},
+		{PlayerID: "p2", Score: -1, TeamID: "t1"},
+		{PlayerID: "p3", Score: 5},
+		{PlayerID: "p4", Score: 2, TeamID: "gone"},
+	}
+
+	got := AggregateTeams(teams, players)
+	want := []TeamView{
+		{TeamID: "t1", Name: "Red", Score: 2, PlayerIDs: []string{"p1", "p2"}},
+		{TeamID: "t2", Name: "Blue", Score: 0, PlayerIDs: []string{}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("AggregateTeams = %+v, want %+v", got, want)
+	}
+	if AggregateTeams(nil, players) != nil {
+		t.Fatalf("expected no teams without teams")
+	}
+}
@@ -0,0 +1,61 @@
+package namethattune
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Video titles look like "Artist - Song (Official Video) [4K]", "Artist – Song ft. Other",
+// "Artist \"Song\" | Live" or just "Song". ParseVideoTitle is best-effort: hosts can fix
+// the result through the PATCH item endpoint.
+
+var (
+	// titleNoiseRe matches bracketed groups that are not part of the song name.
+	titleNoiseRe = regexp.MustCompile(`(?i)\s*[(\[【][^)\]】]*\b(official|video|audio|lyrics?|lyric video|clip|visuali[sz]er|hd|hq|4k|remaster(ed)?|m/?v|color coded)\b[^)\]】]*[)\]】]`)
+	// titleFeatRe matches a featured-artist suffix, bracketed or not.
+	titleFeatRe = regexp.MustCompile(`(?i)\s*[(\[]?\s*\b(feat\.?|ft\.?|featuring)\s+([^)\]]+)[)\]]?`)
+	// titleQuotedRe matches `Artist "Song"`.
+	titleQuotedRe = regexp.MustCompile(`^(.+?)\s+["“«]\s*(.+?)\s*["”»]`)
+)
+
+// titleSeparators split artist from song, most common first.
+var titleSeparators = []string{" - ", " – ", " — ", " -- ", " | "}
+
+// ParseVideoTitle splits a YouTube video title into artist and song. artist is empty when
+// no separator is found.
+func ParseVideoTitle(raw string) (artist, song string) {
+	title := strings.TrimSpace(raw)
+	title = titleNoiseRe.ReplaceAllString(title, "")
+
+	// Anything after a pipe is usually a channel or event name.
+	if before, _, ok := strings.Cut(title, " | "); ok && strings.ContainsAny(before, "-–—") {
+		title = before
+	}
+
+	for _, sep := range titleSeparators {
+		if a, s, ok := strings.Cut(title, sep); ok {
+			artist, song = a, s
+			break
+		}
+	}
+	if song == "" {
+		if m := titleQuotedRe.FindStringSubmatch(title); m != nil {
+			artist, song = m[1], m[2]
+		} else {
+			song = title
+		}
+	}
+
+	// Featured artists belong with the artist, not the song name.
+	if m := titleFeatRe.FindStringSubmatchIndex(song); m != nil {
+		feat := strings.TrimSpace(song[m[4]:m[5]])
+		song = song[:m[0]] + song[m[1]:]
+		if artist != "" && feat != "" && !titleFeatRe.MatchString(artist) {
+			artist += " feat. " + feat
+		}
+	}
+
+	artist = strings.Trim(strings.TrimSpace(artist), `"“”«»`)
+	song = strings.Trim(strings.TrimSpace(song), `"“”«»`)
+	return strings.TrimSpace(artist), strings.TrimSpace(song)
+}
@@ -0,0 +1,24 @@
+package namethattune
+
+import "testing"
+
+func TestParseVideoTitle(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		in, artist, song string
+	}{
+		{"Rick Astley - Never Gonna Give You Up (Official Music Video)", "Rick Astley", "Never Gonna Give You Up"},
+		{"Daft Punk - Get Lucky ft. Pharrell Williams [4K]", "Daft Punk feat. Pharrell Williams", "Get Lucky"},
+		{"Stromae – Alors on danse (Clip Officiel)", "Stromae", "Alors on danse"},
+		{"Indochine - J'ai demandé à la lune (Live) | Paris 2020", "Indochine", "J'ai demandé à la lune (Live)"},
+		{`Queen "Bohemian Rhapsody" HD`, "Queen", "Bohemian Rhapsody"},
+		{"Bohemian Rhapsody", "", "Bohemian Rhapsody"},
+	}
+	for _, c := range cases {
+		artist, song := ParseVideoTitle(c.in)
+		if artist != c.artist || song != c.song {
+			t.Errorf("ParseVideoTitle(%q) = (%q, %q), want (%q, %q)", c.in, artist, song, c.artist, c.song)
+		}
+	}
+}
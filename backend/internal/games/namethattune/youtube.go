@@ -83,6 +83,40 @@ func ExtractYouTubeID(raw string) (string, error) {
 	return "", fmt.Errorf("unsupported youtube url")
 }
 
+// ExtractYouTubePlaylistID parses a YouTube playlist URL and extracts the playlist ID.
+//
+// Supported forms:
+// - https://www.youtube.com/playlist?list=<id>
+// - https://music.youtube.com/playlist?list=<id>
+// - https://www.youtube.com/watch?v=<video>&list=<id>
+func ExtractYouTubePlaylistID(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", fmt.Errorf("empty youtube playlist url")
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("invalid youtube playlist url")
+	}
+
+	host := strings.ToLower(u.Host)
+	host = strings.TrimPrefix(host, "www.")
+	host = strings.TrimPrefix(host, "m.")
+	if !strings.HasSuffix(host, "youtube.com") {
+		return "", fmt.Errorf("unsupported youtube playlist url")
+	}
+	if !strings.HasPrefix(u.Path, "/playlist") && !strings.HasPrefix(u.Path, "/watch") {
+		return "", fmt.Errorf("unsupported youtube playlist url")
+	}
+
+	id := strings.TrimSpace(u.Query().Get("list"))
+	if !isYouTubeID(id) {
+		return "", fmt.Errorf("invalid youtube playlist id")
+	}
+	return id, nil
+}
+
 type YouTubeMetadata struct {
 	Title        string
 	ThumbnailURL string
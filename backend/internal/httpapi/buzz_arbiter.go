@@ -0,0 +1,167 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/valentin/bes-games/backend/internal/games/namethattune"
+)
+
+// buzzArbitrationWindow is how long the server collects buzzes after the first one of a
+// round before picking a winner. It also caps how far back a claim can be compensated,
+// so a client can never claim a press older than the window.
+const buzzArbitrationWindow = 200 * time.Millisecond
+
+// errBuzzLost is returned to players whose buzz was beaten by an earlier press.
+var errBuzzLost = &apiError{Status: http.StatusConflict, Message: "buzz lost"}
+
+// buzzClaim is a buzz received by the server, with the client-reported timing used for
+// latency compensation.
+type buzzClaim struct {
+	PlayerID   string
+	ReceivedAt time.Time
+	// PositionMS is the client's playback position when the buzzer was pressed.
+	PositionMS *int
+	// ClientTS is the client's wall clock (unix ms) when the buzzer was pressed.
+	ClientTS *int64
+}
+
+// rankedBuzz is a claim with its estimated press time on the server clock.
+type rankedBuzz struct {
+	buzzClaim
+	PressedAt time.Time
+}
+
+// buzzArbiter groups the buzzes of a room arriving within buzzArbitrationWindow so the
+// earliest press wins rather than the first packet to reach the database.
+//
+// Arbitration is per process: buzzes landing on different replicas would be judged
+// separately, so every request of a room must reach the same replica (see the README).
+type buzzArbiter struct {
+	window time.Duration
+
+	mu   sync.Mutex
+	open map[string]*buzzRound
+}
+
+type buzzRound struct {
+	claims []buzzClaim
+	done   chan struct{}
+	winner string
+	err    error
+}
+
+func newBuzzArbiter(window time.Duration) *buzzArbiter {
+	return &buzzArbiter{
+		window: window,
+		open:   make(map[string]*buzzRound),
+	}
+}
+
+// submit adds a claim to the room's open round (opening one if needed) and blocks until
+// the round is resolved. The caller that opened the round runs resolve with every claim
+// collected during the window.
+//
+// It returns nil for the winner, errBuzzLost for other claimants, or resolve's error.
+func (a *buzzArbiter) submit(ctx context.Context, roomID string, claim buzzClaim, resolve func(ctx context.Context, claims []buzzClaim) (string, error)) error {
+	a.mu.Lock()
+	round := a.open[roomID]
+	leader := round == nil
+	if leader {
+		round = &buzzRound{done: make(chan struct{})}
+		a.open[roomID] = round
+	}
+	duplicate := false
+	for _, c := range round.claims {
+		if c.PlayerID == claim.PlayerID {
+			duplicate = true
+			break
+		}
+	}
+	if !duplicate {
+		round.claims = append(round.claims, claim)
+	}
+	a.mu.Unlock()
+
+	if leader {
+		time.Sleep(a.window)
+
+		a.mu.Lock()
+		delete(a.open, roomID)
+		claims := round.claims
+		a.mu.Unlock()
+
+		// Other claimants depend on this resolution: do not abort it if our client leaves.
+		round.winner, round.err = resolve(context.WithoutCancel(ctx), claims)
+		close(round.done)
+	} else {
+		select {
+		case <-round.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if round.err != nil {
+		return round.err
+	}
+	if round.winner != claim.PlayerID {
+		return errBuzzLost
+	}
+	return nil
+}
+
+// estimateBuzzPress estimates when a claim was pressed, on the server clock.
+//
+// A playback position is preferred since it does not depend on the client's clock: the
+// track started at the scheduled StartAt (or UpdatedAt) from PositionMS. A client
+// timestamp is only usable once the connection's clock offset is known. Otherwise the
+// receipt time is used. The estimate is clamped to [ReceivedAt-window, ReceivedAt].
+func estimateBuzzPress(claim buzzClaim, playback namethattune.PlaybackView, offset time.Duration, hasOffset bool, window time.Duration) time.Time {
+	est := claim.ReceivedAt
+	switch {
+	case claim.PositionMS != nil && !playback.Paused:
+		anchor := playback.UpdatedAt
+		if playback.StartAt != nil && playback.StartAt.After(anchor) {
+			anchor = *playback.StartAt
+		}
+		est = anchor.Add(time.Duration(*claim.PositionMS-playback.PositionMS) * time.Millisecond)
+	case claim.ClientTS != nil && hasOffset:
+		est = time.UnixMilli(*claim.ClientTS).Add(offset)
+	}
+
+	if earliest := claim.ReceivedAt.Add(-window); est.Before(earliest) {
+		est = earliest
+	}
+	if est.After(claim.ReceivedAt) {
+		est = claim.ReceivedAt
+	}
+	return est.UTC()
+}
+
+// rankBuzzClaims orders claims by estimated press time (earliest first), breaking ties
+// by receipt time.
+func rankBuzzClaims(roomID string, claims []buzzClaim, playback namethattune.PlaybackView, offsets func(roomID, playerID string) (time.Duration, bool), window time.Duration) []rankedBuzz {
+	out := make([]rankedBuzz, 0, len(claims))
+	for _, c := range claims {
+		var offset time.Duration
+		var ok bool
+		if offsets != nil {
+			offset, ok = offsets(roomID, c.PlayerID)
+		}
+		out = append(out, rankedBuzz{
+			buzzClaim: c,
+			PressedAt: estimateBuzzPress(c, playback, offset, ok, window),
+		})
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if !out[i].PressedAt.Equal(out[j].PressedAt) {
+			return out[i].PressedAt.Before(out[j].PressedAt)
+		}
+		return out[i].ReceivedAt.Before(out[j].ReceivedAt)
+	})
+	return out
+}
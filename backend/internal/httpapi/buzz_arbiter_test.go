@@ -0,0 +1,106 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/valentin/bes-games/backend/internal/games/namethattune"
+)
+
+func TestRankBuzzClaims_CompensatesLatency(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2026, 1, 1, 20, 0, 0, 0, time.UTC)
+	playback := namethattune.PlaybackView{PositionMS: 0, UpdatedAt: start.Add(-1500 * time.Millisecond), StartAt: &start}
+
+	fastPos, slowPos := 5080, 5020
+	claims := []buzzClaim{
+		// Fast connection, pressed later: arrives first.
+		{PlayerID: "fast", ReceivedAt: start.Add(5100 * time.Millisecond), PositionMS: &fastPos},
+		// Slow connection, pressed 60ms earlier: arrives 90ms later.
+		{PlayerID: "slow", ReceivedAt: start.Add(5190 * time.Millisecond), PositionMS: &slowPos},
+		// No timing: ranked by receipt.
+		{PlayerID: "plain", ReceivedAt: start.Add(5150 * time.Millisecond)},
+	}
+
+	ranked := rankBuzzClaims("room-1", claims, playback, nil, buzzArbitrationWindow)
+	got := []string{ranked[0].PlayerID, ranked[1].PlayerID, ranked[2].PlayerID}
+	want := []string{"slow", "fast", "plain"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected order %v, got %v", want, got)
+		}
+	}
+	if d := ranked[1].PressedAt.Sub(ranked[0].PressedAt); d != 60*time.Millisecond {
+		t.Fatalf("expected 60ms between slow and fast, got %s", d)
+	}
+}
+
+func TestEstimateBuzzPress_ClientTimestampAndClamp(t *testing.T) {
+	t.Parallel()
+
+	received := time.Date(2026, 1, 1, 20, 0, 5, 0, time.UTC)
+	paused := namethattune.PlaybackView{Paused: true}
+
+	// Client clock is 2s behind the server; it pressed 40ms before the server received it.
+	clientTS := received.Add(-2*time.Second - 40*time.Millisecond).UnixMilli()
+	claim := buzzClaim{PlayerID: "p", ReceivedAt: received, ClientTS: &clientTS}
+
+	if got := estimateBuzzPress(claim, paused, 0, false, buzzArbitrationWindow); !got.Equal(received) {
+		t.Fatalf("expected receipt time without a measured offset, got %s", got)
+	}
+	if got := estimateBuzzPress(claim, paused, 2*time.Second, true, buzzArbitrationWindow); !got.Equal(received.Add(-40 * time.Millisecond)) {
+		t.Fatalf("expected offset-corrected press time, got %s", got)
+	}
+
+	cheat := received.Add(-10 * time.Second).UnixMilli()
+	claim.ClientTS = &cheat
+	if got := estimateBuzzPress(claim, paused, 0, true, buzzArbitrationWindow); !got.Equal(received.Add(-buzzArbitrationWindow)) {
+		t.Fatalf("expected compensation to be clamped to the window, got %s", got)
+	}
+}
+
+func TestBuzzArbiter_SingleResolutionPerWindow(t *testing.T) {
+	t.Parallel()
+
+	arb := newBuzzArbiter(50 * time.Millisecond)
+	var mu sync.Mutex
+	resolutions := 0
+	resolve := func(_ context.Context, claims []buzzClaim) (string, error) {
+		mu.Lock()
+		resolutions++
+		mu.Unlock()
+		if len(claims) != 2 {
+			t.Errorf("expected 2 claims in the window, got %d", len(claims))
+		}
+		return "b", nil
+	}
+
+	errs := make(map[string]error)
+	var wg sync.WaitGroup
+	for _, id := range []string{"a", "b"} {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			err := arb.submit(context.Background(), "room-1", buzzClaim{PlayerID: id, ReceivedAt: time.Now()}, resolve)
+			mu.Lock()
+			errs[id] = err
+			mu.Unlock()
+		}(id)
+		time.Sleep(5 * time.Millisecond)
+	}
+	wg.Wait()
+
+	if resolutions != 1 {
+		t.Fatalf("expected one resolution, got %d", resolutions)
+	}
+	if errs["b"] != nil {
+		t.Fatalf("expected winner to succeed, got %v", errs["b"])
+	}
+	if !errors.Is(errs["a"], errBuzzLost) {
+		t.Fatalf("expected loser to get errBuzzLost, got %v", errs["a"])
+	}
+}
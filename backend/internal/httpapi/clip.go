@@ -0,0 +1,101 @@
+package httpapi
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/valentin/bes-games/backend/internal/games/namethattune"
+	"github.com/valentin/bes-games/backend/internal/realtime"
+)
+
+// clipTimer is the roomTimers name of the clip end timer.
+const clipTimer = "clip"
+
+// clipEndTolerance lets a clip end timer that fires slightly early still end the track.
+const clipEndTolerance = 50 * time.Millisecond
+
+// syncClipTimer (re)schedules the end of the current clip from a fresh snapshot, or cancels
+// it when nothing is playing. Every playback change re-arms it (see broadcastPlaybackChange).
+func (s *Server) syncClipTimer(roomID string, snap namethattune.RoomSnapshot) {
+	remaining, ok := snap.Playback.ClipRemaining(time.Now().UTC())
+	if !ok {
+		s.timers.cancel(roomID, clipTimer)
+		return
+	}
+	updatedAt := snap.Playback.UpdatedAt
+	s.timers.schedule(roomID, clipTimer, remaining, func() {
+		s.handleClipEnd(roomID, updatedAt)
+	})
+}
+
+// handleClipEnd ends the current track once its clip has been played: it moves to the next
+// track (paused) when the rules auto-advance, and pauses at the clip end otherwise.
+func (s *Server) handleClipEnd(roomID string, updatedAt time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	snap, err := s.loadRoomSnapshot(ctx, roomID)
+	if err != nil {
+		return
+	}
+	// Playback changed since the timer was armed; that change re-armed it.
+	if !snap.Playback.UpdatedAt.Equal(updatedAt) {
+		return
+	}
+	remaining, ok := snap.Playback.ClipRemaining(time.Now().UTC())
+	if !ok {
+		return
+	}
+	if remaining > clipEndTolerance {
+		s.syncClipTimer(roomID, snap)
+		return
+	}
+
+	trackIndex := snap.Playback.TrackIndex
+	advanced, err := s.endTimedTrack(ctx, roomID, snap)
+	if err != nil {
+		log.Printf("clip end failed: roomId=%s err=%v", roomID, err)
+		return
+	}
+
+	if s.rt != nil {
+		s.rt.Room(roomID).Broadcast(realtime.Event{
+			Type:   "playback.ended",
+			RoomID: roomID,
+			Payload: map[string]any{
+				"trackIndex": trackIndex,
+				"advanced":   advanced,
+			},
+		})
+	}
+	s.broadcastPlaybackChange(ctx, roomID)
+	if advanced {
+		s.broadcastPreload(ctx, roomID)
+	}
+}
+
+// endTimedTrack ends the current track on a server timer (clip end, round timeout): it
+// moves to the next track (paused) when the rules auto-advance and there is one, and
+// pauses in place otherwise. It reports whether playback advanced.
+func (s *Server) endTimedTrack(ctx context.Context, roomID string, snap namethattune.RoomSnapshot) (bool, error) {
+	next := snap.Playback.TrackIndex + 1
+	if snap.Rules.AutoAdvance && snap.Playlist != nil && next < len(snap.Playlist.Items) {
+		paused := true
+		if err := s.nttRepo.SetPlayback(ctx, roomID, snap.OwnerSub, next, &paused, nil); err != nil {
+			return false, err
+		}
+		if err := s.clearPlaybackState(roomID); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	if err := s.nttRepo.PausePlaybackWithPosition(ctx, roomID); err != nil {
+		return false, err
+	}
+	if err := s.markPlaybackStopped(roomID); err != nil {
+		return false, err
+	}
+	return false, nil
+}
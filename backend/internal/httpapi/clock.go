@@ -0,0 +1,130 @@
+package httpapi
+
+import (
+	"sync"
+	"time"
+
+	"github.com/valentin/bes-games/backend/internal/games/namethattune"
+)
+
+// clockSampleWindow is how many recent time.sync samples are kept per player. The sample
+// with the lowest RTT is the least disturbed by queuing and gives the best offset.
+const clockSampleWindow = 8
+
+// clockSample is one completed NTP-style exchange.
+// Offset is the server clock minus the client clock.
+type clockSample struct {
+	Offset time.Duration
+	RTT    time.Duration
+}
+
+// ntpSample computes offset and RTT from the four exchange timestamps (unix ms):
+// t0 client send, t1 server receive, t2 server send, t3 client receive.
+func ntpSample(t0, t1, t2, t3 int64) (clockSample, bool) {
+	rtt := (t3 - t0) - (t2 - t1)
+	if rtt < 0 || t2 < t1 {
+		return clockSample{}, false
+	}
+	offset := ((t1 - t0) + (t2 - t3)) / 2
+	return clockSample{
+		Offset: time.Duration(offset) * time.Millisecond,
+		RTT:    time.Duration(rtt) * time.Millisecond,
+	}, true
+}
+
+type playerClock struct {
+	connID  string
+	samples []clockSample
+}
+
+func (p *playerClock) best() clockSample {
+	best := p.samples[0]
+	for _, s := range p.samples[1:] {
+		if s.RTT < best.RTT {
+			best = s
+		}
+	}
+	return best
+}
+
+// clockTable tracks the measured clock offset and RTT of each player's connection.
+// It is process-local, like the WebSocket connections it describes.
+type clockTable struct {
+	mu    sync.Mutex
+	rooms map[string]map[string]*playerClock
+}
+
+func newClockTable() *clockTable {
+	return &clockTable{rooms: make(map[string]map[string]*playerClock)}
+}
+
+// record adds a sample for a player measured on connection connID. Samples from a new
+// connection replace the previous connection's.
+func (c *clockTable) record(roomID, playerID, connID string, sample clockSample) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	room := c.rooms[roomID]
+	if room == nil {
+		room = make(map[string]*playerClock)
+		c.rooms[roomID] = room
+	}
+	pc := room[playerID]
+	if pc == nil || pc.connID != connID {
+		pc = &playerClock{connID: connID}
+		room[playerID] = pc
+	}
+	pc.samples = append(pc.samples, sample)
+	if len(pc.samples) > clockSampleWindow {
+		pc.samples = pc.samples[len(pc.samples)-clockSampleWindow:]
+	}
+}
+
+func (c *clockTable) get(roomID, playerID string) (clockSample, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pc := c.rooms[roomID][playerID]
+	if pc == nil || len(pc.samples) == 0 {
+		return clockSample{}, false
+	}
+	return pc.best(), true
+}
+
+func (c *clockTable) offset(roomID, playerID string) (time.Duration, bool) {
+	sample, ok := c.get(roomID, playerID)
+	return sample.Offset, ok
+}
+
+// forgetConn drops the samples measured on a closed connection.
+func (c *clockTable) forgetConn(roomID, connID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	room := c.rooms[roomID]
+	for playerID, pc := range room {
+		if pc.connID == connID {
+			delete(room, playerID)
+		}
+	}
+	if len(room) == 0 {
+		delete(c.rooms, roomID)
+	}
+}
+
+func (c *clockTable) clearRoom(roomID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.rooms, roomID)
+}
+
+// decorate fills the latency of every measured player.
+func (c *clockTable) decorate(roomID string, players []namethattune.PlayerView) {
+	for i := range players {
+		sample, ok := c.get(roomID, players[i].PlayerID)
+		if !ok {
+			continue
+		}
+		players[i].Latency = &namethattune.PlayerLatency{
+			OffsetMS: sample.Offset.Milliseconds(),
+			RTTMS:    sample.RTT.Milliseconds(),
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package httpapi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClockTable_KeepsLowestRTTSample(t *testing.T) {
+	t.Parallel()
+
+	// Client 2s behind the server, 40ms RTT with 10ms server processing.
+	sample, ok := ntpSample(1000, 3015, 3025, 1050)
+	if !ok {
+		t.Fatalf("expected a valid sample")
+	}
+	if sample.Offset != 1995*time.Millisecond || sample.RTT != 40*time.Millisecond {
+		t.Fatalf("unexpected sample: %+v", sample)
+	}
+	if _, ok := ntpSample(1000, 3015, 3025, 1005); ok {
+		t.Fatalf("expected negative RTT to be rejected")
+	}
+
+	clocks := newClockTable()
+	clocks.record("room-1", "p1", "conn-1", clockSample{Offset: 2100 * time.Millisecond, RTT: 300 * time.Millisecond})
+	clocks.record("room-1", "p1", "conn-1", sample)
+	if got, _ := clocks.offset("room-1", "p1"); got != sample.Offset {
+		t.Fatalf("expected offset of the lowest RTT sample, got %s", got)
+	}
+
+	clocks.forgetConn("room-1", "conn-2")
+	if _, ok := clocks.get("room-1", "p1"); !ok {
+		t.Fatalf("expected samples of another connection to be kept")
+	}
+	clocks.forgetConn("room-1", "conn-1")
+	if _, ok := clocks.get("room-1", "p1"); ok {
+		t.Fatalf("expected samples to be dropped with their connection")
+	}
+}
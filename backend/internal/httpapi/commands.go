@@ -0,0 +1,180 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Room commands are the room.command messages of the room WebSocket:
+//
+//	{"type":"room.command","requestId":"42","payload":{"action":"buzz","playerId":"...","playerToken":"...",...}}
+//
+// Each action is a Command registered by a game module (see CommandModule) and is only
+// dispatched on the room WebSocket of that module's game. A command
+// declares the role its sender needs and the type its payload is decoded into; the
+// dispatcher checks the sender's credentials from the payload (ownerToken, or playerId and
+// playerToken) before running it. Errors are answered with room.command.error and, when the
+// message carried a requestId, successes with room.command.ok, both echoing the requestId.
+
+// Command roles.
+const (
+	// CommandOwner commands need the room's ownerToken.
+	CommandOwner = "owner"
+	// CommandPlayer commands need the playerId and playerToken of a seat.
+	CommandPlayer = "player"
+	// CommandAny commands can be sent by any connection but spectators.
+	CommandAny = "any"
+)
+
+// CommandContext describes who sent a command to which room.
+type CommandContext struct {
+	RoomID string
+	// OwnerSub is the room owner's sub, set for CommandOwner commands.
+	OwnerSub string
+	// PlayerID is the sender's seat, set for CommandPlayer commands.
+	PlayerID string
+}
+
+// CommandPayload is implemented by payload types that check their fields once decoded. A
+// Validate error is answered as invalid input.
+type CommandPayload interface {
+	Validate() error
+}
+
+// Command is a room command handler, built with NewCommand.
+type Command struct {
+	Action string
+	Role   string
+	run    func(ctx context.Context, cc CommandContext, raw json.RawMessage) error
+}
+
+// NewCommand returns the command for action, allowed to role, whose payload is decoded into
+// a P before handle runs.
+func NewCommand[P any](action, role string, handle func(ctx context.Context, cc CommandContext, p P) error) Command {
+	return Command{
+		Action: action,
+		Role:   role,
+		run: func(ctx context.Context, cc CommandContext, raw json.RawMessage) error {
+			var p P
+			if len(raw) > 0 {
+				if err := json.Unmarshal(raw, &p); err != nil {
+					return &apiError{Status: http.StatusBadRequest, Message: "invalid command payload"}
+				}
+			}
+			if v, ok := any(&p).(CommandPayload); ok {
+				if err := v.Validate(); err != nil {
+					return &apiError{Status: http.StatusBadRequest, Message: "invalid input"}
+				}
+			}
+			return handle(ctx, cc, p)
+		},
+	}
+}
+
+// CommandModule is a GameModule with room commands. Commands is called once, when the
+// server is created. Actions are scoped to the module's game, so two games can both have
+// a buzz; registering an action twice in the same game panics.
+type CommandModule interface {
+	GameModule
+	Commands(s *Server) []Command
+}
+
+// registerCommands adds the commands of a game's module to the server's registry.
+func (s *Server) registerCommands(gameID string, cmds []Command) {
+	registry := s.commands[gameID]
+	if registry == nil {
+		registry = make(map[string]Command)
+		s.commands[gameID] = registry
+	}
+	for _, cmd := range cmds {
+		if cmd.run == nil || strings.TrimSpace(cmd.Action) == "" {
+			panic("room command: invalid command " + cmd.Action)
+		}
+		switch cmd.Role {
+		case CommandOwner, CommandPlayer, CommandAny:
+		default:
+			panic("room command " + cmd.Action + ": unknown role " + cmd.Role)
+		}
+		if _, ok := registry[cmd.Action]; ok {
+			panic("room command " + cmd.Action + ": registered twice for game " + gameID)
+		}
+		registry[cmd.Action] = cmd
+	}
+}
+
+// commandCredentials are the sender fields common to every command payload.
+type commandCredentials struct {
+	Action      string `json:"action"`
+	OwnerToken  string `json:"ownerToken,omitempty"`
+	PlayerID    string `json:"playerId,omitempty"`
+	PlayerToken string `json:"playerToken,omitempty"`
+}
+
+// dispatchCommand checks the sender of a command payload and runs the command of game
+// gameID. It returns the action, for error reporting, and an *apiError on failure.
+// Spectator connections cannot send commands.
+func (s *Server) dispatchCommand(ctx context.Context, gameID, roomID string, spectating bool, raw json.RawMessage) (string, error) {
+	var creds commandCredentials
+	if err := json.Unmarshal(raw, &creds); err != nil {
+		return "", &apiError{Status: http.StatusBadRequest, Message: "invalid command payload"}
+	}
+	action := strings.TrimSpace(creds.Action)
+	if action == "" {
+		return "", &apiError{Status: http.StatusBadRequest, Message: "missing action"}
+	}
+	if spectating {
+		return action, &apiError{Status: http.StatusForbidden, Message: "spectators cannot send commands"}
+	}
+	cmd, ok := s.commands[gameID][action]
+	if !ok {
+		return action, &apiError{Status: http.StatusBadRequest, Message: "unknown action"}
+	}
+
+	cc := CommandContext{RoomID: roomID}
+	switch cmd.Role {
+	case CommandOwner:
+		ok, err := s.validateOwnerToken(roomID, creds.OwnerToken)
+		if err != nil {
+			status, msg := mapDomainErr(err)
+			return action, &apiError{Status: status, Message: msg}
+		}
+		if !ok {
+			return action, &apiError{Status: http.StatusUnauthorized, Message: "unauthorized"}
+		}
+		ownerSub, err := s.nttRepo.GetRoomOwner(ctx, roomID)
+		if err != nil {
+			status, msg := mapDomainErr(err)
+			return action, &apiError{Status: status, Message: msg}
+		}
+		if ownerSub == "" {
+			return action, &apiError{Status: http.StatusForbidden, Message: "forbidden"}
+		}
+		cc.OwnerSub = ownerSub
+	case CommandPlayer:
+		if creds.PlayerID == "" {
+			return action, &apiError{Status: http.StatusUnauthorized, Message: "unauthorized"}
+		}
+		ok, err := s.validatePlayerToken(roomID, creds.PlayerID, creds.PlayerToken)
+		if err != nil {
+			status, msg := mapDomainErr(err)
+			return action, &apiError{Status: status, Message: msg}
+		}
+		if !ok {
+			return action, &apiError{Status: http.StatusUnauthorized, Message: "unauthorized"}
+		}
+		cc.PlayerID = creds.PlayerID
+	}
+
+	if err := cmd.run(ctx, cc, raw); err != nil {
+		var apiErr *apiError
+		if !errors.As(err, &apiErr) {
+			status, msg := mapDomainErr(err)
+			return action, &apiError{Status: status, Message: msg}
+		}
+		return action, err
+	}
+	return action, nil
+}
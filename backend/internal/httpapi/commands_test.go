@@ -0,0 +1,172 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/valentin/bes-games/backend/internal/games"
+	"github.com/valentin/bes-games/backend/internal/games/namethattune"
+	"github.com/valentin/bes-games/backend/internal/realtime"
+)
+
+type echoPayload struct {
+	Text  string `json:"text"`
+	Times *int   `json:"times"`
+}
+
+func (p echoPayload) Validate() error {
+	if p.Times == nil {
+		return errors.New("missing times")
+	}
+	return nil
+}
+
+// echoModule is a game module that only brings room commands.
+type echoModule struct {
+	got chan CommandContext
+}
+
+func (echoModule) Meta() games.Game              { return games.Game{ID: "echo", Name: "Echo"} }
+func (echoModule) Mount(r chi.Router, s *Server) {}
+
+func (m echoModule) Commands(s *Server) []Command {
+	return []Command{
+		NewCommand("echo.say", CommandPlayer, func(ctx context.Context, cc CommandContext, p echoPayload) error {
+			if p.Text != "hello" || *p.Times != 2 {
+				return &apiError{Status: http.StatusTeapot, Message: "unexpected payload"}
+			}
+			m.got <- cc
+			return nil
+		}),
+		NewCommand("echo.ping", CommandAny, func(ctx context.Context, cc CommandContext, _ struct{}) error {
+			m.got <- cc
+			return nil
+		}),
+	}
+}
+
+func TestDispatchCommand_ChecksRoleAndPayload(t *testing.T) {
+	t.Parallel()
+
+	module := echoModule{got: make(chan CommandContext, 4)}
+	s := NewServer(nil, nil, realtime.NewRegistry(), nil, NewNameThatTuneModule(), module)
+	token, err := s.getOrCreatePlayerToken("room-1", "p1")
+	if err != nil {
+		t.Fatalf("player token: %v", err)
+	}
+
+	for name, tc := range map[string]struct {
+		payload    string
+		spectating bool
+		status     int
+	}{
+		"invalid json":    {`[`, false, http.StatusBadRequest},
+		"missing action":  {`{}`, false, http.StatusBadRequest},
+		"unknown action":  {`{"action":"echo.shout"}`, false, http.StatusBadRequest},
+		"spectator":       {`{"action":"echo.ping"}`, true, http.StatusForbidden},
+		"no token":        {`{"action":"echo.say","playerId":"p1","text":"hello","times":2}`, false, http.StatusUnauthorized},
+		"invalid payload": {`{"action":"echo.say","playerId":"p1","playerToken":"` + token + `","times":"2"}`, false, http.StatusBadRequest},
+		"failed validate": {`{"action":"echo.say","playerId":"p1","playerToken":"` + token + `","text":"hello"}`, false, http.StatusBadRequest},
+		"other game":      {`{"action":"kick","ownerToken":"nope","playerId":"p1"}`, false, http.StatusBadRequest},
+	} {
+		_, err := s.dispatchCommand(context.Background(), "echo", "room-1", tc.spectating, json.RawMessage(tc.payload))
+		if status, _ := mapAPIError(err); status != tc.status {
+			t.Fatalf("%s: expected status %d, got %d (%v)", name, tc.status, status, err)
+		}
+	}
+	_, err = s.dispatchCommand(context.Background(), "name-that-tune", "room-1", false,
+		json.RawMessage(`{"action":"kick","ownerToken":"nope","playerId":"p1"}`))
+	if status, _ := mapAPIError(err); status != http.StatusUnauthorized {
+		t.Fatalf("owner command: expected status 401, got %d (%v)", status, err)
+	}
+
+	action, err := s.dispatchCommand(context.Background(), "echo", "room-1", false,
+		json.RawMessage(`{"action":"echo.say","playerId":"p1","playerToken":"`+token+`","text":"hello","times":2}`))
+	if err != nil || action != "echo.say" {
+		t.Fatalf("expected echo.say to run, got action %q err %v", action, err)
+	}
+	if cc := <-module.got; cc != (CommandContext{RoomID: "room-1", PlayerID: "p1"}) {
+		t.Fatalf("unexpected command context %+v", cc)
+	}
+	if _, err := s.dispatchCommand(context.Background(), "echo", "room-1", false, json.RawMessage(`{"action":"echo.ping"}`)); err != nil {
+		t.Fatalf("expected echo.ping to run without credentials, got %v", err)
+	}
+	if cc := <-module.got; cc != (CommandContext{RoomID: "room-1"}) {
+		t.Fatalf("unexpected command context %+v", cc)
+	}
+}
+
+// otherEchoModule is another game with the same actions as echoModule.
+type otherEchoModule struct{ echoModule }
+
+func (otherEchoModule) Meta() games.Game { return games.Game{ID: "echo-2", Name: "Echo 2"} }
+
+func TestDispatchCommand_ScopedToGame(t *testing.T) {
+	t.Parallel()
+
+	echo := echoModule{got: make(chan CommandContext, 1)}
+	other := otherEchoModule{echoModule{got: make(chan CommandContext, 1)}}
+	s := NewServer(nil, nil, realtime.NewRegistry(), nil, echo, other)
+
+	if _, err := s.dispatchCommand(context.Background(), "echo-2", "room-1", false, json.RawMessage(`{"action":"echo.ping"}`)); err != nil {
+		t.Fatalf("expected echo.ping to run, got %v", err)
+	}
+	select {
+	case <-other.got:
+	case <-echo.got:
+		t.Fatalf("expected the command of the room's game to run")
+	}
+	if _, err := s.dispatchCommand(context.Background(), "", "room-1", false, json.RawMessage(`{"action":"echo.ping"}`)); err == nil {
+		t.Fatalf("expected no command outside a game")
+	}
+}
+
+func TestRegisterCommands_RejectsDuplicates(t *testing.T) {
+	t.Parallel()
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected registering an action twice to panic")
+		}
+	}()
+	module := echoModule{got: make(chan CommandContext, 1)}
+	NewServer(nil, nil, realtime.NewRegistry(), nil, module, module)
+}
+
+// failingStateStore is a room state store whose every call fails.
+type failingStateStore struct{}
+
+var errStateStore = errors.New("room state store down")
+
+func (failingStateStore) Load(context.Context, string) (namethattune.RoomState, error) {
+	return namethattune.RoomState{}, errStateStore
+}
+
+func (failingStateStore) Update(context.Context, string, func(*namethattune.RoomState)) (namethattune.RoomState, error) {
+	return namethattune.RoomState{}, errStateStore
+}
+
+func (failingStateStore) Delete(context.Context, string) error { return errStateStore }
+
+func TestDispatchCommand_StateStoreFailure(t *testing.T) {
+	t.Parallel()
+
+	module := echoModule{got: make(chan CommandContext, 1)}
+	s := NewServer(nil, nil, realtime.NewRegistry(), nil, module)
+	s.SetRoomStateStore(failingStateStore{})
+
+	if _, err := s.getOrCreatePlayerToken("room-1", "p1"); !errors.Is(err, errStateStore) {
+		t.Fatalf("expected the store error, got %v", err)
+	}
+	// A token that cannot be checked is a server error, not a rejected sender.
+	_, err := s.dispatchCommand(context.Background(), "echo", "room-1", false,
+		json.RawMessage(`{"action":"echo.say","playerId":"p1","playerToken":"t","text":"hello","times":2}`))
+	if status, _ := mapAPIError(err); status != http.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d (%v)", status, err)
+	}
+}
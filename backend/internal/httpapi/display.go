@@ -0,0 +1,306 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/valentin/bes-games/backend/internal/games/namethattune"
+	"github.com/valentin/bes-games/backend/internal/realtime"
+)
+
+// The host display is a read-only Server-Sent Events stream for a TV or projector. It is
+// opened with the room's display token (handed to the owner on join, next to ownerToken)
+// and carries curated display.* events instead of raw snapshots:
+//
+//   - display.round: the current round (phase, track as spectators see it, timing)
+//   - display.leaderboard: standings with rank and score changes, for animations
+//   - display.buzz: the player who won the buzzer
+//   - display.answer: the outcome of a typed answer (never the guess itself)
+//   - display.reveal: the answer of the round
+//   - display.closed: the room was closed; the stream ends
+//
+// display.round and display.leaderboard are only sent when they change.
+
+// displayPingInterval keeps idle display streams alive through proxies.
+const displayPingInterval = 25 * time.Second
+
+// displayRound is the payload of display.round.
+type displayRound struct {
+	Phase       string                     `json:"phase"`
+	TrackIndex  int                        `json:"trackIndex"`
+	TrackCount  int                        `json:"trackCount"`
+	Track       *namethattune.PlaylistItem `json:"track,omitempty"`
+	Paused      bool                       `json:"paused"`
+	PositionMS  int                        `json:"positionMs"`
+	StartAt     *time.Time                 `json:"startAt,omitempty"`
+	RoundEndsAt *time.Time                 `json:"roundEndsAt,omitempty"`
+}
+
+// displayStanding is a leaderboard entry. PreviousRank is 0 for players new to the board.
+type displayStanding struct {
+	PlayerID     string `json:"playerId"`
+	Nickname     string `json:"nickname"`
+	PictureURL   string `json:"pictureUrl,omitempty"`
+	Score        int    `json:"score"`
+	Delta        int    `json:"delta"`
+	Rank         int    `json:"rank"`
+	PreviousRank int    `json:"previousRank,omitempty"`
+}
+
+// displayCurator turns the room events of one display stream into display events. It
+// remembers what it last sent to only emit changes.
+type displayCurator struct {
+	round *displayRound
+	board []displayStanding
+}
+
+func (c *displayCurator) curate(ev realtime.Event) []realtime.Event {
+	out := func(typ string, payload any) []realtime.Event {
+		return []realtime.Event{{Type: typ, RoomID: ev.RoomID, Timestamp: ev.Timestamp, Payload: payload}}
+	}
+
+	switch ev.Type {
+	case "room.snapshot":
+		var snap namethattune.RoomSnapshot
+		if err := decodeEventPayload(ev.Payload, &snap); err != nil {
+			return nil
+		}
+		var events []realtime.Event
+		if round := newDisplayRound(snap); c.round == nil || !reflect.DeepEqual(*c.round, round) {
+			c.round = &round
+			events = append(events, out("display.round", round)...)
+		}
+		if board, changed := c.leaderboard(snap.Players); changed {
+			events = append(events, out("display.leaderboard", map[string]any{"standings": board})...)
+		}
+		return events
+	case "buzzer":
+		var p struct {
+			Player namethattune.PlayerView `json:"player"`
+		}
+		if err := decodeEventPayload(ev.Payload, &p); err != nil || p.Player.PlayerID == "" {
+			return nil
+		}
+		return out("display.buzz", map[string]any{
+			"playerId":   p.Player.PlayerID,
+			"nickname":   p.Player.Nickname,
+			"pictureUrl": p.Player.PictureURL,
+		})
+	case "answer.result":
+		var p struct {
+			PlayerID string `json:"playerId"`
+			Nickname string `json:"nickname"`
+			Correct  bool   `json:"correct"`
+			Points   int    `json:"points"`
+		}
+		if err := decodeEventPayload(ev.Payload, &p); err != nil {
+			return nil
+		}
+		return out("display.answer", p)
+	case "round.reveal":
+		var p struct {
+			Answer namethattune.TrackReveal `json:"answer"`
+			Reason string                   `json:"reason"`
+		}
+		if err := decodeEventPayload(ev.Payload, &p); err != nil {
+			return nil
+		}
+		return out("display.reveal", p)
+	case "room.closed":
+		var p struct {
+			Reason string `json:"reason"`
+		}
+		_ = decodeEventPayload(ev.Payload, &p)
+		return out("display.closed", p)
+	default:
+		return nil
+	}
+}
+
+func newDisplayRound(snap namethattune.RoomSnapshot) displayRound {
+	round := displayRound{
+		Phase:       snap.Playback.Phase,
+		TrackIndex:  snap.Playback.TrackIndex,
+		Track:       snap.Playback.Track,
+		Paused:      snap.Playback.Paused,
+		PositionMS:  snap.Playback.PositionMS,
+		StartAt:     snap.Playback.StartAt,
+		RoundEndsAt: snap.Playback.RoundEndsAt,
+	}
+	if snap.Playlist != nil {
+		round.TrackCount = len(snap.Playlist.Items)
+	}
+	return round
+}
+
+// leaderboard ranks players by score (ties share a rank) and reports whether the board
+// differs from the last one sent.
+func (c *displayCurator) leaderboard(players []namethattune.PlayerView) ([]displayStanding, bool) {
+	prev := make(map[string]displayStanding, len(c.board))
+	for _, st := range c.board {
+		prev[st.PlayerID] = st
+	}
+
+	board := make([]displayStanding, 0, len(players))
+	for _, p := range players {
+		board = append(board, displayStanding{
+			PlayerID:   p.PlayerID,
+			Nickname:   p.Nickname,
+			PictureURL: p.PictureURL,
+			Score:      p.Score,
+		})
+	}
+	sort.SliceStable(board, func(i, j int) bool {
+		if board[i].Score != board[j].Score {
+			return board[i].Score > board[j].Score
+		}
+		return board[i].Nickname < board[j].Nickname
+	})
+	for i := range board {
+		board[i].Rank = i + 1
+		if i > 0 && board[i].Score == board[i-1].Score {
+			board[i].Rank = board[i-1].Rank
+		}
+		if old, ok := prev[board[i].PlayerID]; ok {
+			board[i].PreviousRank = old.Rank
+			board[i].Delta = board[i].Score - old.Score
+		}
+	}
+
+	changed := c.board == nil || len(board) != len(c.board)
+	for i := 0; !changed && i < len(board); i++ {
+		old := c.board[i]
+		changed = board[i].PlayerID != old.PlayerID || board[i].Score != old.Score ||
+			board[i].Nickname != old.Nickname || board[i].PictureURL != old.PictureURL
+	}
+	if changed {
+		c.board = board
+	}
+	return board, changed
+}
+
+// decodeEventPayload decodes an event payload into v, whether it was broadcast locally
+// (a Go value) or relayed by another instance (raw JSON).
+func decodeEventPayload(payload any, v any) error {
+	raw, ok := payload.(json.RawMessage)
+	if !ok {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		raw = b
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// handleRoomDisplay streams the host display events of a room (see above). The display
+// token is passed as the displayToken query parameter, since EventSource cannot set
+// headers.
+func (s *Server) handleRoomDisplay(w http.ResponseWriter, r *http.Request) {
+	roomID := roomIDParam(r)
+	if s.rt == nil {
+		writeError(w, http.StatusInternalServerError, "realtime not configured")
+		return
+	}
+	ok, err := s.validateDisplayToken(roomID, r.URL.Query().Get("displayToken"))
+	if err != nil {
+		status, msg := mapDomainErr(err)
+		writeError(w, status, msg)
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	snap, err := s.loadRoomSnapshot(r.Context(), roomID)
+	if err != nil {
+		status, msg := mapDomainErr(err)
+		writeError(w, status, msg)
+		return
+	}
+
+	hub := s.rt.Room(roomID)
+	viewer := realtime.Viewer{Role: realtime.RoleDisplay}
+	events, cancel := hub.Subscribe(64, viewer)
+	defer cancel()
+
+	// The stream outlives the server's write timeout.
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	var curator displayCurator
+	send := func(evs []realtime.Event) error {
+		for _, ev := range evs {
+			b, err := json.Marshal(ev)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, b); err != nil {
+				return err
+			}
+		}
+		return rc.Flush()
+	}
+
+	initial, _ := hub.Render(realtime.Event{
+		Type:      "room.snapshot",
+		RoomID:    roomID,
+		Timestamp: time.Now().UTC(),
+		Payload:   snap,
+	}, viewer)
+	if err := send(curator.curate(initial)); err != nil {
+		return
+	}
+
+	ping := time.NewTicker(displayPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ping.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			if ev.RoomID == "" {
+				ev.RoomID = roomID
+			}
+			// Missed events: curate a fresh snapshot instead.
+			if ev.Type == realtime.EventResync {
+				snap, err := s.loadRoomSnapshot(r.Context(), roomID)
+				if err != nil {
+					return
+				}
+				ev, _ = hub.Render(realtime.Event{
+					Type:      "room.snapshot",
+					RoomID:    roomID,
+					Timestamp: time.Now().UTC(),
+					Payload:   snap,
+				}, viewer)
+			}
+			if err := send(curator.curate(ev)); err != nil {
+				return
+			}
+			if ev.Type == "room.closed" {
+				return
+			}
+		}
+	}
+}
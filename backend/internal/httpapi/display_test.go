@@ -0,0 +1,68 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/valentin/bes-games/backend/internal/games/namethattune"
+	"github.com/valentin/bes-games/backend/internal/realtime"
+)
+
+func TestDisplayCurator(t *testing.T) {
+	t.Parallel()
+
+	snap := namethattune.RoomSnapshot{
+		RoomID: "room-1",
+		Players: []namethattune.PlayerView{
+			{PlayerID: "p1", Nickname: "Alice", Score: 1},
+			{PlayerID: "p2", Nickname: "Bob", Score: 3},
+			{PlayerID: "p3", Nickname: "Carol", Score: 1},
+		},
+		Playlist: &namethattune.PlaylistView{Items: make([]namethattune.PlaylistItem, 4)},
+		Playback: namethattune.PlaybackView{Phase: namethattune.RoundPhaseWaiting, TrackIndex: 1, Paused: true},
+	}
+	snapshotEvent := func(snap namethattune.RoomSnapshot) realtime.Event {
+		return realtime.Event{Type: "room.snapshot", RoomID: "room-1", Payload: snap}
+	}
+
+	var c displayCurator
+	evs := c.curate(snapshotEvent(snap))
+	if len(evs) != 2 || evs[0].Type != "display.round" || evs[1].Type != "display.leaderboard" {
+		t.Fatalf("expected round and leaderboard, got %+v", evs)
+	}
+	if round := evs[0].Payload.(displayRound); round.TrackIndex != 1 || round.TrackCount != 4 || round.Phase != namethattune.RoundPhaseWaiting {
+		t.Fatalf("unexpected round %+v", round)
+	}
+	board := evs[1].Payload.(map[string]any)["standings"].([]displayStanding)
+	if board[0].PlayerID != "p2" || board[0].Rank != 1 || board[1].PlayerID != "p1" || board[1].Rank != 2 || board[2].Rank != 2 {
+		t.Fatalf("unexpected standings %+v", board)
+	}
+
+	if evs := c.curate(snapshotEvent(snap)); len(evs) != 0 {
+		t.Fatalf("expected an unchanged snapshot to emit nothing, got %+v", evs)
+	}
+
+	snap.Players = append([]namethattune.PlayerView(nil), snap.Players...)
+	snap.Players[2].Score = 5
+	evs = c.curate(snapshotEvent(snap))
+	if len(evs) != 1 || evs[0].Type != "display.leaderboard" {
+		t.Fatalf("expected a leaderboard update only, got %+v", evs)
+	}
+	board = evs[0].Payload.(map[string]any)["standings"].([]displayStanding)
+	if board[0].PlayerID != "p3" || board[0].Delta != 4 || board[0].PreviousRank != 2 || board[0].Rank != 1 {
+		t.Fatalf("expected Carol to climb to first, got %+v", board[0])
+	}
+
+	buzz := map[string]any{"player": namethattune.PlayerView{PlayerID: "p1", Nickname: "Alice"}}
+	raw, _ := json.Marshal(buzz)
+	for _, payload := range []any{buzz, json.RawMessage(raw)} {
+		evs = c.curate(realtime.Event{Type: "buzzer", RoomID: "room-1", Payload: payload})
+		if len(evs) != 1 || evs[0].Type != "display.buzz" || evs[0].Payload.(map[string]any)["nickname"] != "Alice" {
+			t.Fatalf("expected a display.buzz for Alice, got %+v", evs)
+		}
+	}
+
+	if evs := c.curate(realtime.Event{Type: "playback.preload", RoomID: "room-1"}); len(evs) != 0 {
+		t.Fatalf("expected other events to be dropped, got %+v", evs)
+	}
+}
@@ -1,6 +1,9 @@
 package httpapi
 
 import (
+	"context"
+	"net/http"
+
 	"github.com/go-chi/chi/v5"
 
 	"github.com/valentin/bes-games/backend/internal/games"
@@ -11,6 +14,23 @@ type GameModule interface {
 	Mount(r chi.Router, s *Server)
 }
 
+type gameIDCtxKey struct{}
+
+// withGameID tags the requests routed to a game module with the game's ID.
+func withGameID(id string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), gameIDCtxKey{}, id)))
+		})
+	}
+}
+
+// requestGameID returns the ID of the game whose routes serve r.
+func requestGameID(r *http.Request) string {
+	id, _ := r.Context().Value(gameIDCtxKey{}).(string)
+	return id
+}
+
 type nameThatTuneModule struct{}
 
 func NewNameThatTuneModule() GameModule {
@@ -32,14 +52,35 @@ func (nameThatTuneModule) Mount(r chi.Router, s *Server) {
 	r.Route("/rooms/{roomId}", func(rr chi.Router) {
 		rr.Get("/", s.handleGetRoom)
 		rr.Post("/join", s.handleJoinRoom)
+		rr.Post("/spectate", s.handleSpectateRoom)
 		rr.Post("/leave", s.handleLeaveRoom)
 		rr.Get("/ws", s.handleRoomWS)
+		rr.Get("/display", s.handleRoomDisplay)
 	})
 
 	r.Get("/playlists", s.requireAuth(s.handleListPlaylists))
 	r.Post("/playlists", s.requireAuth(s.handleCreatePlaylist))
+	r.Post("/playlists/import", s.requireAuth(s.handleImportPlaylistFile))
+	r.Get("/playlists/discover", s.handleDiscoverPlaylists)
+	r.Get("/playlists/{playlistId}", s.requireAuth(s.handleGetPlaylist))
+	r.Get("/playlists/trash", s.requireAuth(s.handleListTrashedPlaylists))
 	r.Patch("/playlists/{playlistId}", s.requireAuth(s.handlePatchPlaylist))
+	r.Delete("/playlists/{playlistId}", s.requireAuth(s.handleDeletePlaylist))
+	r.Post("/playlists/{playlistId}/restore", s.requireAuth(s.handleRestorePlaylist))
+	r.Get("/playlists/{playlistId}/collaborators", s.requireAuth(s.handleListPlaylistCollaborators))
+	r.Put("/playlists/{playlistId}/collaborators/{userSub}", s.requireAuth(s.handlePutPlaylistCollaborator))
+	r.Delete("/playlists/{playlistId}/collaborators/{userSub}", s.requireAuth(s.handleDeletePlaylistCollaborator))
 	r.Post("/playlists/{playlistId}/items", s.requireAuth(s.handleAddPlaylistItem))
+	r.Post("/playlists/{playlistId}/import", s.requireAuth(s.handleImportPlaylist))
+	r.Get("/playlists/{playlistId}/export", s.requireAuth(s.handleExportPlaylist))
+	r.Put("/playlists/{playlistId}/items/order", s.requireAuth(s.handleReorderPlaylistItems))
+	r.Post("/playlists/{playlistId}/items/delete", s.requireAuth(s.handleDeletePlaylistItems))
+	r.Post("/playlists/{playlistId}/items/move", s.requireAuth(s.handleMovePlaylistItems))
+	r.Post("/playlists/{playlistId}/shuffle", s.requireAuth(s.handleShufflePlaylist))
+	r.Post("/playlists/{playlistId}/duplicate", s.requireAuth(s.handleDuplicatePlaylist))
 	r.Patch("/playlists/{playlistId}/items/{itemId}", s.requireAuth(s.handlePatchPlaylistItem))
 	r.Delete("/playlists/{playlistId}/items/{itemId}", s.requireAuth(s.handleDeletePlaylistItem))
+
+	r.Get("/matches", s.requireAuth(s.handleListMatches))
+	r.Get("/matches/{matchId}", s.requireAuth(s.handleGetMatch))
 }
@@ -0,0 +1,196 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/valentin/bes-games/backend/internal/core"
+	"github.com/valentin/bes-games/backend/internal/realtime"
+)
+
+// A room WebSocket opened with ?playerId=&playerToken= is bound to that seat. The server
+// pings every connection; one that misses a pong is closed. Once the last connection of a
+// seat is gone for the presence timeout, the seat is marked disconnected exactly as if its
+// player had called /leave (owner timeout and room close included), and a new bound
+// connection marks it connected again. Both changes are broadcast as player.presence
+// events, followed by a snapshot so that later snapshots and patches agree with them.
+// Presence is process-local, like the connections it follows: the seats of a room are
+// only counted right when all of its WebSockets reach the same replica.
+
+const (
+	presencePingInterval = 15 * time.Second
+	presencePongTimeout  = 10 * time.Second
+
+	// DefaultPresenceTimeout is how long a seat stays connected without any bound
+	// connection, so that a page reload or a short network drop goes unnoticed.
+	DefaultPresenceTimeout = 30 * time.Second
+)
+
+type presenceKey struct {
+	roomID   string
+	playerID string
+}
+
+// presenceTable counts the live WebSocket connections bound to each seat.
+type presenceTable struct {
+	mu    sync.Mutex
+	conns map[presenceKey]int
+}
+
+func newPresenceTable() *presenceTable {
+	return &presenceTable{conns: make(map[presenceKey]int)}
+}
+
+// bind adds a connection to a seat and reports whether it is the seat's only one.
+func (p *presenceTable) bind(roomID, playerID string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := presenceKey{roomID: roomID, playerID: playerID}
+	p.conns[key]++
+	return p.conns[key] == 1
+}
+
+// unbind removes a connection from a seat and reports whether it was the seat's last one.
+func (p *presenceTable) unbind(roomID, playerID string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := presenceKey{roomID: roomID, playerID: playerID}
+	if p.conns[key] == 0 {
+		return false
+	}
+	p.conns[key]--
+	if p.conns[key] > 0 {
+		return false
+	}
+	delete(p.conns, key)
+	return true
+}
+
+// bound reports whether a seat has at least one live connection.
+func (p *presenceTable) bound(roomID, playerID string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.conns[presenceKey{roomID: roomID, playerID: playerID}] > 0
+}
+
+// SetPresenceTimeout sets how long a seat stays connected after its last WebSocket closed.
+func (s *Server) SetPresenceTimeout(d time.Duration) {
+	if d > 0 {
+		s.presenceTimeout = d
+	}
+}
+
+func presenceTimerName(playerID string) string {
+	return "presence:" + playerID
+}
+
+// presenceConnected binds a connection to a seat, reconnecting the seat if it was marked
+// disconnected.
+func (s *Server) presenceConnected(ctx context.Context, roomID, playerID string) {
+	if !s.presence.bind(roomID, playerID) {
+		return
+	}
+	s.timers.cancel(roomID, presenceTimerName(playerID))
+
+	reconnected, isOwner, err := s.nttRepo.ReconnectPlayer(ctx, roomID, playerID)
+	if err != nil {
+		log.Printf("presence reconnect failed: roomId=%s playerId=%s err=%v", roomID, playerID, err)
+		return
+	}
+	if !reconnected {
+		return
+	}
+	// Owner came back online: cancel pending shutdown.
+	if isOwner {
+		s.rooms.cancelOwnerTimeout(roomID)
+	}
+	s.broadcastPresence(roomID, playerID, true)
+	s.broadcastSnapshot(ctx, roomID)
+}
+
+// presenceDisconnected unbinds a connection from a seat. When it was the last one, the
+// seat is marked disconnected after the presence timeout unless a connection came back.
+func (s *Server) presenceDisconnected(roomID, playerID string) {
+	if !s.presence.unbind(roomID, playerID) {
+		return
+	}
+	s.timers.schedule(roomID, presenceTimerName(playerID), s.presenceTimeout, func() {
+		if s.presence.bound(roomID, playerID) {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.presenceLost(ctx, roomID, playerID); err != nil {
+			log.Printf("presence disconnect failed: roomId=%s playerId=%s err=%v", roomID, playerID, err)
+		}
+	})
+}
+
+// presenceLost marks a seat whose connections are gone as disconnected.
+func (s *Server) presenceLost(ctx context.Context, roomID, playerID string) error {
+	snap, err := s.loadRoomSnapshot(ctx, roomID)
+	if err != nil {
+		if errors.Is(err, core.ErrRoomNotFound) {
+			return nil
+		}
+		return err
+	}
+	// The player may have left or been kicked in the meantime.
+	connected := false
+	for _, p := range snap.Players {
+		if p.PlayerID == playerID {
+			connected = p.Connected
+			break
+		}
+	}
+	if !connected {
+		return nil
+	}
+
+	leaveRes, err := s.nttRepo.LeaveRoom(ctx, roomID, playerID)
+	if err != nil {
+		if errors.Is(err, core.ErrPlayerNotFound) {
+			return nil
+		}
+		return err
+	}
+	if s.afterLeave(ctx, roomID, leaveRes) != "" {
+		return nil
+	}
+	s.broadcastPresence(roomID, playerID, false)
+
+	// The player no longer holds back a readiness wait.
+	if snap, err = s.loadRoomSnapshot(ctx, roomID); err != nil {
+		return err
+	}
+	started, err := s.startWhenUnblocked(ctx, roomID, snap)
+	if err != nil {
+		return err
+	}
+	if started {
+		s.broadcastPlaybackChange(ctx, roomID)
+	} else {
+		s.broadcastSnapshot(ctx, roomID)
+	}
+	return nil
+}
+
+func (s *Server) broadcastPresence(roomID, playerID string, connected bool) {
+	if s.rt == nil {
+		return
+	}
+	s.rt.Room(roomID).Broadcast(realtime.Event{
+		Type:   "player.presence",
+		RoomID: roomID,
+		Payload: map[string]any{
+			"playerId":  playerID,
+			"connected": connected,
+		},
+	})
+}
@@ -0,0 +1,34 @@
+package httpapi
+
+import "testing"
+
+func TestPresenceTable_CountsConnectionsPerSeat(t *testing.T) {
+	t.Parallel()
+
+	p := newPresenceTable()
+	if !p.bind("room-1", "player-1") {
+		t.Fatalf("expected the first connection to be reported")
+	}
+	if p.bind("room-1", "player-1") {
+		t.Fatalf("expected a second connection not to be reported")
+	}
+	if !p.bind("room-2", "player-1") {
+		t.Fatalf("expected seats to be counted per room")
+	}
+
+	if p.unbind("room-1", "player-1") {
+		t.Fatalf("expected the seat to keep its other connection")
+	}
+	if !p.bound("room-1", "player-1") {
+		t.Fatalf("expected the seat to be bound")
+	}
+	if !p.unbind("room-1", "player-1") {
+		t.Fatalf("expected the last connection to be reported")
+	}
+	if p.bound("room-1", "player-1") {
+		t.Fatalf("expected the seat to be unbound")
+	}
+	if p.unbind("room-1", "player-1") {
+		t.Fatalf("expected unbinding an unbound seat to be a no-op")
+	}
+}
@@ -0,0 +1,104 @@
+package httpapi
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/valentin/bes-games/backend/internal/games/namethattune"
+)
+
+// resumeSigner signs seat resume tokens, "<playerId>.<nonce>.<mac>", where mac is an
+// HMAC-SHA256 over the room ID, player ID and nonce. The nonce is persisted with the seat,
+// so a token stays valid across restarts as long as the signing secret does.
+type resumeSigner struct {
+	key []byte
+}
+
+// newResumeSigner returns a signer for secret. Without a secret, a random key is used and
+// tokens only last as long as the process.
+func newResumeSigner(secret string) resumeSigner {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			panic("resume signer: " + err.Error())
+		}
+	}
+	return resumeSigner{key: key}
+}
+
+func (rs resumeSigner) mac(roomID, playerID, nonce string) string {
+	m := hmac.New(sha256.New, rs.key)
+	m.Write([]byte(roomID + "\x00" + playerID + "\x00" + nonce))
+	return base64.RawURLEncoding.EncodeToString(m.Sum(nil))
+}
+
+func (rs resumeSigner) sign(roomID, playerID, nonce string) string {
+	return playerID + "." + nonce + "." + rs.mac(roomID, playerID, nonce)
+}
+
+// parse verifies a token for roomID and returns the seat it claims.
+func (rs resumeSigner) parse(roomID, token string) (namethattune.ResumeClaim, bool) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+		return namethattune.ResumeClaim{}, false
+	}
+	if !hmac.Equal([]byte(parts[2]), []byte(rs.mac(roomID, parts[0], parts[1]))) {
+		return namethattune.ResumeClaim{}, false
+	}
+	return namethattune.ResumeClaim{PlayerID: parts[0], Nonce: parts[1]}, true
+}
+
+// SetResumeSecret sets the secret resume tokens are signed with. Without one, tokens do
+// not survive a restart and are not shared between replicas.
+func (s *Server) SetResumeSecret(secret string) {
+	if secret != "" {
+		s.resume = newResumeSigner(secret)
+	}
+}
+
+// SetSeatGracePeriod sets how long a seat left by its player can be reclaimed before
+// RunSeatExpiry removes it.
+func (s *Server) SetSeatGracePeriod(d time.Duration) {
+	if d > 0 {
+		s.seatGrace = d
+	}
+}
+
+// RunSeatExpiry removes seats left unclaimed past the grace period, every interval, until
+// ctx is done. A zero or negative interval disables the expiry.
+func (s *Server) RunSeatExpiry(ctx context.Context, interval time.Duration) {
+	runEvery(ctx, "seat expiry", interval, s.expireSeats)
+}
+
+func (s *Server) expireSeats(ctx context.Context) {
+	expireCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	seats, err := s.nttRepo.ExpireSeats(expireCtx, s.seatGrace)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("seat expiry failed: err=%v", err)
+		}
+		return
+	}
+	rooms := make(map[string]bool)
+	for _, seat := range seats {
+		if err := s.clearPlayerToken(seat.RoomID, seat.PlayerID); err != nil {
+			log.Printf("seat expiry clear token failed: roomId=%s playerId=%s err=%v", seat.RoomID, seat.PlayerID, err)
+		}
+		rooms[seat.RoomID] = true
+	}
+	for roomID := range rooms {
+		s.broadcastSnapshot(expireCtx, roomID)
+	}
+	if len(seats) > 0 {
+		log.Printf("seat expiry: removed %d seats in %d rooms", len(seats), len(rooms))
+	}
+}
@@ -0,0 +1,35 @@
+package httpapi
+
+import "testing"
+
+func TestResumeSigner(t *testing.T) {
+	t.Parallel()
+
+	rs := newResumeSigner("secret")
+	token := rs.sign("room-1", "player-1", "nonce-1")
+
+	claim, ok := rs.parse("room-1", token)
+	if !ok || claim.PlayerID != "player-1" || claim.Nonce != "nonce-1" {
+		t.Fatalf("expected token to round-trip, got %+v ok=%v", claim, ok)
+	}
+	if _, ok := newResumeSigner("secret").parse("room-1", token); !ok {
+		t.Fatalf("expected a signer with the same secret to accept the token")
+	}
+
+	for name, tc := range map[string]struct {
+		signer resumeSigner
+		roomID string
+		token  string
+	}{
+		"other room":   {rs, "room-2", token},
+		"other secret": {newResumeSigner("other"), "room-1", token},
+		"random key":   {newResumeSigner(""), "room-1", token},
+		"other player": {rs, "room-1", "player-2" + token[len("player-1"):]},
+		"malformed":    {rs, "room-1", "player-1.nonce-1"},
+		"empty":        {rs, "room-1", ""},
+	} {
+		if _, ok := tc.signer.parse(tc.roomID, tc.token); ok {
+			t.Errorf("%s: expected token to be rejected", name)
+		}
+	}
+}
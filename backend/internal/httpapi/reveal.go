@@ -0,0 +1,72 @@
+package httpapi
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/valentin/bes-games/backend/internal/games/namethattune"
+	"github.com/valentin/bes-games/backend/internal/realtime"
+)
+
+// Reasons of a round.reveal event.
+const (
+	revealReasonAnswer  = "answer"
+	revealReasonTimeout = "timeout"
+	revealReasonOwner   = "owner"
+)
+
+// revealTrack moves the current round to the revealed phase and broadcasts its answer.
+func (s *Server) revealTrack(roomID string, snap namethattune.RoomSnapshot, reason string) error {
+	if snap.Playback.Track == nil {
+		return nil
+	}
+	err := s.updatePlaybackState(roomID, func(p *namethattune.PlaybackState) {
+		p.Revealed = true
+		p.BuzzedBy = ""
+	})
+	if err != nil {
+		return err
+	}
+	if s.rt != nil {
+		s.rt.Room(roomID).Broadcast(realtime.Event{
+			Type:   "round.reveal",
+			RoomID: roomID,
+			Payload: map[string]any{
+				"answer": namethattune.NewTrackReveal(snap.Playback.TrackIndex, *snap.Playback.Track),
+				"reason": reason,
+			},
+		})
+	}
+	return nil
+}
+
+// doRoundReveal reveals the answer of the current track to every player (owner action).
+func (s *Server) doRoundReveal(ctx context.Context, roomID, sub string) (namethattune.RoomSnapshot, error) {
+	snap, err := s.loadRoomSnapshot(ctx, roomID)
+	if err != nil {
+		status, msg := mapDomainErr(err)
+		return namethattune.RoomSnapshot{}, &apiError{Status: status, Message: msg}
+	}
+	if snap.OwnerSub == "" || snap.OwnerSub != sub {
+		return namethattune.RoomSnapshot{}, &apiError{Status: http.StatusForbidden, Message: "forbidden"}
+	}
+	if snap.Playback.Track == nil {
+		return namethattune.RoomSnapshot{}, &apiError{Status: http.StatusBadRequest, Message: "no track loaded"}
+	}
+	if snap.Playback.Phase == namethattune.RoundPhaseRevealed {
+		return snap, nil
+	}
+
+	if err := s.revealTrack(roomID, snap, revealReasonOwner); err != nil {
+		status, msg := mapDomainErr(err)
+		return namethattune.RoomSnapshot{}, &apiError{Status: status, Message: msg}
+	}
+
+	snap, err = s.loadRoomSnapshot(ctx, roomID)
+	if err != nil {
+		status, msg := mapDomainErr(err)
+		return namethattune.RoomSnapshot{}, &apiError{Status: status, Message: msg}
+	}
+	s.broadcastSnapshot(ctx, roomID)
+	return snap, nil
+}
@@ -0,0 +1,184 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// Room command payloads of Name That Tune. The sender credentials (ownerToken, playerId and
+// playerToken) are read by the dispatcher; for owner commands, playerId names the target
+// player.
+
+var errMissingField = errors.New("missing field")
+
+type targetPayload struct {
+	PlayerID string `json:"playerId"`
+}
+
+type scoreAddPayload struct {
+	PlayerID string `json:"playerId"`
+	Delta    *int   `json:"delta"`
+}
+
+func (p scoreAddPayload) Validate() error { return requireFields(p.Delta != nil) }
+
+type scoreSetPayload struct {
+	PlayerID string `json:"playerId"`
+	Score    *int   `json:"score"`
+}
+
+func (p scoreSetPayload) Validate() error { return requireFields(p.Score != nil) }
+
+type rulesSetPayload struct {
+	Rules json.RawMessage `json:"rules"`
+}
+
+func (p rulesSetPayload) Validate() error { return requireFields(len(p.Rules) > 0) }
+
+type playlistLoadPayload struct {
+	PlaylistID string `json:"playlistId"`
+}
+
+type playbackSetPayload struct {
+	TrackIndex *int  `json:"trackIndex"`
+	Paused     *bool `json:"paused,omitempty"`
+	PositionMS *int  `json:"positionMs,omitempty"`
+}
+
+func (p playbackSetPayload) Validate() error { return requireFields(p.TrackIndex != nil) }
+
+type playbackPausePayload struct {
+	Paused *bool `json:"paused"`
+}
+
+func (p playbackPausePayload) Validate() error { return requireFields(p.Paused != nil) }
+
+type playbackSeekPayload struct {
+	PositionMS *int `json:"positionMs"`
+}
+
+func (p playbackSeekPayload) Validate() error { return requireFields(p.PositionMS != nil) }
+
+type playbackBufferPayload struct {
+	Buffering *bool `json:"buffering"`
+}
+
+func (p playbackBufferPayload) Validate() error { return requireFields(p.Buffering != nil) }
+
+type playbackReadyPayload struct {
+	Ready             *bool  `json:"ready"`
+	PlaybackUpdatedAt string `json:"playbackUpdatedAt,omitempty"`
+}
+
+func (p playbackReadyPayload) Validate() error { return requireFields(p.Ready != nil) }
+
+type buzzPayload struct {
+	PositionMS *int   `json:"positionMs,omitempty"`
+	ClientTS   *int64 `json:"clientTs,omitempty"`
+}
+
+type answerPayload struct {
+	Answer string `json:"answer"`
+}
+
+type buzzResolvePayload struct {
+	PlayerID string `json:"playerId"`
+	Correct  *bool  `json:"correct"`
+}
+
+func (p buzzResolvePayload) Validate() error { return requireFields(p.Correct != nil) }
+
+type teamCreatePayload struct {
+	Name string `json:"name"`
+}
+
+type teamDeletePayload struct {
+	TeamID string `json:"teamId"`
+}
+
+type teamAssignPayload struct {
+	PlayerID string `json:"playerId"`
+	TeamID   string `json:"teamId"`
+}
+
+func requireFields(ok bool) error {
+	if !ok {
+		return errMissingField
+	}
+	return nil
+}
+
+// Commands returns the room commands of Name That Tune.
+func (nameThatTuneModule) Commands(s *Server) []Command {
+	return []Command{
+		// Owner controls.
+		NewCommand("kick", CommandOwner, func(ctx context.Context, cc CommandContext, p targetPayload) error {
+			_, err := s.doKick(ctx, cc.RoomID, cc.OwnerSub, p.PlayerID)
+			return err
+		}),
+		NewCommand("score.add", CommandOwner, func(ctx context.Context, cc CommandContext, p scoreAddPayload) error {
+			_, err := s.doScoreAdd(ctx, cc.RoomID, cc.OwnerSub, p.PlayerID, *p.Delta)
+			return err
+		}),
+		NewCommand("score.set", CommandOwner, func(ctx context.Context, cc CommandContext, p scoreSetPayload) error {
+			_, err := s.doScoreSet(ctx, cc.RoomID, cc.OwnerSub, p.PlayerID, *p.Score)
+			return err
+		}),
+		NewCommand("rules.set", CommandOwner, func(ctx context.Context, cc CommandContext, p rulesSetPayload) error {
+			_, err := s.doRulesSet(ctx, cc.RoomID, cc.OwnerSub, p.Rules)
+			return err
+		}),
+		NewCommand("playlist.load", CommandOwner, func(ctx context.Context, cc CommandContext, p playlistLoadPayload) error {
+			_, err := s.doLoadPlaylist(ctx, cc.RoomID, cc.OwnerSub, p.PlaylistID)
+			return err
+		}),
+		NewCommand("playback.set", CommandOwner, func(ctx context.Context, cc CommandContext, p playbackSetPayload) error {
+			_, err := s.doPlaybackSet(ctx, cc.RoomID, cc.OwnerSub, *p.TrackIndex, p.Paused, p.PositionMS)
+			return err
+		}),
+		NewCommand("playback.pause", CommandOwner, func(ctx context.Context, cc CommandContext, p playbackPausePayload) error {
+			_, err := s.doPlaybackPause(ctx, cc.RoomID, cc.OwnerSub, *p.Paused)
+			return err
+		}),
+		NewCommand("playback.seek", CommandOwner, func(ctx context.Context, cc CommandContext, p playbackSeekPayload) error {
+			_, err := s.doPlaybackSeek(ctx, cc.RoomID, cc.OwnerSub, *p.PositionMS)
+			return err
+		}),
+		NewCommand("buzz.resolve", CommandOwner, func(ctx context.Context, cc CommandContext, p buzzResolvePayload) error {
+			return s.doBuzzResolve(ctx, cc.RoomID, cc.OwnerSub, p.PlayerID, *p.Correct)
+		}),
+		NewCommand("round.reveal", CommandOwner, func(ctx context.Context, cc CommandContext, _ struct{}) error {
+			_, err := s.doRoundReveal(ctx, cc.RoomID, cc.OwnerSub)
+			return err
+		}),
+		NewCommand("team.create", CommandOwner, func(ctx context.Context, cc CommandContext, p teamCreatePayload) error {
+			_, err := s.doTeamCreate(ctx, cc.RoomID, cc.OwnerSub, p.Name)
+			return err
+		}),
+		NewCommand("team.delete", CommandOwner, func(ctx context.Context, cc CommandContext, p teamDeletePayload) error {
+			_, err := s.doTeamDelete(ctx, cc.RoomID, cc.OwnerSub, p.TeamID)
+			return err
+		}),
+		NewCommand("team.assign", CommandOwner, func(ctx context.Context, cc CommandContext, p teamAssignPayload) error {
+			_, err := s.doTeamAssign(ctx, cc.RoomID, cc.OwnerSub, p.PlayerID, p.TeamID)
+			return err
+		}),
+
+		// Player actions.
+		NewCommand("playback.buffer", CommandPlayer, func(ctx context.Context, cc CommandContext, p playbackBufferPayload) error {
+			_, err := s.doPlaybackBuffering(ctx, cc.RoomID, cc.PlayerID, *p.Buffering)
+			return err
+		}),
+		NewCommand("playback.ready", CommandPlayer, func(ctx context.Context, cc CommandContext, p playbackReadyPayload) error {
+			_, err := s.doPlaybackReady(ctx, cc.RoomID, cc.PlayerID, *p.Ready, p.PlaybackUpdatedAt)
+			return err
+		}),
+		NewCommand("buzz", CommandPlayer, func(ctx context.Context, cc CommandContext, p buzzPayload) error {
+			return s.doBuzz(ctx, cc.RoomID, cc.PlayerID, p.PositionMS, p.ClientTS)
+		}),
+		NewCommand("answer.submit", CommandPlayer, func(ctx context.Context, cc CommandContext, p answerPayload) error {
+			return s.doAnswerSubmit(ctx, cc.RoomID, cc.PlayerID, p.Answer)
+		}),
+	}
+}
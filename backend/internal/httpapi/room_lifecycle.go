@@ -96,6 +96,9 @@ func (l *roomLifecycle) closeRoom(ctx context.Context, roomID string, reason roo
 		})
 	}
 
+	// Persist the match before the room (and its roster) disappears.
+	logMatchErr(roomID, "finish", l.repo.FinishMatch(ctx, roomID))
+
 	if err := l.repo.DeleteRoom(ctx, roomID); err != nil && !errors.Is(err, core.ErrRoomNotFound) {
 		return err
 	}
@@ -0,0 +1,125 @@
+package httpapi
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/valentin/bes-games/backend/internal/games/namethattune"
+	"github.com/valentin/bes-games/backend/internal/realtime"
+)
+
+// The round timer ends a track nobody found after the room's RoundDurationMS of playing
+// time. Played time is kept in the room state (PlaybackState.RoundPlayedMS): the timer
+// runs from the synchronized start, is suspended by pauses and buzzes, and restarts from
+// zero on every track and whenever a round that ended is played again.
+
+// roundTimer is the roomTimers name of the round timer.
+const roundTimer = "round"
+
+func roundDuration(rules namethattune.RoomRules) time.Duration {
+	return time.Duration(rules.RoundDurationMS) * time.Millisecond
+}
+
+// syncRoundTimer resumes or suspends the round according to a fresh snapshot and
+// (re)schedules its timeout. Like syncClipTimer, it runs on every playback change.
+func (s *Server) syncRoundTimer(roomID string, snap *namethattune.RoomSnapshot) {
+	d := roundDuration(snap.Rules)
+	playing := !snap.Playback.Paused && snap.Playback.Track != nil
+	if d <= 0 || !playing {
+		s.timers.cancel(roomID, roundTimer)
+	}
+	if d <= 0 {
+		return
+	}
+
+	st, err := s.loadPlaybackState(roomID)
+	if err != nil {
+		log.Printf("round timer: load state failed: roomId=%s err=%v", roomID, err)
+		return
+	}
+	switch {
+	case playing && st.RoundResumedAt == nil:
+		anchor := snap.Playback.UpdatedAt
+		if snap.Playback.StartAt != nil && snap.Playback.StartAt.After(anchor) {
+			anchor = *snap.Playback.StartAt
+		}
+		rs, err := s.updateRoomState(roomID, func(rs *namethattune.RoomState) { rs.Playback.ResumeRound(anchor) })
+		if err != nil {
+			log.Printf("round timer: resume failed: roomId=%s err=%v", roomID, err)
+			return
+		}
+		st = rs.Playback
+	case !playing && st.RoundResumedAt != nil:
+		// Paused (buzz, host, buffering) at UpdatedAt.
+		if err := s.updatePlaybackState(roomID, func(p *namethattune.PlaybackState) { p.SuspendRound(snap.Playback.UpdatedAt) }); err != nil {
+			log.Printf("round timer: suspend failed: roomId=%s err=%v", roomID, err)
+		}
+	}
+	if !playing {
+		return
+	}
+
+	now := time.Now().UTC()
+	remaining := st.RoundRemaining(d, now)
+	endsAt := now.Add(remaining)
+	snap.Playback.RoundEndsAt = &endsAt
+
+	updatedAt := snap.Playback.UpdatedAt
+	s.timers.schedule(roomID, roundTimer, remaining, func() {
+		s.handleRoundTimeout(roomID, updatedAt)
+	})
+}
+
+// handleRoundTimeout reveals the answer of the current track and ends it according to
+// the room rules.
+func (s *Server) handleRoundTimeout(roomID string, updatedAt time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	snap, err := s.loadRoomSnapshot(ctx, roomID)
+	if err != nil {
+		return
+	}
+	d := roundDuration(snap.Rules)
+	if d <= 0 || snap.Playback.Paused || snap.Playback.Track == nil || !snap.Playback.UpdatedAt.Equal(updatedAt) {
+		return
+	}
+	st, err := s.loadPlaybackState(roomID)
+	if err != nil {
+		log.Printf("round timeout failed: roomId=%s err=%v", roomID, err)
+		return
+	}
+	if remaining := st.RoundRemaining(d, time.Now().UTC()); remaining > clipEndTolerance {
+		s.syncRoundTimer(roomID, &snap)
+		return
+	}
+
+	reveal := namethattune.NewTrackReveal(snap.Playback.TrackIndex, *snap.Playback.Track)
+	if err := s.revealTrack(roomID, snap, revealReasonTimeout); err != nil {
+		log.Printf("round timeout failed: roomId=%s err=%v", roomID, err)
+		return
+	}
+	// Without auto-advance the track stays, revealed: playing it again starts a new round
+	// (see PlaybackState.ResumeRound).
+	advanced, err := s.endTimedTrack(ctx, roomID, snap)
+	if err != nil {
+		log.Printf("round timeout failed: roomId=%s err=%v", roomID, err)
+		return
+	}
+
+	if s.rt != nil {
+		s.rt.Room(roomID).Broadcast(realtime.Event{
+			Type:   "round.timeout",
+			RoomID: roomID,
+			Payload: map[string]any{
+				"answer":   reveal,
+				"advanced": advanced,
+			},
+		})
+	}
+	s.broadcastPlaybackChange(ctx, roomID)
+	if advanced {
+		s.broadcastPreload(ctx, roomID)
+	}
+}
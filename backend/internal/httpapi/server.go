@@ -1,16 +1,18 @@
 package httpapi
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/coder/websocket"
@@ -34,7 +36,8 @@ const playbackSyncLead = 1500 * time.Millisecond
 // Persistence model:
 //   - Core user profile is stored in Postgres via core.Repo.
 //   - Game state (rooms, players, playback, playlists) is stored in Postgres via a game repo.
-//   - Realtime updates are fanned out via realtime.Registry (in-memory pub/sub), while the source of truth is DB.
+//   - Realtime updates are fanned out via realtime.Registry (in-memory pub/sub, optionally relayed across
+//     instances through a realtime.Backend), while the source of truth is DB.
 //
 // Endpoints (summary):
 // - GET    /healthz
@@ -44,9 +47,11 @@ const playbackSyncLead = 1500 * time.Millisecond
 // - GET    /api/games/{gameId}/rooms
 // - POST   /api/games/{gameId}/rooms                          (auth required)
 // - GET    /api/games/{gameId}/rooms/{roomId}
-// - POST   /api/games/{gameId}/rooms/{roomId}/join            (anon allowed)
+// - POST   /api/games/{gameId}/rooms/{roomId}/join            (anon allowed; resumeToken reclaims a seat)
+// - POST   /api/games/{gameId}/rooms/{roomId}/spectate        (anon allowed; no seat)
 // - POST   /api/games/{gameId}/rooms/{roomId}/leave
 // - WS     /api/games/{gameId}/rooms/{roomId}/ws
+// - GET    /api/games/{gameId}/rooms/{roomId}/display         (SSE; display token)
 //
 // Owner controls (auth required; must be room owner) (per-game):
 //
@@ -58,31 +63,53 @@ const playbackSyncLead = 1500 * time.Millisecond
 // Playlists (per-game, auth required):
 // - GET    /api/games/{gameId}/playlists
 // - POST   /api/games/{gameId}/playlists
-// - PATCH  /api/games/{gameId}/playlists/{playlistId}
+// - GET    /api/games/{gameId}/playlists/discover?q=&limit=&offset= (public playlists, no auth)
+// - GET    /api/games/{gameId}/playlists/{playlistId}
+// - PATCH  /api/games/{gameId}/playlists/{playlistId} (name, visibility)
+// - DELETE /api/games/{gameId}/playlists/{playlistId} (moves it to the trash)
+// - GET    /api/games/{gameId}/playlists/trash
+// - POST   /api/games/{gameId}/playlists/{playlistId}/restore
+// - GET    /api/games/{gameId}/playlists/{playlistId}/collaborators
+// - PUT    /api/games/{gameId}/playlists/{playlistId}/collaborators/{userSub}
+// - DELETE /api/games/{gameId}/playlists/{playlistId}/collaborators/{userSub}
 // - POST   /api/games/{gameId}/playlists/{playlistId}/items
+// - POST   /api/games/{gameId}/playlists/{playlistId}/import
+// - GET    /api/games/{gameId}/playlists/{playlistId}/export?format=json|csv
+// - POST   /api/games/{gameId}/playlists/import?format=json|csv[&name=...]
+// - PUT    /api/games/{gameId}/playlists/{playlistId}/items/order
+// - POST   /api/games/{gameId}/playlists/{playlistId}/items/delete
+// - POST   /api/games/{gameId}/playlists/{playlistId}/items/move
+// - POST   /api/games/{gameId}/playlists/{playlistId}/shuffle
+// - POST   /api/games/{gameId}/playlists/{playlistId}/duplicate
 // - PATCH  /api/games/{gameId}/playlists/{playlistId}/items/{itemId}
 // - DELETE /api/games/{gameId}/playlists/{playlistId}/items/{itemId}
 //
+// Match history (per-game, auth required):
+// - GET    /api/games/{gameId}/matches
+// - GET    /api/games/{gameId}/matches/{matchId}
+//
 // Player actions (per-game):
 type Server struct {
-	coreRepo              *core.Repo
-	nttRepo               *namethattune.Repo
-	rt                    *realtime.Registry
-	gameModules           []GameModule
-	rooms                 *roomLifecycle
-	buzzMu                sync.Mutex
-	buzzCD                map[string]map[string]time.Time
-	tokenMu               sync.Mutex
-	playerTokens          map[string]map[string]string
-	ownerTokens           map[string]string
-	playbackMu            sync.Mutex
-	playbackBuffering     map[string]map[string]bool
-	playbackReady         map[string]map[string]bool
-	playbackWaitingReady  map[string]bool
-	playbackWaitingBuffer map[string]bool
-	playbackStartAt       map[string]time.Time
-	playbackAutoPause     map[string]bool
-	auth                  *AuthService
+	coreRepo    *core.Repo
+	nttRepo     *namethattune.Repo
+	rt          *realtime.Registry
+	gameModules []GameModule
+	// commands are the room WebSocket commands registered by the game modules, by game ID
+	// and action.
+	commands  map[string]map[string]Command
+	rooms     *roomLifecycle
+	state     namethattune.RoomStateStore
+	buzzes    *buzzArbiter
+	clocks    *clockTable
+	timers    *roomTimers
+	playlists namethattune.PlaylistSource
+	retention time.Duration
+	resume    resumeSigner
+	seatGrace time.Duration
+	presence  *presenceTable
+	// presenceTimeout is how long a seat stays connected after its last WebSocket closed.
+	presenceTimeout time.Duration
+	auth            *AuthService
 }
 
 type wsOriginPatternsCtxKey struct{}
@@ -108,25 +135,93 @@ func NewServer(coreRepo *core.Repo, nttRepo *namethattune.Repo, rt *realtime.Reg
 	}
 
 	s := &Server{
-		coreRepo:              coreRepo,
-		nttRepo:               nttRepo,
-		rt:                    rt,
-		gameModules:           append([]GameModule(nil), gameModules...),
-		buzzCD:                make(map[string]map[string]time.Time),
-		playerTokens:          make(map[string]map[string]string),
-		ownerTokens:           make(map[string]string),
-		playbackBuffering:     make(map[string]map[string]bool),
-		playbackReady:         make(map[string]map[string]bool),
-		playbackWaitingReady:  make(map[string]bool),
-		playbackWaitingBuffer: make(map[string]bool),
-		playbackStartAt:       make(map[string]time.Time),
-		playbackAutoPause:     make(map[string]bool),
-		auth:                  auth,
+		coreRepo:        coreRepo,
+		nttRepo:         nttRepo,
+		rt:              rt,
+		gameModules:     append([]GameModule(nil), gameModules...),
+		commands:        make(map[string]map[string]Command),
+		state:           namethattune.NewMemoryRoomStateStore(),
+		buzzes:          newBuzzArbiter(buzzArbitrationWindow),
+		clocks:          newClockTable(),
+		timers:          newRoomTimers(),
+		retention:       namethattune.DefaultPlaylistRetention,
+		resume:          newResumeSigner(""),
+		seatGrace:       namethattune.DefaultSeatGracePeriod,
+		presence:        newPresenceTable(),
+		presenceTimeout: DefaultPresenceTimeout,
+		auth:            auth,
 	}
 	s.rooms = newRoomLifecycle(nttRepo, rt, s.clearRoomState)
+	for _, module := range s.gameModules {
+		if cm, ok := module.(CommandModule); ok {
+			s.registerCommands(module.Meta().ID, cm.Commands(s))
+		}
+	}
+	if rt != nil {
+		rt.SetRenderer(renderRoomEvent)
+	}
 	return s
 }
 
+// SetRoomStateStore replaces the default in-memory room state store (tokens, buzz
+// cooldowns, playback sync flags). Call it before serving requests.
+func (s *Server) SetRoomStateStore(store namethattune.RoomStateStore) {
+	if store != nil {
+		s.state = store
+	}
+}
+
+// SetPlaylistSource enables playlist import from remote (YouTube) playlists. Without a
+// source, the import endpoint answers 503.
+func (s *Server) SetPlaylistSource(src namethattune.PlaylistSource) {
+	s.playlists = src
+}
+
+// SetPlaylistRetention sets how long deleted playlists stay restorable before
+// RunPlaylistPurge removes them.
+func (s *Server) SetPlaylistRetention(d time.Duration) {
+	if d > 0 {
+		s.retention = d
+	}
+}
+
+// RunPlaylistPurge permanently removes playlists past the trash retention window, every
+// interval, until ctx is done. A zero or negative interval disables the purge.
+func (s *Server) RunPlaylistPurge(ctx context.Context, interval time.Duration) {
+	runEvery(ctx, "playlist purge", interval, func(ctx context.Context) {
+		purgeCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		n, err := s.nttRepo.PurgeDeletedPlaylists(purgeCtx, s.retention)
+		cancel()
+		switch {
+		case err != nil && ctx.Err() == nil:
+			log.Printf("playlist purge failed: err=%v", err)
+		case n > 0:
+			log.Printf("playlist purge: removed %d playlists", n)
+		}
+	})
+}
+
+// runEvery runs fn now and then every interval until ctx is done. A zero or negative
+// interval, which time.NewTicker rejects, disables the loop.
+func runEvery(ctx context.Context, name string, interval time.Duration, fn func(ctx context.Context)) {
+	if interval <= 0 {
+		log.Printf("%s disabled: interval=%s", name, interval)
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		fn(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 func (s *Server) Handler(opts Options) http.Handler {
 	r := chi.NewRouter()
 
@@ -161,8 +256,9 @@ func (s *Server) Handler(opts Options) http.Handler {
 
 	r.Get("/healthz", func(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, http.StatusOK, map[string]any{
-			"status": "ok",
-			"time":   time.Now().UTC().Format(time.RFC3339Nano),
+			"status":   "ok",
+			"time":     time.Now().UTC().Format(time.RFC3339Nano),
+			"realtime": s.realtimeStats(),
 		})
 	})
 
@@ -183,6 +279,7 @@ func (s *Server) Handler(opts Options) http.Handler {
 			module := module
 			meta := module.Meta()
 			api.Route("/games/"+meta.ID, func(game chi.Router) {
+				game.Use(withGameID(meta.ID))
 				module.Mount(game, s)
 			})
 		}
@@ -235,6 +332,14 @@ func playlistItemIDParam(r *http.Request) string {
 	return strings.TrimSpace(chi.URLParam(r, "itemId"))
 }
 
+func collaboratorSubParam(r *http.Request) string {
+	return strings.TrimSpace(chi.URLParam(r, "userSub"))
+}
+
+func matchIDParam(r *http.Request) string {
+	return strings.TrimSpace(chi.URLParam(r, "matchId"))
+}
+
 func writeJSON(w http.ResponseWriter, status int, v any) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(status)
@@ -278,291 +383,228 @@ func randomToken() string {
 	return base64.RawURLEncoding.EncodeToString(buf)
 }
 
-func (s *Server) getOrCreatePlayerToken(roomID, playerID string) string {
-	s.tokenMu.Lock()
-	defer s.tokenMu.Unlock()
-	room := s.playerTokens[roomID]
-	if room == nil {
-		room = make(map[string]string)
-		s.playerTokens[roomID] = room
-	}
-	if token, ok := room[playerID]; ok {
-		return token
-	}
-	token := randomToken()
-	room[playerID] = token
-	return token
-}
+// roomStateTimeout bounds room state store calls. Helpers are invoked from handlers and
+// background loops alike, so they do not take the caller's context. They return store
+// errors: handlers answer them as server errors, background loops log them.
+const roomStateTimeout = 3 * time.Second
 
-func (s *Server) clearPlayerToken(roomID, playerID string) {
-	s.tokenMu.Lock()
-	defer s.tokenMu.Unlock()
-	room := s.playerTokens[roomID]
-	if room == nil {
-		return
-	}
-	delete(room, playerID)
-	if len(room) == 0 {
-		delete(s.playerTokens, roomID)
-	}
+func (s *Server) loadRoomState(roomID string) (namethattune.RoomState, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), roomStateTimeout)
+	defer cancel()
+	return s.state.Load(ctx, roomID)
 }
 
-func (s *Server) validatePlayerToken(roomID, playerID, token string) bool {
-	if token == "" {
-		return false
-	}
-	s.tokenMu.Lock()
-	defer s.tokenMu.Unlock()
-	room := s.playerTokens[roomID]
-	if room == nil {
-		return false
-	}
-	return room[playerID] == token
+func (s *Server) updateRoomState(roomID string, fn func(*namethattune.RoomState)) (namethattune.RoomState, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), roomStateTimeout)
+	defer cancel()
+	return s.state.Update(ctx, roomID, fn)
 }
 
-func (s *Server) getOrCreateOwnerToken(roomID string) string {
-	s.tokenMu.Lock()
-	defer s.tokenMu.Unlock()
-	if token, ok := s.ownerTokens[roomID]; ok {
-		return token
-	}
-	token := randomToken()
-	s.ownerTokens[roomID] = token
-	return token
+func (s *Server) updatePlaybackState(roomID string, fn func(*namethattune.PlaybackState)) error {
+	_, err := s.updateRoomState(roomID, func(st *namethattune.RoomState) { fn(&st.Playback) })
+	return err
 }
 
-func (s *Server) validateOwnerToken(roomID, token string) bool {
-	if token == "" {
-		return false
-	}
-	s.tokenMu.Lock()
-	defer s.tokenMu.Unlock()
-	return s.ownerTokens[roomID] == token
+// loadPlaybackState returns the playback synchronization flags of a room.
+func (s *Server) loadPlaybackState(roomID string) (namethattune.PlaybackState, error) {
+	st, err := s.loadRoomState(roomID)
+	return st.Playback, err
 }
 
-func (s *Server) buzzCooldownUntil(roomID, playerID string) (time.Time, bool) {
-	s.buzzMu.Lock()
-	defer s.buzzMu.Unlock()
-
-	room := s.buzzCD[roomID]
-	if room == nil {
-		return time.Time{}, false
+func (s *Server) getOrCreatePlayerToken(roomID, playerID string) (string, error) {
+	var token string
+	_, err := s.updateRoomState(roomID, func(st *namethattune.RoomState) {
+		if existing, ok := st.PlayerTokens[playerID]; ok {
+			token = existing
+			return
+		}
+		if st.PlayerTokens == nil {
+			st.PlayerTokens = make(map[string]string)
+		}
+		token = randomToken()
+		st.PlayerTokens[playerID] = token
+	})
+	if err != nil {
+		return "", err
 	}
-	until, ok := room[playerID]
-	return until, ok
+	return token, nil
 }
 
-func (s *Server) setBuzzCooldown(roomID, playerID string, until time.Time) {
-	s.buzzMu.Lock()
-	defer s.buzzMu.Unlock()
-
-	room := s.buzzCD[roomID]
-	if room == nil {
-		room = make(map[string]time.Time)
-		s.buzzCD[roomID] = room
-	}
-	room[playerID] = until
+func (s *Server) clearPlayerToken(roomID, playerID string) error {
+	_, err := s.updateRoomState(roomID, func(st *namethattune.RoomState) {
+		delete(st.PlayerTokens, playerID)
+	})
+	return err
 }
 
-func (s *Server) clearBuzzCooldown(roomID, playerID string) {
-	s.buzzMu.Lock()
-	defer s.buzzMu.Unlock()
-
-	room := s.buzzCD[roomID]
-	if room == nil {
-		return
+func (s *Server) validatePlayerToken(roomID, playerID, token string) (bool, error) {
+	if token == "" {
+		return false, nil
 	}
-	delete(room, playerID)
-	if len(room) == 0 {
-		delete(s.buzzCD, roomID)
+	st, err := s.loadRoomState(roomID)
+	if err != nil {
+		return false, err
 	}
+	return st.PlayerTokens[playerID] == token, nil
 }
 
-func (s *Server) setPlaybackBuffering(roomID, playerID string, buffering bool) bool {
-	s.playbackMu.Lock()
-	defer s.playbackMu.Unlock()
-	room := s.playbackBuffering[roomID]
-	if room == nil {
-		room = make(map[string]bool)
-		s.playbackBuffering[roomID] = room
-	}
-	prev, ok := room[playerID]
-	if !buffering {
-		if ok {
-			delete(room, playerID)
+func (s *Server) getOrCreateOwnerToken(roomID string) (string, error) {
+	var token string
+	_, err := s.updateRoomState(roomID, func(st *namethattune.RoomState) {
+		if st.OwnerToken == "" {
+			st.OwnerToken = randomToken()
 		}
-		if len(room) == 0 {
-			delete(s.playbackBuffering, roomID)
-		}
-		return ok
+		token = st.OwnerToken
+	})
+	if err != nil {
+		return "", err
 	}
-	room[playerID] = true
-	return !ok || !prev
+	return token, nil
 }
 
-func (s *Server) bufferingPlayers(roomID string, players []namethattune.PlayerView) []string {
-	s.playbackMu.Lock()
-	defer s.playbackMu.Unlock()
-	room := s.playbackBuffering[roomID]
-	if len(room) == 0 {
-		return nil
+func (s *Server) validateOwnerToken(roomID, token string) (bool, error) {
+	if token == "" {
+		return false, nil
 	}
-	connected := make(map[string]bool, len(players))
-	for _, p := range players {
-		if p.Connected {
-			connected[p.PlayerID] = true
-		}
+	st, err := s.loadRoomState(roomID)
+	if err != nil {
+		return false, err
 	}
-	out := make([]string, 0, len(room))
-	for pid := range room {
-		if connected[pid] {
-			out = append(out, pid)
+	return st.OwnerToken == token, nil
+}
+
+func (s *Server) getOrCreateDisplayToken(roomID string) (string, error) {
+	var token string
+	_, err := s.updateRoomState(roomID, func(st *namethattune.RoomState) {
+		if st.DisplayToken == "" {
+			st.DisplayToken = randomToken()
 		}
+		token = st.DisplayToken
+	})
+	if err != nil {
+		return "", err
 	}
-	if len(out) == 0 {
-		delete(s.playbackBuffering, roomID)
-	}
-	return out
+	return token, nil
 }
 
-func (s *Server) setPlaybackReady(roomID, playerID string, ready bool) {
-	s.playbackMu.Lock()
-	defer s.playbackMu.Unlock()
-	room := s.playbackReady[roomID]
-	if room == nil {
-		room = make(map[string]bool)
-		s.playbackReady[roomID] = room
-	}
-	if ready {
-		room[playerID] = true
-		return
+func (s *Server) validateDisplayToken(roomID, token string) (bool, error) {
+	if token == "" {
+		return false, nil
 	}
-	delete(room, playerID)
-	if len(room) == 0 {
-		delete(s.playbackReady, roomID)
+	st, err := s.loadRoomState(roomID)
+	if err != nil {
+		return false, err
 	}
+	return st.DisplayToken == token, nil
 }
 
-func (s *Server) allPlayersReady(roomID string, players []namethattune.PlayerView) bool {
-	s.playbackMu.Lock()
-	defer s.playbackMu.Unlock()
-	room := s.playbackReady[roomID]
-	for _, p := range players {
-		if !p.Connected {
-			continue
-		}
-		if room == nil || !room[p.PlayerID] {
-			return false
+func (s *Server) getOrCreateSpectatorToken(roomID string) (string, error) {
+	var token string
+	_, err := s.updateRoomState(roomID, func(st *namethattune.RoomState) {
+		if st.SpectatorToken == "" {
+			st.SpectatorToken = randomToken()
 		}
+		token = st.SpectatorToken
+	})
+	if err != nil {
+		return "", err
 	}
-	return true
+	return token, nil
 }
 
-func (s *Server) notReadyPlayers(roomID string, players []namethattune.PlayerView) []string {
-	s.playbackMu.Lock()
-	defer s.playbackMu.Unlock()
-	room := s.playbackReady[roomID]
-	out := make([]string, 0, len(players))
-	for _, p := range players {
-		if !p.Connected {
-			continue
-		}
-		if room == nil || !room[p.PlayerID] {
-			out = append(out, p.PlayerID)
-		}
+func (s *Server) validateSpectatorToken(roomID, token string) (bool, error) {
+	if token == "" {
+		return false, nil
 	}
-	return out
-}
-
-func (s *Server) setPlaybackWaitingReady(roomID string, waiting bool) {
-	s.playbackMu.Lock()
-	defer s.playbackMu.Unlock()
-	if waiting {
-		s.playbackWaitingReady[roomID] = true
-		return
+	st, err := s.loadRoomState(roomID)
+	if err != nil {
+		return false, err
 	}
-	delete(s.playbackWaitingReady, roomID)
-}
-
-func (s *Server) isPlaybackWaitingReady(roomID string) bool {
-	s.playbackMu.Lock()
-	defer s.playbackMu.Unlock()
-	return s.playbackWaitingReady[roomID]
+	return st.SpectatorToken == token, nil
 }
 
-func (s *Server) setPlaybackWaitingBuffer(roomID string, waiting bool) {
-	s.playbackMu.Lock()
-	defer s.playbackMu.Unlock()
-	if waiting {
-		s.playbackWaitingBuffer[roomID] = true
-		return
+// buzzCooldownActive reports whether a player is on buzz cooldown at now.
+func (s *Server) buzzCooldownActive(roomID, playerID string, now time.Time) (bool, error) {
+	st, err := s.loadRoomState(roomID)
+	if err != nil {
+		return false, err
 	}
-	delete(s.playbackWaitingBuffer, roomID)
+	until, ok := st.BuzzCooldowns[playerID]
+	return ok && until.After(now), nil
 }
 
-func (s *Server) isPlaybackWaitingBuffer(roomID string) bool {
-	s.playbackMu.Lock()
-	defer s.playbackMu.Unlock()
-	return s.playbackWaitingBuffer[roomID]
+func (s *Server) setBuzzCooldown(roomID, playerID string, until time.Time) error {
+	_, err := s.updateRoomState(roomID, func(st *namethattune.RoomState) {
+		if st.BuzzCooldowns == nil {
+			st.BuzzCooldowns = make(map[string]time.Time)
+		}
+		st.BuzzCooldowns[playerID] = until
+	})
+	return err
 }
 
-func (s *Server) setPlaybackStartAt(roomID string, startAt time.Time) {
-	s.playbackMu.Lock()
-	defer s.playbackMu.Unlock()
-	s.playbackStartAt[roomID] = startAt
+func (s *Server) clearBuzzCooldown(roomID, playerID string) error {
+	_, err := s.updateRoomState(roomID, func(st *namethattune.RoomState) {
+		delete(st.BuzzCooldowns, playerID)
+	})
+	return err
 }
 
-func (s *Server) getPlaybackStartAt(roomID string) (time.Time, bool) {
-	s.playbackMu.Lock()
-	defer s.playbackMu.Unlock()
-	startAt, ok := s.playbackStartAt[roomID]
-	return startAt, ok
+func (s *Server) setPlaybackBuffering(roomID, playerID string, buffering bool) error {
+	return s.updatePlaybackState(roomID, func(p *namethattune.PlaybackState) {
+		p.SetBuffering(playerID, buffering)
+	})
 }
 
-func (s *Server) clearPlaybackStartAt(roomID string) {
-	s.playbackMu.Lock()
-	defer s.playbackMu.Unlock()
-	delete(s.playbackStartAt, roomID)
+func (s *Server) setPlaybackReady(roomID, playerID string, ready bool) error {
+	return s.updatePlaybackState(roomID, func(p *namethattune.PlaybackState) {
+		p.SetReady(playerID, ready)
+	})
 }
 
-func (s *Server) setPlaybackAutoPause(roomID string, paused bool) {
-	s.playbackMu.Lock()
-	defer s.playbackMu.Unlock()
-	if paused {
-		s.playbackAutoPause[roomID] = true
-		return
-	}
-	delete(s.playbackAutoPause, roomID)
+// markPlaybackStarted schedules a synchronized start and clears every waiting flag in a
+// single state update.
+func (s *Server) markPlaybackStarted(roomID string) error {
+	startAt := time.Now().UTC().Add(playbackSyncLead)
+	return s.updatePlaybackState(roomID, func(p *namethattune.PlaybackState) {
+		p.StartAt = &startAt
+		p.WaitingReady = false
+		p.WaitingBuffer = false
+		p.AutoPause = false
+		p.BuzzedBy = ""
+		p.ResumeRound(startAt)
+	})
 }
 
-func (s *Server) isPlaybackAutoPaused(roomID string) bool {
-	s.playbackMu.Lock()
-	defer s.playbackMu.Unlock()
-	return s.playbackAutoPause[roomID]
+// markPlaybackStopped drops the scheduled start and every waiting flag, keeping the
+// per-player readiness of the current track. The round timer is suspended.
+func (s *Server) markPlaybackStopped(roomID string) error {
+	now := time.Now().UTC()
+	return s.updatePlaybackState(roomID, func(p *namethattune.PlaybackState) {
+		p.StartAt = nil
+		p.WaitingReady = false
+		p.WaitingBuffer = false
+		p.AutoPause = false
+		p.SuspendRound(now)
+	})
 }
 
-func (s *Server) clearPlaybackState(roomID string) {
-	s.playbackMu.Lock()
-	defer s.playbackMu.Unlock()
-	delete(s.playbackBuffering, roomID)
-	delete(s.playbackReady, roomID)
-	delete(s.playbackWaitingReady, roomID)
-	delete(s.playbackWaitingBuffer, roomID)
-	delete(s.playbackStartAt, roomID)
-	delete(s.playbackAutoPause, roomID)
+func (s *Server) clearPlaybackState(roomID string) error {
+	_, err := s.updateRoomState(roomID, func(st *namethattune.RoomState) {
+		st.Playback = namethattune.PlaybackState{}
+	})
+	return err
 }
 
 func (s *Server) clearRoomState(roomID string) {
-	s.tokenMu.Lock()
-	delete(s.playerTokens, roomID)
-	delete(s.ownerTokens, roomID)
-	s.tokenMu.Unlock()
+	s.clocks.clearRoom(roomID)
+	s.timers.cancelRoom(roomID)
 
-	s.buzzMu.Lock()
-	delete(s.buzzCD, roomID)
-	s.buzzMu.Unlock()
-
-	s.clearPlaybackState(roomID)
+	ctx, cancel := context.WithTimeout(context.Background(), roomStateTimeout)
+	defer cancel()
+	if err := s.state.Delete(ctx, roomID); err != nil {
+		log.Printf("room state: delete failed: roomId=%s err=%v", roomID, err)
+	}
 }
 
 // =============================
@@ -617,20 +659,52 @@ func (s *Server) doScoreAdd(ctx context.Context, roomID, sub, playerID string, d
 	return snap, nil
 }
 
+// doRulesSet applies a partial rules document onto the room's current rules.
+func (s *Server) doRulesSet(ctx context.Context, roomID, sub string, raw json.RawMessage) (namethattune.RoomSnapshot, error) {
+	current, err := s.nttRepo.GetRoomRules(ctx, roomID)
+	if err != nil {
+		status, msg := mapDomainErr(err)
+		return namethattune.RoomSnapshot{}, &apiError{Status: status, Message: msg}
+	}
+	rules, err := namethattune.MergeRoomRules(current, raw)
+	if err != nil {
+		return namethattune.RoomSnapshot{}, &apiError{Status: http.StatusBadRequest, Message: "invalid room rules"}
+	}
+	if err := s.nttRepo.SetRoomRules(ctx, roomID, sub, rules); err != nil {
+		status, msg := mapDomainErr(err)
+		return namethattune.RoomSnapshot{}, &apiError{Status: status, Message: msg}
+	}
+
+	snap, err := s.loadRoomSnapshot(ctx, roomID)
+	if err != nil {
+		status, msg := mapDomainErr(err)
+		return namethattune.RoomSnapshot{}, &apiError{Status: status, Message: msg}
+	}
+
+	s.broadcastPlaybackChange(ctx, roomID)
+	return snap, nil
+}
+
 func (s *Server) doLoadPlaylist(ctx context.Context, roomID, sub, playlistID string) (namethattune.RoomSnapshot, error) {
+	// Loading another playlist ends the current match (if any).
+	logMatchErr(roomID, "finish", s.nttRepo.FinishMatch(ctx, roomID))
+
 	if err := s.nttRepo.LoadPlaylistToRoom(ctx, roomID, sub, strings.TrimSpace(playlistID)); err != nil {
 		status, msg := mapDomainErr(err)
 		return namethattune.RoomSnapshot{}, &apiError{Status: status, Message: msg}
 	}
 
-	s.clearPlaybackState(roomID)
+	if err := s.clearPlaybackState(roomID); err != nil {
+		status, msg := mapDomainErr(err)
+		return namethattune.RoomSnapshot{}, &apiError{Status: status, Message: msg}
+	}
 	snap, err := s.loadRoomSnapshot(ctx, roomID)
 	if err != nil {
 		status, msg := mapDomainErr(err)
 		return namethattune.RoomSnapshot{}, &apiError{Status: status, Message: msg}
 	}
 
-	s.broadcastSnapshot(ctx, roomID)
+	s.broadcastPlaybackChange(ctx, roomID)
 	s.broadcastPreload(ctx, roomID)
 	return snap, nil
 }
@@ -643,7 +717,13 @@ func (s *Server) doPlaybackSet(ctx context.Context, roomID, sub string, trackInd
 	}
 
 	if prevErr != nil || prevSnap.Playback.TrackIndex != trackIndex {
-		s.clearPlaybackState(roomID)
+		if err := s.clearPlaybackState(roomID); err != nil {
+			status, msg := mapDomainErr(err)
+			return namethattune.RoomSnapshot{}, &apiError{Status: status, Message: msg}
+		}
+	}
+	if paused != nil && !*paused {
+		logMatchErr(roomID, "start round", s.nttRepo.StartRound(ctx, roomID))
 	}
 	snap, err := s.loadRoomSnapshot(ctx, roomID)
 	if err != nil {
@@ -651,7 +731,7 @@ func (s *Server) doPlaybackSet(ctx context.Context, roomID, sub string, trackInd
 		return namethattune.RoomSnapshot{}, &apiError{Status: status, Message: msg}
 	}
 
-	s.broadcastSnapshot(ctx, roomID)
+	s.broadcastPlaybackChange(ctx, roomID)
 	if prevErr != nil || prevSnap.Playback.TrackIndex != trackIndex {
 		s.broadcastPreload(ctx, roomID)
 	}
@@ -664,10 +744,10 @@ func (s *Server) doPlaybackPause(ctx context.Context, roomID, sub string, paused
 			status, msg := mapDomainErr(err)
 			return namethattune.RoomSnapshot{}, &apiError{Status: status, Message: msg}
 		}
-		s.setPlaybackWaitingReady(roomID, false)
-		s.setPlaybackWaitingBuffer(roomID, false)
-		s.clearPlaybackStartAt(roomID)
-		s.setPlaybackAutoPause(roomID, false)
+		if err := s.markPlaybackStopped(roomID); err != nil {
+			status, msg := mapDomainErr(err)
+			return namethattune.RoomSnapshot{}, &apiError{Status: status, Message: msg}
+		}
 
 		snap, err := s.loadRoomSnapshot(ctx, roomID)
 		if err != nil {
@@ -675,7 +755,7 @@ func (s *Server) doPlaybackPause(ctx context.Context, roomID, sub string, paused
 			return namethattune.RoomSnapshot{}, &apiError{Status: status, Message: msg}
 		}
 
-		s.broadcastSnapshot(ctx, roomID)
+		s.broadcastPlaybackChange(ctx, roomID)
 		return snap, nil
 	}
 
@@ -685,13 +765,26 @@ func (s *Server) doPlaybackPause(ctx context.Context, roomID, sub string, paused
 		return namethattune.RoomSnapshot{}, &apiError{Status: status, Message: msg}
 	}
 
-	if !s.allPlayersReady(roomID, snap.Players) {
-		s.setPlaybackWaitingReady(roomID, true)
-		s.setPlaybackWaitingBuffer(roomID, false)
-		s.clearPlaybackStartAt(roomID)
-		s.setPlaybackAutoPause(roomID, false)
-		s.decorateSnapshot(roomID, &snap)
-		s.broadcastSnapshot(ctx, roomID)
+	playback, err := s.loadPlaybackState(roomID)
+	if err != nil {
+		status, msg := mapDomainErr(err)
+		return namethattune.RoomSnapshot{}, &apiError{Status: status, Message: msg}
+	}
+	if !playback.AllPlayersReady(snap.Players) {
+		err := s.updatePlaybackState(roomID, func(p *namethattune.PlaybackState) {
+			p.WaitingReady = true
+			p.WaitingBuffer = false
+			p.StartAt = nil
+			p.AutoPause = false
+		})
+		if err == nil {
+			err = s.decorateSnapshot(roomID, &snap)
+		}
+		if err != nil {
+			status, msg := mapDomainErr(err)
+			return namethattune.RoomSnapshot{}, &apiError{Status: status, Message: msg}
+		}
+		s.broadcastPlaybackChange(ctx, roomID)
 		return snap, nil
 	}
 
@@ -699,12 +792,12 @@ func (s *Server) doPlaybackPause(ctx context.Context, roomID, sub string, paused
 		status, msg := mapDomainErr(err)
 		return namethattune.RoomSnapshot{}, &apiError{Status: status, Message: msg}
 	}
+	logMatchErr(roomID, "start round", s.nttRepo.StartRound(ctx, roomID))
 
-	startAt := time.Now().UTC().Add(playbackSyncLead)
-	s.setPlaybackStartAt(roomID, startAt)
-	s.setPlaybackWaitingReady(roomID, false)
-	s.setPlaybackWaitingBuffer(roomID, false)
-	s.setPlaybackAutoPause(roomID, false)
+	if err := s.markPlaybackStarted(roomID); err != nil {
+		status, msg := mapDomainErr(err)
+		return namethattune.RoomSnapshot{}, &apiError{Status: status, Message: msg}
+	}
 
 	snap, err = s.loadRoomSnapshot(ctx, roomID)
 	if err != nil {
@@ -712,7 +805,7 @@ func (s *Server) doPlaybackPause(ctx context.Context, roomID, sub string, paused
 		return namethattune.RoomSnapshot{}, &apiError{Status: status, Message: msg}
 	}
 
-	s.broadcastSnapshot(ctx, roomID)
+	s.broadcastPlaybackChange(ctx, roomID)
 	return snap, nil
 }
 
@@ -722,16 +815,22 @@ func (s *Server) doPlaybackSeek(ctx context.Context, roomID, sub string, positio
 		return namethattune.RoomSnapshot{}, &apiError{Status: status, Message: msg}
 	}
 
-	s.setPlaybackWaitingReady(roomID, false)
-	s.setPlaybackWaitingBuffer(roomID, false)
-	s.clearPlaybackStartAt(roomID)
+	err := s.updatePlaybackState(roomID, func(p *namethattune.PlaybackState) {
+		p.WaitingReady = false
+		p.WaitingBuffer = false
+		p.StartAt = nil
+	})
+	if err != nil {
+		status, msg := mapDomainErr(err)
+		return namethattune.RoomSnapshot{}, &apiError{Status: status, Message: msg}
+	}
 	snap, err := s.loadRoomSnapshot(ctx, roomID)
 	if err != nil {
 		status, msg := mapDomainErr(err)
 		return namethattune.RoomSnapshot{}, &apiError{Status: status, Message: msg}
 	}
 
-	s.broadcastSnapshot(ctx, roomID)
+	s.broadcastPlaybackChange(ctx, roomID)
 	return snap, nil
 }
 
@@ -753,25 +852,20 @@ func (s *Server) doPlaybackReady(ctx context.Context, roomID, playerID string, r
 			return namethattune.RoomSnapshot{}, &apiError{Status: http.StatusBadRequest, Message: "invalid playbackUpdatedAt"}
 		}
 		if !parsed.Equal(snap.Playback.UpdatedAt) {
-			s.decorateSnapshot(roomID, &snap)
 			return snap, nil
 		}
 	}
 
-	s.setPlaybackReady(roomID, playerID, ready)
+	if err := s.setPlaybackReady(roomID, playerID, ready); err != nil {
+		status, msg := mapDomainErr(err)
+		return namethattune.RoomSnapshot{}, &apiError{Status: status, Message: msg}
+	}
 
-	bufferingPlayers := s.bufferingPlayers(roomID, snap.Players)
-	if ready && len(bufferingPlayers) == 0 &&
-		(s.isPlaybackWaitingBuffer(roomID) || s.isPlaybackWaitingReady(roomID)) &&
-		s.allPlayersReady(roomID, snap.Players) {
-		if err := s.nttRepo.TogglePauseSafe(ctx, roomID, snap.OwnerSub, false); err != nil {
+	if ready {
+		if _, err := s.startWhenUnblocked(ctx, roomID, snap); err != nil {
 			status, msg := mapDomainErr(err)
 			return namethattune.RoomSnapshot{}, &apiError{Status: status, Message: msg}
 		}
-		s.setPlaybackAutoPause(roomID, false)
-		s.setPlaybackWaitingBuffer(roomID, false)
-		s.setPlaybackWaitingReady(roomID, false)
-		s.setPlaybackStartAt(roomID, time.Now().UTC().Add(playbackSyncLead))
 	}
 
 	snap, err = s.loadRoomSnapshot(ctx, roomID)
@@ -780,10 +874,31 @@ func (s *Server) doPlaybackReady(ctx context.Context, roomID, playerID string, r
 		return namethattune.RoomSnapshot{}, &apiError{Status: status, Message: msg}
 	}
 
-	s.broadcastSnapshot(ctx, roomID)
+	s.broadcastPlaybackChange(ctx, roomID)
 	return snap, nil
 }
 
+// startWhenUnblocked starts playback held for buffering or readiness once no connected
+// player is left to wait for. It reports whether playback started.
+func (s *Server) startWhenUnblocked(ctx context.Context, roomID string, snap namethattune.RoomSnapshot) (bool, error) {
+	playback, err := s.loadPlaybackState(roomID)
+	if err != nil {
+		return false, err
+	}
+	if len(playback.BufferingPlayers(snap.Players)) > 0 ||
+		!(playback.WaitingBuffer || playback.WaitingReady) ||
+		!playback.AllPlayersReady(snap.Players) {
+		return false, nil
+	}
+	if err := s.nttRepo.TogglePauseSafe(ctx, roomID, snap.OwnerSub, false); err != nil {
+		return false, err
+	}
+	if err := s.markPlaybackStarted(roomID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 func (s *Server) doPlaybackBuffering(ctx context.Context, roomID, playerID string, buffering bool) (namethattune.RoomSnapshot, error) {
 	playerID = strings.TrimSpace(playerID)
 	if playerID == "" {
@@ -810,39 +925,42 @@ func (s *Server) doPlaybackBuffering(ctx context.Context, roomID, playerID strin
 		return snap, nil
 	}
 
-	s.setPlaybackBuffering(roomID, playerID, buffering)
+	if err := s.setPlaybackBuffering(roomID, playerID, buffering); err != nil {
+		status, msg := mapDomainErr(err)
+		return namethattune.RoomSnapshot{}, &apiError{Status: status, Message: msg}
+	}
 
 	now := time.Now().UTC()
 	startAt := snap.Playback.StartAt
 	waitingToStart := startAt != nil && startAt.After(now)
 
 	if buffering {
-		if !snap.Playback.Paused && !waitingToStart {
+		autoPause := !snap.Playback.Paused && !waitingToStart
+		if autoPause {
 			if err := s.nttRepo.PausePlaybackWithPosition(ctx, roomID); err != nil {
 				status, msg := mapDomainErr(err)
 				return namethattune.RoomSnapshot{}, &apiError{Status: status, Message: msg}
 			}
-			s.setPlaybackAutoPause(roomID, true)
-			s.setPlaybackWaitingBuffer(roomID, true)
-		} else if waitingToStart || s.isPlaybackWaitingBuffer(roomID) || s.isPlaybackWaitingReady(roomID) {
-			s.setPlaybackWaitingBuffer(roomID, true)
 		}
-		if waitingToStart {
-			s.clearPlaybackStartAt(roomID)
+		err := s.updatePlaybackState(roomID, func(p *namethattune.PlaybackState) {
+			if autoPause {
+				p.AutoPause = true
+				p.WaitingBuffer = true
+			} else if waitingToStart || p.WaitingBuffer || p.WaitingReady {
+				p.WaitingBuffer = true
+			}
+			if waitingToStart {
+				p.StartAt = nil
+			}
+		})
+		if err != nil {
+			status, msg := mapDomainErr(err)
+			return namethattune.RoomSnapshot{}, &apiError{Status: status, Message: msg}
 		}
 	} else {
-		bufferingPlayers := s.bufferingPlayers(roomID, snap.Players)
-		if len(bufferingPlayers) == 0 &&
-			(s.isPlaybackWaitingBuffer(roomID) || s.isPlaybackWaitingReady(roomID)) &&
-			s.allPlayersReady(roomID, snap.Players) {
-			if err := s.nttRepo.TogglePauseSafe(ctx, roomID, snap.OwnerSub, false); err != nil {
-				status, msg := mapDomainErr(err)
-				return namethattune.RoomSnapshot{}, &apiError{Status: status, Message: msg}
-			}
-			s.setPlaybackAutoPause(roomID, false)
-			s.setPlaybackWaitingBuffer(roomID, false)
-			s.setPlaybackWaitingReady(roomID, false)
-			s.setPlaybackStartAt(roomID, time.Now().UTC().Add(playbackSyncLead))
+		if _, err := s.startWhenUnblocked(ctx, roomID, snap); err != nil {
+			status, msg := mapDomainErr(err)
+			return namethattune.RoomSnapshot{}, &apiError{Status: status, Message: msg}
 		}
 	}
 
@@ -852,35 +970,119 @@ func (s *Server) doPlaybackBuffering(ctx context.Context, roomID, playerID strin
 		return namethattune.RoomSnapshot{}, &apiError{Status: status, Message: msg}
 	}
 
-	s.broadcastSnapshot(ctx, roomID)
+	s.broadcastPlaybackChange(ctx, roomID)
 	return snap, nil
 }
 
-func (s *Server) doBuzz(ctx context.Context, roomID, playerID string) error {
+// doBuzz submits a buzz to the room's arbitration window. positionMS and clientTS are the
+// optional client-reported press timing used for latency compensation.
+func (s *Server) doBuzz(ctx context.Context, roomID, playerID string, positionMS *int, clientTS *int64) error {
 	playerID = strings.TrimSpace(playerID)
-	if until, ok := s.buzzCooldownUntil(roomID, playerID); ok && until.After(time.Now().UTC()) {
+	now := time.Now().UTC()
+	st, err := s.loadRoomState(roomID)
+	if err != nil {
+		status, msg := mapDomainErr(err)
+		return &apiError{Status: status, Message: msg}
+	}
+	if until, ok := st.BuzzCooldowns[playerID]; ok && until.After(now) {
 		return &apiError{Status: http.StatusBadRequest, Message: "buzz cooldown active"}
 	}
-
-	player, err := s.nttRepo.HandleBuzz(ctx, roomID, playerID)
+	rules, err := s.nttRepo.GetRoomRules(ctx, roomID)
 	if err != nil {
 		status, msg := mapDomainErr(err)
 		return &apiError{Status: status, Message: msg}
 	}
+	playback := st.Playback
+	if rules.MaxBuzzesPerTrack > 0 && playback.Buzzes[playerID] >= rules.MaxBuzzesPerTrack {
+		return &apiError{Status: http.StatusBadRequest, Message: "buzz limit reached"}
+	}
+	mates, err := s.buzzLockMates(ctx, roomID, playerID, rules)
+	if err != nil {
+		return err
+	}
+	if teamBuzzed(playback, mates) {
+		return &apiError{Status: http.StatusBadRequest, Message: "team already buzzed"}
+	}
+
+	claim := buzzClaim{
+		PlayerID:   playerID,
+		ReceivedAt: now,
+		PositionMS: positionMS,
+		ClientTS:   clientTS,
+	}
+	return s.buzzes.submit(ctx, roomID, claim, func(ctx context.Context, claims []buzzClaim) (string, error) {
+		return s.resolveBuzzRound(ctx, roomID, claims)
+	})
+}
+
+// resolveBuzzRound awards the buzzer to the earliest claim of an arbitration window and
+// broadcasts the outcome, runner-ups included. It returns the winning player ID.
+func (s *Server) resolveBuzzRound(ctx context.Context, roomID string, claims []buzzClaim) (string, error) {
+	snap, err := s.loadRoomSnapshot(ctx, roomID)
+	if err != nil {
+		status, msg := mapDomainErr(err)
+		return "", &apiError{Status: status, Message: msg}
+	}
+	ranked := rankBuzzClaims(roomID, claims, snap.Playback, s.clocks.offset, buzzArbitrationWindow)
+
+	var player namethattune.PlayerView
+	var winner int
+	for i, c := range ranked {
+		player, err = s.nttRepo.HandleBuzz(ctx, roomID, c.PlayerID)
+		if err == nil {
+			winner = i
+			break
+		}
+		// A claimant may have been kicked meanwhile; give the buzzer to the next one.
+		if errors.Is(err, core.ErrPlayerNotFound) && i < len(ranked)-1 {
+			continue
+		}
+		status, msg := mapDomainErr(err)
+		return "", &apiError{Status: status, Message: msg}
+	}
+	logMatchErr(roomID, "record buzz", s.nttRepo.RecordBuzz(ctx, roomID, player.PlayerID))
+	err = s.updatePlaybackState(roomID, func(p *namethattune.PlaybackState) {
+		if p.Buzzes == nil {
+			p.Buzzes = make(map[string]int)
+		}
+		p.Buzzes[player.PlayerID]++
+		p.BuzzedBy = player.PlayerID
+	})
+	if err != nil {
+		status, msg := mapDomainErr(err)
+		return "", &apiError{Status: status, Message: msg}
+	}
+
+	nicknames := make(map[string]string, len(snap.Players))
+	for _, p := range snap.Players {
+		nicknames[p.PlayerID] = p.Nickname
+	}
+	runnerUps := make([]map[string]any, 0, len(ranked)-winner-1)
+	for _, c := range ranked[winner+1:] {
+		runnerUps = append(runnerUps, map[string]any{
+			"playerId": c.PlayerID,
+			"nickname": nicknames[c.PlayerID],
+			"behindMs": c.PressedAt.Sub(ranked[winner].PressedAt).Milliseconds(),
+		})
+	}
 
 	if s.rt != nil {
+		// Everyone sees the buzz: keep the winner's sub out of it.
+		winnerView := player
+		winnerView.Sub = ""
 		s.rt.Room(roomID).Broadcast(realtime.Event{
 			Type:   "buzzer",
 			RoomID: roomID,
 			Payload: map[string]any{
-				"player": player,
+				"player":    winnerView,
+				"runnerUps": runnerUps,
 			},
 		})
 	}
 
-	s.broadcastSnapshot(ctx, roomID)
+	s.broadcastPlaybackChange(ctx, roomID)
 	s.broadcastPreload(ctx, roomID)
-	return nil
+	return player.PlayerID, nil
 }
 
 func (s *Server) doBuzzResolve(ctx context.Context, roomID, sub, playerID string, correct bool) error {
@@ -889,58 +1091,64 @@ func (s *Server) doBuzzResolve(ctx context.Context, roomID, sub, playerID string
 		return &apiError{Status: http.StatusBadRequest, Message: "invalid input"}
 	}
 
+	snap, err := s.loadRoomSnapshot(ctx, roomID)
+	if err != nil {
+		status, msg := mapDomainErr(err)
+		return &apiError{Status: status, Message: msg}
+	}
+	rules := snap.Rules
+
 	var cooldownUntil string
+	var lockedOut []string
+	var points int
 	if correct {
-		s.clearBuzzCooldown(roomID, playerID)
-		if err := s.nttRepo.AddScore(ctx, roomID, sub, playerID, 1); err != nil {
+		// The buzz paused playback, so PositionMS is where the player buzzed.
+		points = rules.PointsCorrect + rules.SpeedBonus(snap.Playback.ClipOffsetMS(snap.Playback.PositionMS))
+		if err := s.clearBuzzCooldown(roomID, playerID); err != nil {
 			status, msg := mapDomainErr(err)
 			return &apiError{Status: status, Message: msg}
 		}
-
-		snap, err := s.loadRoomSnapshot(ctx, roomID)
-		if err != nil {
+		if err := s.nttRepo.AddScore(ctx, roomID, sub, playerID, points); err != nil {
 			status, msg := mapDomainErr(err)
 			return &apiError{Status: status, Message: msg}
 		}
-		if snap.Playlist != nil && len(snap.Playlist.Items) > 0 {
-			nextIndex := snap.Playback.TrackIndex + 1
-			max := (len(snap.Playlist.Items) - 1)
-			if nextIndex > max {
-				nextIndex = max
-			}
-			paused := true
-			position := 0
-			if err := s.nttRepo.SetPlayback(ctx, roomID, sub, nextIndex, &paused, &position); err != nil {
+		// Record before advancing: the round is tied to the current track.
+		logMatchErr(roomID, "resolve buzz", s.nttRepo.ResolveBuzz(ctx, roomID, playerID, true))
+		if err := s.revealTrack(roomID, snap, revealReasonAnswer); err != nil {
+			status, msg := mapDomainErr(err)
+			return &apiError{Status: status, Message: msg}
+		}
+
+		if err := s.endTrackAfterCorrect(ctx, roomID, sub, snap); err != nil {
+			return err
+		}
+	} else {
+		if rules.PenaltyWrong > 0 {
+			points = -rules.PenaltyWrong
+			if err := s.nttRepo.AddScore(ctx, roomID, sub, playerID, points); err != nil {
 				status, msg := mapDomainErr(err)
 				return &apiError{Status: status, Message: msg}
 			}
-			s.clearPlaybackState(roomID)
-		} else {
-			paused := true
-			if err := s.nttRepo.TogglePauseSafe(ctx, roomID, sub, paused); err != nil {
+		}
+		logMatchErr(roomID, "resolve buzz", s.nttRepo.ResolveBuzz(ctx, roomID, playerID, false))
+		if rules.CooldownMS > 0 {
+			until := time.Now().UTC().Add(time.Duration(rules.CooldownMS) * time.Millisecond)
+			if lockedOut, err = s.setWrongAnswerCooldown(ctx, roomID, playerID, rules, until); err != nil {
 				status, msg := mapDomainErr(err)
 				return &apiError{Status: status, Message: msg}
 			}
-			s.clearPlaybackStartAt(roomID)
-			s.setPlaybackWaitingReady(roomID, false)
-			s.setPlaybackWaitingBuffer(roomID, false)
-			s.setPlaybackAutoPause(roomID, false)
+			cooldownUntil = until.Format(time.RFC3339Nano)
 		}
-	} else {
-		const cooldown = 5 * time.Second
-		until := time.Now().UTC().Add(cooldown)
-		s.setBuzzCooldown(roomID, playerID, until)
-		cooldownUntil = until.Format(time.RFC3339Nano)
 		paused := false
 		if err := s.nttRepo.TogglePauseSafe(ctx, roomID, sub, paused); err != nil {
 			status, msg := mapDomainErr(err)
 			return &apiError{Status: status, Message: msg}
 		}
 		if !paused {
-			s.setPlaybackStartAt(roomID, time.Now().UTC().Add(playbackSyncLead))
-			s.setPlaybackWaitingReady(roomID, false)
-			s.setPlaybackWaitingBuffer(roomID, false)
-			s.setPlaybackAutoPause(roomID, false)
+			if err := s.markPlaybackStarted(roomID); err != nil {
+				status, msg := mapDomainErr(err)
+				return &apiError{Status: status, Message: msg}
+			}
 		}
 	}
 
@@ -951,42 +1159,268 @@ func (s *Server) doBuzzResolve(ctx context.Context, roomID, sub, playerID string
 			Payload: map[string]any{
 				"playerId": playerID,
 				"correct":  correct,
+				"points":   points,
 			},
 		})
-		if !correct {
+		if cooldownUntil != "" {
 			s.rt.Room(roomID).Broadcast(realtime.Event{
 				Type:   "buzzer.cooldown",
 				RoomID: roomID,
 				Payload: map[string]any{
-					"playerId": playerID,
-					"until":    cooldownUntil,
+					"playerId":  playerID,
+					"playerIds": lockedOut,
+					"until":     cooldownUntil,
 				},
 			})
 		}
 	}
 
-	s.broadcastSnapshot(ctx, roomID)
+	s.broadcastPlaybackChange(ctx, roomID)
 	return nil
 }
 
-func (s *Server) decorateSnapshot(roomID string, snap *namethattune.RoomSnapshot) {
-	if snap == nil {
-		return
+// endTrackAfterCorrect moves to the next track (paused) when the rules auto-advance, or
+// pauses the current one otherwise.
+func (s *Server) endTrackAfterCorrect(ctx context.Context, roomID, sub string, snap namethattune.RoomSnapshot) error {
+	if snap.Rules.AutoAdvance && snap.Playlist != nil && len(snap.Playlist.Items) > 0 {
+		nextIndex := snap.Playback.TrackIndex + 1
+		max := (len(snap.Playlist.Items) - 1)
+		if nextIndex > max {
+			nextIndex = max
+		}
+		paused := true
+		position := 0
+		if err := s.nttRepo.SetPlayback(ctx, roomID, sub, nextIndex, &paused, &position); err != nil {
+			status, msg := mapDomainErr(err)
+			return &apiError{Status: status, Message: msg}
+		}
+		if err := s.clearPlaybackState(roomID); err != nil {
+			status, msg := mapDomainErr(err)
+			return &apiError{Status: status, Message: msg}
+		}
+		return nil
+	}
+
+	paused := true
+	if err := s.nttRepo.TogglePauseSafe(ctx, roomID, sub, paused); err != nil {
+		status, msg := mapDomainErr(err)
+		return &apiError{Status: status, Message: msg}
+	}
+	if err := s.markPlaybackStopped(roomID); err != nil {
+		status, msg := mapDomainErr(err)
+		return &apiError{Status: status, Message: msg}
+	}
+	return nil
+}
+
+// maxAnswerLength bounds typed answers.
+const maxAnswerLength = 200
+
+// doAnswerSubmit checks a typed answer against the current track (typed answer mode) and
+// scores it automatically. The first correct answer solves the track.
+func (s *Server) doAnswerSubmit(ctx context.Context, roomID, playerID, answer string) error {
+	playerID = strings.TrimSpace(playerID)
+	answer = strings.TrimSpace(answer)
+	if playerID == "" || answer == "" || len(answer) > maxAnswerLength {
+		return &apiError{Status: http.StatusBadRequest, Message: "invalid input"}
+	}
+
+	snap, err := s.loadRoomSnapshot(ctx, roomID)
+	if err != nil {
+		status, msg := mapDomainErr(err)
+		return &apiError{Status: status, Message: msg}
+	}
+	rules := snap.Rules
+	if rules.AnswerMode != namethattune.AnswerModeTyped {
+		return &apiError{Status: http.StatusBadRequest, Message: "typed answers are disabled"}
+	}
+	if snap.Playback.Track == nil || snap.Playback.Paused {
+		return &apiError{Status: http.StatusBadRequest, Message: "no track playing"}
+	}
+
+	var player *namethattune.PlayerView
+	for i := range snap.Players {
+		if snap.Players[i].PlayerID == playerID {
+			player = &snap.Players[i]
+			break
+		}
+	}
+	if player == nil {
+		return &apiError{Status: http.StatusNotFound, Message: "player not found"}
+	}
+	if player.Sub != "" && player.Sub == snap.OwnerSub {
+		return &apiError{Status: http.StatusForbidden, Message: "forbidden"}
+	}
+
+	now := time.Now().UTC()
+	cooldown, err := s.buzzCooldownActive(roomID, playerID, now)
+	if err != nil {
+		status, msg := mapDomainErr(err)
+		return &apiError{Status: status, Message: msg}
+	}
+	if cooldown {
+		return &apiError{Status: http.StatusBadRequest, Message: "buzz cooldown active"}
+	}
+
+	// Position in the clip when the answer arrived, for the speed bonus.
+	positionMS := snap.Playback.ClipOffsetMS(snap.Playback.CurrentPositionMS(now))
+
+	correct := namethattune.MatchAnswer(answer, *snap.Playback.Track)
+
+	mates, err := s.buzzLockMates(ctx, roomID, playerID, rules)
+	if err != nil {
+		return err
+	}
+
+	var apiErr *apiError
+	err = s.updatePlaybackState(roomID, func(p *namethattune.PlaybackState) {
+		switch {
+		case p.SolvedBy != "":
+			apiErr = &apiError{Status: http.StatusConflict, Message: "track already solved"}
+			return
+		case rules.MaxBuzzesPerTrack > 0 && p.Buzzes[playerID] >= rules.MaxBuzzesPerTrack:
+			apiErr = &apiError{Status: http.StatusBadRequest, Message: "answer limit reached"}
+			return
+		case teamBuzzed(*p, mates):
+			apiErr = &apiError{Status: http.StatusBadRequest, Message: "team already answered"}
+			return
+		}
+		if p.Buzzes == nil {
+			p.Buzzes = make(map[string]int)
+		}
+		p.Buzzes[playerID]++
+		if correct {
+			p.SolvedBy = playerID
+		}
+	})
+	if err != nil {
+		status, msg := mapDomainErr(err)
+		return &apiError{Status: status, Message: msg}
+	}
+	if apiErr != nil {
+		return apiErr
+	}
+
+	logMatchErr(roomID, "record answer", s.nttRepo.RecordBuzz(ctx, roomID, playerID))
+	logMatchErr(roomID, "resolve answer", s.nttRepo.ResolveBuzz(ctx, roomID, playerID, correct))
+
+	var points int
+	var cooldownUntil string
+	var lockedOut []string
+	if correct {
+		points = rules.PointsCorrect + rules.SpeedBonus(positionMS)
+		if err := s.clearBuzzCooldown(roomID, playerID); err != nil {
+			status, msg := mapDomainErr(err)
+			return &apiError{Status: status, Message: msg}
+		}
+		if err := s.nttRepo.AddScore(ctx, roomID, snap.OwnerSub, playerID, points); err != nil {
+			status, msg := mapDomainErr(err)
+			return &apiError{Status: status, Message: msg}
+		}
+		if err := s.revealTrack(roomID, snap, revealReasonAnswer); err != nil {
+			status, msg := mapDomainErr(err)
+			return &apiError{Status: status, Message: msg}
+		}
+		if err := s.endTrackAfterCorrect(ctx, roomID, snap.OwnerSub, snap); err != nil {
+			return err
+		}
+	} else {
+		if rules.PenaltyWrong > 0 {
+			points = -rules.PenaltyWrong
+			if err := s.nttRepo.AddScore(ctx, roomID, snap.OwnerSub, playerID, points); err != nil {
+				status, msg := mapDomainErr(err)
+				return &apiError{Status: status, Message: msg}
+			}
+		}
+		if rules.CooldownMS > 0 {
+			until := now.Add(time.Duration(rules.CooldownMS) * time.Millisecond)
+			if lockedOut, err = s.setWrongAnswerCooldown(ctx, roomID, playerID, rules, until); err != nil {
+				status, msg := mapDomainErr(err)
+				return &apiError{Status: status, Message: msg}
+			}
+			cooldownUntil = until.Format(time.RFC3339Nano)
+		}
+	}
+
+	if s.rt != nil {
+		// The guess itself is not broadcast: it could give the answer away.
+		s.rt.Room(roomID).Broadcast(realtime.Event{
+			Type:   "answer.result",
+			RoomID: roomID,
+			Payload: map[string]any{
+				"playerId": playerID,
+				"nickname": player.Nickname,
+				"correct":  correct,
+				"points":   points,
+			},
+		})
+		if cooldownUntil != "" {
+			s.rt.Room(roomID).Broadcast(realtime.Event{
+				Type:   "buzzer.cooldown",
+				RoomID: roomID,
+				Payload: map[string]any{
+					"playerId":  playerID,
+					"playerIds": lockedOut,
+					"until":     cooldownUntil,
+				},
+			})
+		}
+	}
+
+	if correct {
+		// The track ended.
+		s.broadcastPlaybackChange(ctx, roomID)
+	} else {
+		s.broadcastSnapshot(ctx, roomID)
+	}
+	if correct && rules.AutoAdvance {
+		s.broadcastPreload(ctx, roomID)
+	}
+	return nil
+}
+
+func (s *Server) decorateSnapshot(roomID string, snap *namethattune.RoomSnapshot) error {
+	if snap == nil {
+		return nil
 	}
-	if startAt, ok := s.getPlaybackStartAt(roomID); ok {
-		t := startAt
+	playback, err := s.loadPlaybackState(roomID)
+	if err != nil {
+		return err
+	}
+	if playback.StartAt != nil {
+		t := *playback.StartAt
 		snap.Playback.StartAt = &t
 	}
-	buffering := s.bufferingPlayers(roomID, snap.Players)
+	buffering := playback.BufferingPlayers(snap.Players)
 	if len(buffering) > 0 {
 		snap.Playback.BufferingPlayers = buffering
 	}
-	notReady := s.notReadyPlayers(roomID, snap.Players)
+	notReady := playback.NotReadyPlayers(snap.Players)
 	if len(notReady) > 0 {
 		snap.Playback.WaitingForReadyPlayers = notReady
 	}
-	snap.Playback.WaitingForBuffer = s.isPlaybackWaitingBuffer(roomID) || len(buffering) > 0
-	snap.Playback.WaitingForReady = s.isPlaybackWaitingReady(roomID) && len(notReady) > 0
+	snap.Playback.WaitingForBuffer = playback.WaitingBuffer || len(buffering) > 0
+	snap.Playback.WaitingForReady = playback.WaitingReady && len(notReady) > 0
+	snap.Playback.Phase = namethattune.RoundPhaseWaiting
+	if snap.Playback.Track != nil {
+		snap.Playback.Phase = playback.Phase(snap.Playback.Paused)
+	}
+	if d := roundDuration(snap.Rules); d > 0 && !snap.Playback.Paused && playback.RoundResumedAt != nil {
+		now := time.Now().UTC()
+		endsAt := now.Add(playback.RoundRemaining(d, now))
+		snap.Playback.RoundEndsAt = &endsAt
+	}
+	s.clocks.decorate(roomID, snap.Players)
+	return nil
+}
+
+// logMatchErr reports match history failures. History is best-effort: a failed write
+// must never block gameplay, so callers log and carry on.
+func logMatchErr(roomID, op string, err error) {
+	if err == nil {
+		return
+	}
+	log.Printf("match history %s failed: roomId=%s err=%v", op, roomID, err)
 }
 
 func decodeJSON(r *http.Request, dst any) error {
@@ -1009,6 +1443,16 @@ func mapDomainErr(err error) (int, string) {
 		return http.StatusNotFound, err.Error()
 	case errors.Is(err, namethattune.ErrPlaylistNotFound):
 		return http.StatusNotFound, err.Error()
+	case errors.Is(err, namethattune.ErrMatchNotFound):
+		return http.StatusNotFound, err.Error()
+	case errors.Is(err, namethattune.ErrPlaylistSourceNotFound):
+		return http.StatusNotFound, err.Error()
+	case errors.Is(err, namethattune.ErrPlaylistForbidden):
+		return http.StatusForbidden, err.Error()
+	case errors.Is(err, namethattune.ErrUserNotFound):
+		return http.StatusNotFound, err.Error()
+	case errors.Is(err, namethattune.ErrTeamNotFound):
+		return http.StatusNotFound, err.Error()
 	case errors.Is(err, core.ErrInvalidInput):
 		return http.StatusBadRequest, err.Error()
 	default:
@@ -1018,12 +1462,29 @@ func mapDomainErr(err error) (int, string) {
 
 // After state-changing operations, we broadcast a fresh snapshot to the room websocket subscribers.
 func (s *Server) broadcastSnapshot(ctx context.Context, roomID string) {
-	if s.rt == nil {
+	snap, err := s.loadRoomSnapshot(ctx, roomID)
+	if err != nil {
+		// If snapshot can't be fetched, do not broadcast.
 		return
 	}
+	s.publishSnapshot(roomID, snap)
+}
+
+// broadcastPlaybackChange is broadcastSnapshot for actions that changed playback (track,
+// pause, seek, rules...): it first re-arms the clip and round timers from the new state.
+// Other broadcasts leave the timers alone.
+func (s *Server) broadcastPlaybackChange(ctx context.Context, roomID string) {
 	snap, err := s.loadRoomSnapshot(ctx, roomID)
 	if err != nil {
-		// If snapshot can't be fetched, do not broadcast.
+		return
+	}
+	s.syncClipTimer(roomID, snap)
+	s.syncRoundTimer(roomID, &snap)
+	s.publishSnapshot(roomID, snap)
+}
+
+func (s *Server) publishSnapshot(roomID string, snap namethattune.RoomSnapshot) {
+	if s.rt == nil {
 		return
 	}
 	s.rt.Room(roomID).Broadcast(realtime.Event{
@@ -1059,7 +1520,9 @@ func (s *Server) loadRoomSnapshot(ctx context.Context, roomID string) (namethatt
 	if err != nil {
 		return snap, err
 	}
-	s.decorateSnapshot(roomID, &snap)
+	if err := s.decorateSnapshot(roomID, &snap); err != nil {
+		return namethattune.RoomSnapshot{}, err
+	}
 	return snap, nil
 }
 
@@ -1083,23 +1546,33 @@ func (s *Server) handleListRooms(w http.ResponseWriter, r *http.Request) {
 		HasPassword   bool      `json:"hasPassword"`
 		OnlinePlayers int       `json:"onlinePlayers"`
 		Subscribers   int       `json:"subscribers"`
+		Spectators    int       `json:"spectators"`
 		UpdatedAt     time.Time `json:"updatedAt"`
 	}
 
+	// Owner subs are only shown to the owner themselves.
+	sub := userSub(r)
 	out := make([]roomInfo, 0, len(rooms))
 	for _, ri := range rooms {
-		subs := 0
+		ownerSub := ""
+		if sub != "" && sub == ri.OwnerSub {
+			ownerSub = ri.OwnerSub
+		}
+		subs, spectators := 0, 0
 		if s.rt != nil {
-			subs = s.rt.Room(ri.ID).SubscriberCount()
+			hub := s.rt.Room(ri.ID)
+			subs = hub.SubscriberCount()
+			spectators = hub.ViewerCount(func(v realtime.Viewer) bool { return v.Audience })
 		}
 		out = append(out, roomInfo{
 			RoomID:        ri.ID,
 			Name:          ri.Name,
-			OwnerSub:      ri.OwnerSub,
+			OwnerSub:      ownerSub,
 			Visibility:    ri.Visibility,
 			HasPassword:   ri.HasPassword,
 			OnlinePlayers: ri.OnlinePlayers,
 			Subscribers:   subs,
+			Spectators:    spectators,
 			UpdatedAt:     ri.UpdatedAt,
 		})
 	}
@@ -1120,10 +1593,11 @@ func (s *Server) handleListGames(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleCreateRoom(w http.ResponseWriter, r *http.Request) {
 	type reqBody struct {
-		Name       string `json:"name"`
-		PlaylistID string `json:"playlistId"`
-		Visibility string `json:"visibility"`
-		Password   string `json:"password"`
+		Name       string          `json:"name"`
+		PlaylistID string          `json:"playlistId"`
+		Visibility string          `json:"visibility"`
+		Password   string          `json:"password"`
+		Rules      json.RawMessage `json:"rules,omitempty"`
 	}
 	var body reqBody
 	if err := decodeJSON(r, &body); err != nil {
@@ -1131,6 +1605,12 @@ func (s *Server) handleCreateRoom(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	rules, err := namethattune.MergeRoomRules(namethattune.DefaultRoomRules(), body.Rules)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid room rules")
+		return
+	}
+
 	visibility := strings.TrimSpace(body.Visibility)
 	if visibility == "" {
 		visibility = "public"
@@ -1147,6 +1627,7 @@ func (s *Server) handleCreateRoom(w http.ResponseWriter, r *http.Request) {
 		strings.TrimSpace(body.PlaylistID),
 		visibility,
 		strings.TrimSpace(body.Password),
+		rules,
 	)
 	if err != nil {
 		status, msg := mapDomainErr(err)
@@ -1168,7 +1649,70 @@ func (s *Server) handleGetRoom(w http.ResponseWriter, r *http.Request) {
 		writeError(w, status, msg)
 		return
 	}
-	writeJSON(w, http.StatusOK, snap)
+	viewer, err := s.requestViewer(r, snap)
+	if err != nil {
+		status, msg := mapDomainErr(err)
+		writeError(w, status, msg)
+		return
+	}
+	writeJSON(w, http.StatusOK, viewSnapshot(snap, viewer))
+}
+
+// handleSpectateRoom lets a client watch a room without joining it: the room password is
+// checked as for a join, but no player seat is taken, so spectators cannot buzz and do not
+// count toward readiness or buffering waits. The client then connects to the room WS with
+// ?spectate=1&spectatorToken=<the returned token>.
+func (s *Server) handleSpectateRoom(w http.ResponseWriter, r *http.Request) {
+	roomID := roomIDParam(r)
+
+	type reqBody struct {
+		Password string `json:"password,omitempty"`
+	}
+	var body reqBody
+	if err := decodeJSON(r, &body); err != nil && !isJSONEOF(err) {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	if err := s.nttRepo.CheckRoomAccess(r.Context(), roomID, userSub(r), strings.TrimSpace(body.Password)); err != nil {
+		status, msg := mapDomainErr(err)
+		writeError(w, status, msg)
+		return
+	}
+
+	snap, err := s.loadRoomSnapshot(r.Context(), roomID)
+	if err != nil {
+		status, msg := mapDomainErr(err)
+		writeError(w, status, msg)
+		return
+	}
+
+	spectatorToken, err := s.getOrCreateSpectatorToken(roomID)
+	if err != nil {
+		status, msg := mapDomainErr(err)
+		writeError(w, status, msg)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"spectatorToken": spectatorToken,
+		"snapshot":       viewSnapshot(snap, realtime.Viewer{Role: realtime.RoleSpectator}),
+	})
+}
+
+// canSpectate reports whether a room WebSocket request without a seat may watch the room.
+// Spectator connections (?spectate=1) need the room's spectator token; other connections
+// without credentials need it too, unless the room has no password.
+func (s *Server) canSpectate(r *http.Request, roomID string, spectating bool) (bool, error) {
+	ok, err := s.validateSpectatorToken(roomID, r.URL.Query().Get("spectatorToken"))
+	if err != nil || ok || spectating {
+		return ok, err
+	}
+	err = s.nttRepo.CheckRoomAccess(r.Context(), roomID, userSub(r), "")
+	if errors.Is(err, core.ErrUnauthorized) {
+		return false, nil
+	}
+	return err == nil, err
 }
 
 func (s *Server) handleJoinRoom(w http.ResponseWriter, r *http.Request) {
@@ -1178,6 +1722,8 @@ func (s *Server) handleJoinRoom(w http.ResponseWriter, r *http.Request) {
 		Nickname   string `json:"nickname,omitempty"`
 		PictureURL string `json:"pictureUrl,omitempty"`
 		Password   string `json:"password,omitempty"`
+		// ResumeToken reclaims the seat it was issued for (see resumeSigner).
+		ResumeToken string `json:"resumeToken,omitempty"`
 	}
 	var body reqBody
 	// Optional body. If empty, decodeJSON may return EOF; treat as ok.
@@ -1186,6 +1732,13 @@ func (s *Server) handleJoinRoom(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var resume *namethattune.ResumeClaim
+	if body.ResumeToken != "" {
+		if claim, ok := s.resume.parse(roomID, body.ResumeToken); ok {
+			resume = &claim
+		}
+	}
+
 	joinRes, err := s.nttRepo.JoinRoom(
 		r.Context(),
 		roomID,
@@ -1199,6 +1752,7 @@ func (s *Server) handleJoinRoom(w http.ResponseWriter, r *http.Request) {
 		strings.TrimSpace(body.Nickname),
 		strings.TrimSpace(body.PictureURL),
 		strings.TrimSpace(body.Password),
+		resume,
 	)
 	if err != nil {
 		status, msg := mapDomainErr(err)
@@ -1221,21 +1775,40 @@ func (s *Server) handleJoinRoom(w http.ResponseWriter, r *http.Request) {
 	// Broadcast snapshot for all listeners.
 	s.broadcastSnapshot(r.Context(), roomID)
 
-	playerToken := s.getOrCreatePlayerToken(roomID, joinRes.PlayerID)
-	ownerToken := ""
+	// Tokens that were not stored would be rejected on the next command: fail the join
+	// instead, the client can retry it.
+	playerToken, err := s.getOrCreatePlayerToken(roomID, joinRes.PlayerID)
+	if err != nil {
+		status, msg := mapDomainErr(err)
+		writeError(w, status, msg)
+		return
+	}
+	ownerToken, displayToken := "", ""
+	viewer := realtime.Viewer{Role: realtime.RolePlayer, ID: joinRes.PlayerID}
 	if joinRes.IsOwner {
-		ownerToken = s.getOrCreateOwnerToken(roomID)
+		if ownerToken, err = s.getOrCreateOwnerToken(roomID); err == nil {
+			displayToken, err = s.getOrCreateDisplayToken(roomID)
+		}
+		if err != nil {
+			status, msg := mapDomainErr(err)
+			writeError(w, status, msg)
+			return
+		}
+		viewer = realtime.Viewer{Role: realtime.RoleOwner}
 	}
 
 	writeJSON(w, http.StatusOK, map[string]any{
-		"playerId":    joinRes.PlayerID,
-		"playerToken": playerToken,
-		"ownerToken":  ownerToken,
+		"playerId":     joinRes.PlayerID,
+		"playerToken":  playerToken,
+		"resumeToken":  s.resume.sign(roomID, joinRes.PlayerID, joinRes.ResumeNonce),
+		"resumed":      joinRes.Resumed,
+		"ownerToken":   ownerToken,
+		"displayToken": displayToken,
 		"owner": map[string]any{
 			"playerId": joinRes.OwnerPlayerID,
 			"online":   joinRes.OwnerConnected,
 		},
-		"snapshot": snap,
+		"snapshot": viewSnapshot(snap, viewer),
 	})
 }
 
@@ -1251,34 +1824,47 @@ func (s *Server) handleLeaveRoom(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	leaveRes, err := s.nttRepo.LeaveRoom(r.Context(), roomID, strings.TrimSpace(body.PlayerID))
+	playerID := strings.TrimSpace(body.PlayerID)
+	leaveRes, err := s.nttRepo.LeaveRoom(r.Context(), roomID, playerID)
 	if err != nil {
 		status, msg := mapDomainErr(err)
 		writeError(w, status, msg)
 		return
 	}
-	s.clearPlayerToken(roomID, strings.TrimSpace(body.PlayerID))
-
-	closedReason := ""
-	if leaveRes.OwnerLeft && leaveRes.ConnectedAfter == 0 {
-		_ = s.rooms.closeRoom(r.Context(), roomID, reasonOwnerLeftEmpty)
-		closedReason = string(reasonOwnerLeftEmpty)
-	} else if !leaveRes.OwnerConnected && leaveRes.ConnectedAfter == 0 {
-		_ = s.rooms.closeRoom(r.Context(), roomID, reasonOwnerLeftEmpty)
-		closedReason = string(reasonOwnerLeftEmpty)
-	} else if leaveRes.OwnerLeft {
-		s.rooms.scheduleOwnerTimeout(roomID, 10*time.Minute)
+	// The seat is already gone; a stale token can no longer act on it.
+	if err := s.clearPlayerToken(roomID, playerID); err != nil {
+		log.Printf("leave room clear token failed: roomId=%s playerId=%s err=%v", roomID, playerID, err)
 	}
 
-	if closedReason != "" {
+	if closedReason := s.afterLeave(r.Context(), roomID, leaveRes); closedReason != "" {
 		writeJSON(w, http.StatusOK, map[string]any{"ok": true, "closed": true, "reason": closedReason})
 		return
 	}
 
-	s.broadcastSnapshot(r.Context(), roomID)
+	s.broadcastPresence(roomID, playerID, false)
+	// The player no longer holds back a readiness wait.
+	if snap, err := s.loadRoomSnapshot(r.Context(), roomID); err == nil {
+		if _, err := s.startWhenUnblocked(r.Context(), roomID, snap); err != nil {
+			log.Printf("leave room start playback failed: roomId=%s err=%v", roomID, err)
+		}
+	}
+	s.broadcastPlaybackChange(r.Context(), roomID)
 	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
 }
 
+// afterLeave closes the room once nobody is left, or gives an owner who left some time to
+// come back. It returns the close reason, empty when the room stays open.
+func (s *Server) afterLeave(ctx context.Context, roomID string, leaveRes namethattune.LeaveResult) string {
+	if leaveRes.ConnectedAfter == 0 && (leaveRes.OwnerLeft || !leaveRes.OwnerConnected) {
+		_ = s.rooms.closeRoom(ctx, roomID, reasonOwnerLeftEmpty)
+		return string(reasonOwnerLeftEmpty)
+	}
+	if leaveRes.OwnerLeft {
+		s.rooms.scheduleOwnerTimeout(roomID, 10*time.Minute)
+	}
+	return ""
+}
+
 // =============================
 // REST handlers: Profile / account
 // =============================
@@ -1390,7 +1976,8 @@ func (s *Server) handlePatchPlaylist(w http.ResponseWriter, r *http.Request) {
 	playlistID := playlistIDParam(r)
 
 	type reqBody struct {
-		Name *string `json:"name,omitempty"`
+		Name       *string `json:"name,omitempty"`
+		Visibility *string `json:"visibility,omitempty"`
 	}
 	var body reqBody
 	if err := decodeJSON(r, &body); err != nil {
@@ -1398,12 +1985,19 @@ func (s *Server) handlePatchPlaylist(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if body.Name == nil {
+	if body.Name == nil && body.Visibility == nil {
 		writeError(w, http.StatusBadRequest, "invalid input")
 		return
 	}
 
-	pl, err := s.nttRepo.UpdatePlaylistName(r.Context(), sub, playlistID, strings.TrimSpace(*body.Name))
+	var pl namethattune.Playlist
+	var err error
+	if body.Name != nil {
+		pl, err = s.nttRepo.UpdatePlaylistName(r.Context(), sub, playlistID, strings.TrimSpace(*body.Name))
+	}
+	if err == nil && body.Visibility != nil {
+		pl, err = s.nttRepo.SetPlaylistVisibility(r.Context(), sub, playlistID, *body.Visibility)
+	}
 	if err != nil {
 		status, msg := mapDomainErr(err)
 		writeError(w, status, msg)
@@ -1413,59 +2007,424 @@ func (s *Server) handlePatchPlaylist(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, pl)
 }
 
-func (s *Server) handleAddPlaylistItem(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleDeletePlaylist(w http.ResponseWriter, r *http.Request) {
 	sub := userSub(r)
-	playlistID := playlistIDParam(r)
-
-	type reqBody struct {
-		YouTubeURL string `json:"youtubeUrl"`
-	}
-	var body reqBody
-	if err := decodeJSON(r, &body); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid json")
-		return
-	}
 
-	youtubeURL := strings.TrimSpace(body.YouTubeURL)
-	meta, err := namethattune.FetchYouTubeMetadata(r.Context(), youtubeURL)
+	roomIDs, err := s.nttRepo.DeletePlaylist(r.Context(), sub, playlistIDParam(r))
 	if err != nil {
-		status, msg := mapDomainErr(fmt.Errorf("%w: %s", core.ErrInvalidInput, err.Error()))
+		status, msg := mapDomainErr(err)
 		writeError(w, status, msg)
 		return
 	}
 
-	item, pl, err := s.nttRepo.AddPlaylistItem(r.Context(), sub, playlistID, meta.Title, youtubeURL, meta.ThumbnailURL)
+	// Rooms that were playing it are now empty: close their match and tell the clients.
+	for _, roomID := range roomIDs {
+		logMatchErr(roomID, "finish", s.nttRepo.FinishMatch(r.Context(), roomID))
+		if err := s.clearPlaybackState(roomID); err != nil {
+			log.Printf("delete playlist clear playback failed: roomId=%s err=%v", roomID, err)
+		}
+		s.broadcastPlaybackChange(r.Context(), roomID)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+func (s *Server) handleListTrashedPlaylists(w http.ResponseWriter, r *http.Request) {
+	sub := userSub(r)
+
+	pls, err := s.nttRepo.ListTrashedPlaylists(r.Context(), sub, s.retention)
 	if err != nil {
 		status, msg := mapDomainErr(err)
 		writeError(w, status, msg)
 		return
 	}
 
-	writeJSON(w, http.StatusCreated, map[string]any{
-		"item":     item,
-		"playlist": pl,
-	})
+	writeJSON(w, http.StatusOK, map[string]any{"playlists": pls})
 }
 
-func (s *Server) handlePatchPlaylistItem(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleRestorePlaylist(w http.ResponseWriter, r *http.Request) {
+	sub := userSub(r)
+
+	pl, err := s.nttRepo.RestorePlaylist(r.Context(), sub, playlistIDParam(r), s.retention)
+	if err != nil {
+		status, msg := mapDomainErr(err)
+		writeError(w, status, msg)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, pl)
+}
+
+func (s *Server) handleGetPlaylist(w http.ResponseWriter, r *http.Request) {
+	sub := userSub(r)
+
+	pl, err := s.nttRepo.GetPlaylist(r.Context(), sub, playlistIDParam(r))
+	if err != nil {
+		status, msg := mapDomainErr(err)
+		writeError(w, status, msg)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, pl)
+}
+
+func (s *Server) handleDiscoverPlaylists(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	offset, _ := strconv.Atoi(q.Get("offset"))
+
+	pls, err := s.nttRepo.DiscoverPlaylists(r.Context(), q.Get("q"), limit, offset)
+	if err != nil {
+		status, msg := mapDomainErr(err)
+		writeError(w, status, msg)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"playlists": pls})
+}
+
+func (s *Server) handleListPlaylistCollaborators(w http.ResponseWriter, r *http.Request) {
+	sub := userSub(r)
+
+	collaborators, err := s.nttRepo.ListPlaylistCollaborators(r.Context(), sub, playlistIDParam(r))
+	if err != nil {
+		status, msg := mapDomainErr(err)
+		writeError(w, status, msg)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"collaborators": collaborators})
+}
+
+func (s *Server) handlePutPlaylistCollaborator(w http.ResponseWriter, r *http.Request) {
 	sub := userSub(r)
 	playlistID := playlistIDParam(r)
-	itemID := playlistItemIDParam(r)
 
 	type reqBody struct {
-		Title *string `json:"title,omitempty"`
+		Role string `json:"role"`
+	}
+	var body reqBody
+	if err := decodeJSON(r, &body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	if err := s.nttRepo.SetPlaylistCollaborator(r.Context(), sub, playlistID, collaboratorSubParam(r), strings.TrimSpace(body.Role)); err != nil {
+		status, msg := mapDomainErr(err)
+		writeError(w, status, msg)
+		return
+	}
+
+	s.handleListPlaylistCollaborators(w, r)
+}
+
+func (s *Server) handleDeletePlaylistCollaborator(w http.ResponseWriter, r *http.Request) {
+	sub := userSub(r)
+
+	if err := s.nttRepo.RemovePlaylistCollaborator(r.Context(), sub, playlistIDParam(r), collaboratorSubParam(r)); err != nil {
+		status, msg := mapDomainErr(err)
+		writeError(w, status, msg)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+}
+
+func (s *Server) handleAddPlaylistItem(w http.ResponseWriter, r *http.Request) {
+	sub := userSub(r)
+	playlistID := playlistIDParam(r)
+
+	type reqBody struct {
+		YouTubeURL string `json:"youtubeUrl"`
 	}
 	var body reqBody
 	if err := decodeJSON(r, &body); err != nil {
 		writeError(w, http.StatusBadRequest, "invalid json")
 		return
 	}
-	if body.Title == nil {
+
+	youtubeURL := strings.TrimSpace(body.YouTubeURL)
+	meta, err := namethattune.FetchYouTubeMetadata(r.Context(), youtubeURL)
+	if err != nil {
+		status, msg := mapDomainErr(fmt.Errorf("%w: %s", core.ErrInvalidInput, err.Error()))
+		writeError(w, status, msg)
+		return
+	}
+
+	item, pl, err := s.nttRepo.AddPlaylistItem(r.Context(), sub, playlistID, meta.Title, youtubeURL, meta.ThumbnailURL)
+	if err != nil {
+		status, msg := mapDomainErr(err)
+		writeError(w, status, msg)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"item":     item,
+		"playlist": pl,
+	})
+}
+
+func (s *Server) handleImportPlaylist(w http.ResponseWriter, r *http.Request) {
+	sub := userSub(r)
+	playlistID := playlistIDParam(r)
+
+	if s.playlists == nil {
+		writeError(w, http.StatusServiceUnavailable, "playlist import not configured")
+		return
+	}
+
+	type reqBody struct {
+		URL string `json:"url"`
+	}
+	var body reqBody
+	if err := decodeJSON(r, &body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	remoteID, err := namethattune.ExtractYouTubePlaylistID(body.URL)
+	if err != nil {
+		status, msg := mapDomainErr(fmt.Errorf("%w: %s", core.ErrInvalidInput, err.Error()))
+		writeError(w, status, msg)
+		return
+	}
+
+	entries, err := s.playlists.FetchPlaylist(r.Context(), remoteID)
+	if err != nil {
+		if !errors.Is(err, namethattune.ErrPlaylistSourceNotFound) {
+			log.Printf("playlist import fetch failed: playlistId=%s remote=%s err=%v", playlistID, remoteID, err)
+			writeError(w, http.StatusBadGateway, "playlist lookup failed")
+			return
+		}
+		status, msg := mapDomainErr(err)
+		writeError(w, status, msg)
+		return
+	}
+
+	res, pl, err := s.nttRepo.ImportPlaylistItems(r.Context(), sub, playlistID, entries)
+	if err != nil {
+		status, msg := mapDomainErr(err)
+		writeError(w, status, msg)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"added":    res.Added,
+		"skipped":  res.Skipped,
+		"playlist": pl,
+	})
+}
+
+// maxPlaylistFileBytes bounds the size of an uploaded playlist file.
+const maxPlaylistFileBytes = 4 << 20
+
+// playlistFileFormat picks the playlist file format from ?format=, then Content-Type.
+func playlistFileFormat(r *http.Request) string {
+	if f := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("format"))); f != "" {
+		return f
+	}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "text/csv") {
+		return "csv"
+	}
+	return "json"
+}
+
+func (s *Server) handleExportPlaylist(w http.ResponseWriter, r *http.Request) {
+	sub := userSub(r)
+	playlistID := playlistIDParam(r)
+
+	format := playlistFileFormat(r)
+	if format != "json" && format != "csv" {
+		writeError(w, http.StatusBadRequest, "invalid format")
+		return
+	}
+
+	pl, err := s.nttRepo.GetPlaylist(r.Context(), sub, playlistID)
+	if err != nil {
+		status, msg := mapDomainErr(err)
+		writeError(w, status, msg)
+		return
+	}
+	file := namethattune.NewPlaylistFile(pl, time.Now())
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="playlist-%s.%s"`, pl.ID, format))
+	if format == "json" {
+		writeJSON(w, http.StatusOK, file)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if err := namethattune.WritePlaylistCSV(w, file); err != nil {
+		log.Printf("playlist export failed: playlistId=%s err=%v", pl.ID, err)
+	}
+}
+
+func (s *Server) handleImportPlaylistFile(w http.ResponseWriter, r *http.Request) {
+	sub := userSub(r)
+
+	raw, err := io.ReadAll(io.LimitReader(r.Body, maxPlaylistFileBytes+1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid body")
+		return
+	}
+	if len(raw) > maxPlaylistFileBytes {
+		writeError(w, http.StatusRequestEntityTooLarge, "playlist file too large")
+		return
+	}
+
+	var file namethattune.PlaylistFile
+	switch playlistFileFormat(r) {
+	case "json":
+		file, err = namethattune.ParsePlaylistJSON(raw)
+	case "csv":
+		file, err = namethattune.ParsePlaylistCSV(bytes.NewReader(raw))
+	default:
+		writeError(w, http.StatusBadRequest, "invalid format")
+		return
+	}
+	if err != nil {
+		status, msg := mapDomainErr(err)
+		writeError(w, status, msg)
+		return
+	}
+	if name := strings.TrimSpace(r.URL.Query().Get("name")); name != "" {
+		file.Name = name
+	}
+	if strings.TrimSpace(file.Name) == "" {
+		file.Name = "Imported playlist"
+	}
+
+	pl, err := s.nttRepo.CreatePlaylistFromFile(r.Context(), sub, file)
+	if err != nil {
+		status, msg := mapDomainErr(err)
+		writeError(w, status, msg)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, pl)
+}
+
+// playlistItemIDsBody is the body of bulk item operations.
+type playlistItemIDsBody struct {
+	ItemIDs []string `json:"itemIds"`
+}
+
+func (s *Server) handleReorderPlaylistItems(w http.ResponseWriter, r *http.Request) {
+	sub := userSub(r)
+
+	var body playlistItemIDsBody
+	if err := decodeJSON(r, &body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	pl, err := s.nttRepo.ReorderPlaylistItems(r.Context(), sub, playlistIDParam(r), body.ItemIDs)
+	if err != nil {
+		status, msg := mapDomainErr(err)
+		writeError(w, status, msg)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, pl)
+}
+
+func (s *Server) handleDeletePlaylistItems(w http.ResponseWriter, r *http.Request) {
+	sub := userSub(r)
+
+	var body playlistItemIDsBody
+	if err := decodeJSON(r, &body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	pl, err := s.nttRepo.DeletePlaylistItems(r.Context(), sub, playlistIDParam(r), body.ItemIDs)
+	if err != nil {
+		status, msg := mapDomainErr(err)
+		writeError(w, status, msg)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, pl)
+}
+
+func (s *Server) handleMovePlaylistItems(w http.ResponseWriter, r *http.Request) {
+	sub := userSub(r)
+
+	type reqBody struct {
+		ItemIDs          []string `json:"itemIds"`
+		TargetPlaylistID string   `json:"targetPlaylistId"`
+	}
+	var body reqBody
+	if err := decodeJSON(r, &body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	from, to, err := s.nttRepo.MovePlaylistItems(r.Context(), sub, playlistIDParam(r), strings.TrimSpace(body.TargetPlaylistID), body.ItemIDs)
+	if err != nil {
+		status, msg := mapDomainErr(err)
+		writeError(w, status, msg)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"playlist": from,
+		"target":   to,
+	})
+}
+
+func (s *Server) handleShufflePlaylist(w http.ResponseWriter, r *http.Request) {
+	sub := userSub(r)
+
+	pl, err := s.nttRepo.ShufflePlaylistItems(r.Context(), sub, playlistIDParam(r))
+	if err != nil {
+		status, msg := mapDomainErr(err)
+		writeError(w, status, msg)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, pl)
+}
+
+func (s *Server) handleDuplicatePlaylist(w http.ResponseWriter, r *http.Request) {
+	sub := userSub(r)
+
+	type reqBody struct {
+		Name string `json:"name"`
+	}
+	var body reqBody
+	// The body is optional.
+	if err := decodeJSON(r, &body); err != nil && !isJSONEOF(err) {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	pl, err := s.nttRepo.DuplicatePlaylist(r.Context(), sub, playlistIDParam(r), body.Name)
+	if err != nil {
+		status, msg := mapDomainErr(err)
+		writeError(w, status, msg)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, pl)
+}
+
+func (s *Server) handlePatchPlaylistItem(w http.ResponseWriter, r *http.Request) {
+	sub := userSub(r)
+	playlistID := playlistIDParam(r)
+	itemID := playlistItemIDParam(r)
+
+	var body namethattune.PlaylistItemPatch
+	if err := decodeJSON(r, &body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if body.Empty() {
 		writeError(w, http.StatusBadRequest, "invalid input")
 		return
 	}
 
-	item, err := s.nttRepo.UpdatePlaylistItemTitle(r.Context(), sub, playlistID, itemID, *body.Title)
+	item, err := s.nttRepo.UpdatePlaylistItem(r.Context(), sub, playlistID, itemID, body)
 	if err != nil {
 		status, msg := mapDomainErr(err)
 		writeError(w, status, msg)
@@ -1489,6 +2448,36 @@ func (s *Server) handleDeletePlaylistItem(w http.ResponseWriter, r *http.Request
 	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
 }
 
+// =============================
+// REST handlers: Match history
+// =============================
+
+func (s *Server) handleListMatches(w http.ResponseWriter, r *http.Request) {
+	sub := userSub(r)
+
+	matches, err := s.nttRepo.ListUserMatches(r.Context(), sub)
+	if err != nil {
+		status, msg := mapDomainErr(err)
+		writeError(w, status, msg)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"matches": matches})
+}
+
+func (s *Server) handleGetMatch(w http.ResponseWriter, r *http.Request) {
+	sub := userSub(r)
+
+	match, err := s.nttRepo.GetMatch(r.Context(), sub, matchIDParam(r))
+	if err != nil {
+		status, msg := mapDomainErr(err)
+		writeError(w, status, msg)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, match)
+}
+
 // =============================
 // WebSocket: room events
 // =============================
@@ -1499,14 +2488,16 @@ func (s *Server) handleRoomWS(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "missing roomId")
 		return
 	}
+	// Only the commands of the game mounting this route are dispatched.
+	gameID := requestGameID(r)
 
 	if s.rt == nil {
 		writeError(w, http.StatusInternalServerError, "realtime not configured")
 		return
 	}
 
-	events, cancel := s.rt.Room(roomID).Subscribe(256)
-	defer cancel()
+	connID := randomToken()
+	defer s.clocks.forgetConn(roomID, connID)
 
 	originPatterns := []string{
 		"http://localhost:5173",
@@ -1520,6 +2511,7 @@ func (s *Server) handleRoomWS(w http.ResponseWriter, r *http.Request) {
 
 	c, err := websocket.Accept(w, r, &websocket.AcceptOptions{
 		OriginPatterns: originPatterns,
+		Subprotocols:   []string{wsProtocolV2, wsProtocolV1},
 	})
 	if err != nil {
 		// Make failures visible (otherwise the browser just shows "WebSocket connection failed").
@@ -1546,6 +2538,8 @@ func (s *Server) handleRoomWS(w http.ResponseWriter, r *http.Request) {
 	defer func() { _ = c.Close(websocket.StatusNormalClosure, "bye") }()
 
 	// Send initial snapshot.
+	hub := s.rt.Room(roomID)
+	seqBefore := hub.Seq()
 	snap, err := s.loadRoomSnapshot(r.Context(), roomID)
 	if err != nil {
 		status, msg := mapDomainErr(err)
@@ -1554,34 +2548,99 @@ func (s *Server) handleRoomWS(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := wsWriteJSON(r.Context(), c, realtime.Event{
+	// The connection's view of the room is fixed at connect time (see requestViewer).
+	// ?spectate=1 forces the spectator view, e.g. for a screen shown to the audience; such
+	// a connection cannot send room commands.
+	spectating := r.URL.Query().Get("spectate") == "1"
+	viewer := realtime.Viewer{Role: realtime.RoleSpectator, Audience: true}
+	if !spectating {
+		if viewer, err = s.requestViewer(r, snap); err != nil {
+			_ = c.Close(websocket.StatusInternalError, "internal server error")
+			return
+		}
+	}
+	// Watching without a seat needs the same access as joining: the spectator token from
+	// /spectate, or a room anyone may join.
+	if viewer.Role == realtime.RoleSpectator {
+		allowed, err := s.canSpectate(r, roomID, spectating)
+		if err != nil {
+			_ = c.Close(websocket.StatusInternalError, "internal server error")
+			return
+		}
+		if !allowed {
+			_ = c.Close(websocket.StatusPolicyViolation, "unauthorized")
+			return
+		}
+	}
+	events, cancel := hub.Subscribe(256, viewer)
+	defer cancel()
+
+	// Events carry per-room sequence numbers (realtime.Event.Seq). The snapshot sent on
+	// connect and on room.resync carries the seq of the last event it reflects; events up
+	// to that seq are skipped, so a client that sees a seq jump afterwards missed an event
+	// and should send room.resync to get the full snapshot again.
+	covered := hub.Seq()
+	if covered != seqBefore {
+		// Something happened while subscribing: reload so the snapshot reflects it.
+		if snap, err = s.loadRoomSnapshot(r.Context(), roomID); err != nil {
+			status, msg := mapDomainErr(err)
+			_ = c.Close(websocket.StatusPolicyViolation, msg)
+			_ = status
+			return
+		}
+	}
+	patches := c.Subprotocol() == wsProtocolV2 || r.URL.Query().Get("patches") == "1"
+	var stream snapshotStream
+
+	// A connection opened with the player's token keeps their seat connected (see presence.go).
+	if q := r.URL.Query(); !spectating && q.Get("playerId") != "" {
+		playerID := q.Get("playerId")
+		ok, err := s.validatePlayerToken(roomID, playerID, q.Get("playerToken"))
+		if err != nil {
+			_ = c.Close(websocket.StatusInternalError, "internal server error")
+			return
+		}
+		if ok {
+			s.presenceConnected(r.Context(), roomID, playerID)
+			defer s.presenceDisconnected(roomID, playerID)
+		}
+	}
+
+	initial, err := stream.reset(realtime.Event{
 		Type:    "room.snapshot",
 		RoomID:  roomID,
-		Payload: snap,
-	}); err != nil {
+		Seq:     covered,
+		Payload: viewSnapshot(snap, viewer),
+	})
+	if err != nil {
+		return
+	}
+	if err := wsWriteJSON(r.Context(), c, initial); err != nil {
 		return
 	}
 
 	type wsInbound struct {
-		Type    string          `json:"type"`
-		RoomID  string          `json:"roomId"`
-		Payload json.RawMessage `json:"payload"`
-	}
-	type wsCommandPayload struct {
-		Action            string `json:"action"`
-		OwnerToken        string `json:"ownerToken,omitempty"`
-		PlayerToken       string `json:"playerToken,omitempty"`
-		PlayerID          string `json:"playerId,omitempty"`
-		PlaylistID        string `json:"playlistId,omitempty"`
-		TrackIndex        *int   `json:"trackIndex,omitempty"`
-		Paused            *bool  `json:"paused,omitempty"`
-		PositionMS        *int   `json:"positionMs,omitempty"`
-		Delta             *int   `json:"delta,omitempty"`
-		Score             *int   `json:"score,omitempty"`
-		Correct           *bool  `json:"correct,omitempty"`
-		Buffering         *bool  `json:"buffering,omitempty"`
-		Ready             *bool  `json:"ready,omitempty"`
-		PlaybackUpdatedAt string `json:"playbackUpdatedAt,omitempty"`
+		Type   string `json:"type"`
+		RoomID string `json:"roomId"`
+		// RequestID is echoed in the answer to a room.command (see commands.go).
+		RequestID string          `json:"requestId,omitempty"`
+		Payload   json.RawMessage `json:"payload"`
+	}
+	// time.sync is an NTP-style exchange (unix ms): the client sends t0, the server
+	// answers with t0, t1 (receive) and t2 (send), and the client notes t3 on receipt.
+	// Players report their last completed exchange in Sample on the next request so the
+	// server can track their offset and RTT.
+	type wsTimeSyncSample struct {
+		T0 int64 `json:"t0"`
+		T1 int64 `json:"t1"`
+		T2 int64 `json:"t2"`
+		T3 int64 `json:"t3"`
+	}
+	type wsTimeSyncPayload struct {
+		T0          int64             `json:"t0"`
+		PlayerID    string            `json:"playerId,omitempty"`
+		PlayerToken string            `json:"playerToken,omitempty"`
+		Sample      *wsTimeSyncSample `json:"sample,omitempty"`
 	}
 
 	sendDirect := make(chan realtime.Event, 16)
@@ -1603,10 +2662,35 @@ func (s *Server) handleRoomWS(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 
+	resync := make(chan struct{}, 1)
+
 	// Reader: handle commands + drain to detect close/pings.
 	readDone := make(chan struct{})
+
+	// Heartbeat: a connection that stops answering pings is closed, which releases its
+	// seat binding.
+	go func() {
+		ticker := time.NewTicker(presencePingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-readDone:
+				return
+			case <-ticker.C:
+			}
+			pingCtx, cancel := context.WithTimeout(r.Context(), presencePongTimeout)
+			err := c.Ping(pingCtx)
+			cancel()
+			if err != nil {
+				_ = c.Close(websocket.StatusPolicyViolation, "heartbeat timeout")
+				return
+			}
+		}
+	}()
 	go func() {
 		defer close(readDone)
+		// Server timestamps of the last time.sync answer, to reject forged samples.
+		var lastSyncT1, lastSyncT2 int64
 		for {
 			_, data, err := c.Read(r.Context())
 			if err != nil {
@@ -1625,215 +2709,71 @@ func (s *Server) handleRoomWS(w http.ResponseWriter, r *http.Request) {
 				})
 				continue
 			}
-			if msg.Type != "room.command" {
-				continue
-			}
-			if msg.RoomID != "" && msg.RoomID != roomID {
+			if msg.Type == "time.sync" {
+				t1 := time.Now().UTC().UnixMilli()
+				var payload wsTimeSyncPayload
+				if err := json.Unmarshal(msg.Payload, &payload); err != nil || payload.T0 == 0 {
+					queueDirect(realtime.Event{
+						Type:   "room.command.error",
+						RoomID: roomID,
+						Payload: map[string]any{
+							"message": "invalid time.sync payload",
+							"status":  http.StatusBadRequest,
+						},
+					})
+					continue
+				}
+				if sample := payload.Sample; sample != nil && payload.PlayerID != "" &&
+					sample.T1 == lastSyncT1 && sample.T2 == lastSyncT2 {
+					// A sample that cannot be checked is dropped; the next one will do.
+					if ok, err := s.validatePlayerToken(roomID, payload.PlayerID, payload.PlayerToken); err != nil {
+						log.Printf("time sync validate token failed: roomId=%s err=%v", roomID, err)
+					} else if ok {
+						if measured, ok := ntpSample(sample.T0, sample.T1, sample.T2, sample.T3); ok {
+							s.clocks.record(roomID, payload.PlayerID, connID, measured)
+						}
+					}
+				}
+				lastSyncT1 = t1
+				lastSyncT2 = time.Now().UTC().UnixMilli()
 				queueDirect(realtime.Event{
-					Type:   "room.command.error",
+					Type:   "time.sync",
 					RoomID: roomID,
 					Payload: map[string]any{
-						"message": "roomId mismatch",
-						"status":  http.StatusBadRequest,
+						"t0": payload.T0,
+						"t1": lastSyncT1,
+						"t2": lastSyncT2,
 					},
 				})
 				continue
 			}
-
-			var payload wsCommandPayload
-			if err := json.Unmarshal(msg.Payload, &payload); err != nil {
-				queueDirect(realtime.Event{
-					Type:   "room.command.error",
-					RoomID: roomID,
-					Payload: map[string]any{
-						"message": "invalid command payload",
-						"status":  http.StatusBadRequest,
-					},
-				})
+			if msg.Type == "room.resync" {
+				select {
+				case resync <- struct{}{}:
+				default:
+				}
 				continue
 			}
-			action := strings.TrimSpace(payload.Action)
-			if action == "" {
-				queueDirect(realtime.Event{
-					Type:   "room.command.error",
-					RoomID: roomID,
-					Payload: map[string]any{
-						"message": "missing action",
-						"status":  http.StatusBadRequest,
-					},
-				})
+			if msg.Type != "room.command" {
 				continue
 			}
-
-			var cmdErr error
-			var ownerSub string
-			ownerSubForRoom := func() (string, error) {
-				if ownerSub != "" {
-					return ownerSub, nil
-				}
-				snap, err := s.loadRoomSnapshot(r.Context(), roomID)
-				if err != nil {
-					status, msg := mapDomainErr(err)
-					return "", &apiError{Status: status, Message: msg}
-				}
-				if snap.OwnerSub == "" {
-					return "", &apiError{Status: http.StatusForbidden, Message: "forbidden"}
-				}
-				ownerSub = snap.OwnerSub
-				return ownerSub, nil
-			}
-			switch action {
-			case "kick":
-				if !s.validateOwnerToken(roomID, payload.OwnerToken) {
-					cmdErr = &apiError{Status: http.StatusUnauthorized, Message: "unauthorized"}
-					break
-				}
-				sub, err := ownerSubForRoom()
-				if err != nil {
-					cmdErr = err
-					break
-				}
-				_, cmdErr = s.doKick(r.Context(), roomID, sub, payload.PlayerID)
-			case "score.add":
-				if !s.validateOwnerToken(roomID, payload.OwnerToken) {
-					cmdErr = &apiError{Status: http.StatusUnauthorized, Message: "unauthorized"}
-					break
-				}
-				if payload.Delta == nil {
-					cmdErr = &apiError{Status: http.StatusBadRequest, Message: "invalid input"}
-					break
-				}
-				sub, err := ownerSubForRoom()
-				if err != nil {
-					cmdErr = err
-					break
-				}
-				_, cmdErr = s.doScoreAdd(r.Context(), roomID, sub, payload.PlayerID, *payload.Delta)
-			case "score.set":
-				if !s.validateOwnerToken(roomID, payload.OwnerToken) {
-					cmdErr = &apiError{Status: http.StatusUnauthorized, Message: "unauthorized"}
-					break
-				}
-				if payload.Score == nil {
-					cmdErr = &apiError{Status: http.StatusBadRequest, Message: "invalid input"}
-					break
-				}
-				sub, err := ownerSubForRoom()
-				if err != nil {
-					cmdErr = err
-					break
-				}
-				_, cmdErr = s.doScoreSet(r.Context(), roomID, sub, payload.PlayerID, *payload.Score)
-			case "playlist.load":
-				if !s.validateOwnerToken(roomID, payload.OwnerToken) {
-					cmdErr = &apiError{Status: http.StatusUnauthorized, Message: "unauthorized"}
-					break
-				}
-				sub, err := ownerSubForRoom()
-				if err != nil {
-					cmdErr = err
-					break
-				}
-				_, cmdErr = s.doLoadPlaylist(r.Context(), roomID, sub, payload.PlaylistID)
-			case "playback.set":
-				if !s.validateOwnerToken(roomID, payload.OwnerToken) {
-					cmdErr = &apiError{Status: http.StatusUnauthorized, Message: "unauthorized"}
-					break
-				}
-				if payload.TrackIndex == nil {
-					cmdErr = &apiError{Status: http.StatusBadRequest, Message: "invalid input"}
-					break
-				}
-				sub, err := ownerSubForRoom()
-				if err != nil {
-					cmdErr = err
-					break
-				}
-				_, cmdErr = s.doPlaybackSet(r.Context(), roomID, sub, *payload.TrackIndex, payload.Paused, payload.PositionMS)
-			case "playback.pause":
-				if !s.validateOwnerToken(roomID, payload.OwnerToken) {
-					cmdErr = &apiError{Status: http.StatusUnauthorized, Message: "unauthorized"}
-					break
-				}
-				if payload.Paused == nil {
-					cmdErr = &apiError{Status: http.StatusBadRequest, Message: "invalid input"}
-					break
-				}
-				sub, err := ownerSubForRoom()
-				if err != nil {
-					cmdErr = err
-					break
-				}
-				_, cmdErr = s.doPlaybackPause(r.Context(), roomID, sub, *payload.Paused)
-			case "playback.seek":
-				if !s.validateOwnerToken(roomID, payload.OwnerToken) {
-					cmdErr = &apiError{Status: http.StatusUnauthorized, Message: "unauthorized"}
-					break
-				}
-				if payload.PositionMS == nil {
-					cmdErr = &apiError{Status: http.StatusBadRequest, Message: "invalid input"}
-					break
-				}
-				sub, err := ownerSubForRoom()
-				if err != nil {
-					cmdErr = err
-					break
-				}
-				_, cmdErr = s.doPlaybackSeek(r.Context(), roomID, sub, *payload.PositionMS)
-			case "playback.buffer":
-				if payload.Buffering == nil {
-					cmdErr = &apiError{Status: http.StatusBadRequest, Message: "invalid input"}
-					break
-				}
-				if payload.PlayerID == "" || !s.validatePlayerToken(roomID, payload.PlayerID, payload.PlayerToken) {
-					cmdErr = &apiError{Status: http.StatusUnauthorized, Message: "unauthorized"}
-					break
-				}
-				_, cmdErr = s.doPlaybackBuffering(r.Context(), roomID, payload.PlayerID, *payload.Buffering)
-			case "playback.ready":
-				if payload.Ready == nil {
-					cmdErr = &apiError{Status: http.StatusBadRequest, Message: "invalid input"}
-					break
-				}
-				if payload.PlayerID == "" || !s.validatePlayerToken(roomID, payload.PlayerID, payload.PlayerToken) {
-					cmdErr = &apiError{Status: http.StatusUnauthorized, Message: "unauthorized"}
-					break
-				}
-				_, cmdErr = s.doPlaybackReady(r.Context(), roomID, payload.PlayerID, *payload.Ready, payload.PlaybackUpdatedAt)
-			case "buzz":
-				if payload.PlayerID == "" || !s.validatePlayerToken(roomID, payload.PlayerID, payload.PlayerToken) {
-					cmdErr = &apiError{Status: http.StatusUnauthorized, Message: "unauthorized"}
-					break
-				}
-				cmdErr = s.doBuzz(r.Context(), roomID, payload.PlayerID)
-			case "buzz.resolve":
-				if !s.validateOwnerToken(roomID, payload.OwnerToken) {
-					cmdErr = &apiError{Status: http.StatusUnauthorized, Message: "unauthorized"}
-					break
-				}
-				if payload.Correct == nil {
-					cmdErr = &apiError{Status: http.StatusBadRequest, Message: "invalid input"}
-					break
-				}
-				sub, err := ownerSubForRoom()
-				if err != nil {
-					cmdErr = err
-					break
-				}
-				cmdErr = s.doBuzzResolve(r.Context(), roomID, sub, payload.PlayerID, *payload.Correct)
-			default:
-				cmdErr = &apiError{Status: http.StatusBadRequest, Message: "unknown action"}
+			if msg.RoomID != "" && msg.RoomID != roomID {
+				queueDirect(commandErrorEvent(roomID, msg.RequestID, "", &apiError{Status: http.StatusBadRequest, Message: "roomId mismatch"}))
+				continue
 			}
 
-			if cmdErr != nil {
-				status, msg := mapAPIError(cmdErr)
+			action, err := s.dispatchCommand(r.Context(), gameID, roomID, spectating, msg.Payload)
+			if err != nil {
+				queueDirect(commandErrorEvent(roomID, msg.RequestID, action, err))
+				continue
+			}
+			if msg.RequestID != "" {
 				queueDirect(realtime.Event{
-					Type:   "room.command.error",
+					Type:   "room.command.ok",
 					RoomID: roomID,
 					Payload: map[string]any{
-						"action":  action,
-						"message": msg,
-						"status":  status,
+						"action":    action,
+						"requestId": msg.RequestID,
 					},
 				})
 			}
@@ -1852,20 +2792,64 @@ func (s *Server) handleRoomWS(w http.ResponseWriter, r *http.Request) {
 			if !ok {
 				return
 			}
+			if ev, ok = hub.Render(ev, viewer); !ok {
+				continue
+			}
 			if ev.RoomID == "" {
 				ev.RoomID = roomID
 			}
 			if err := wsWriteJSON(r.Context(), c, ev); err != nil {
 				return
 			}
+		case <-resync:
+			seq := hub.Seq()
+			snap, err := s.loadRoomSnapshot(r.Context(), roomID)
+			if err != nil {
+				continue
+			}
+			ev, err := stream.reset(realtime.Event{
+				Type:    "room.snapshot",
+				RoomID:  roomID,
+				Seq:     seq,
+				Payload: viewSnapshot(snap, viewer),
+			})
+			if err != nil {
+				continue
+			}
+			covered = seq
+			if err := wsWriteJSON(r.Context(), c, ev); err != nil {
+				return
+			}
 		case ev, ok := <-events:
 			if !ok {
 				return
 			}
+			// The hub dropped events for this connection: push the full snapshot again, or
+			// close with wsStatusSlowConsumer once the hub gave up on it.
+			if ev.Type == realtime.EventResync {
+				if rs, _ := ev.Payload.(realtime.Resync); rs.Closed {
+					_ = c.Close(wsStatusSlowConsumer, "slow consumer")
+					return
+				}
+				select {
+				case resync <- struct{}{}:
+				default:
+				}
+				continue
+			}
+			if ev.Seq <= covered {
+				continue
+			}
 			// Ensure roomId is set.
 			if ev.RoomID == "" {
 				ev.RoomID = roomID
 			}
+			if patches && ev.Type == "room.snapshot" {
+				if ev, err = stream.next(ev); err != nil {
+					log.Printf("ws snapshot patch failed: roomId=%s err=%v", roomID, err)
+					continue
+				}
+			}
 			if err := wsWriteJSON(r.Context(), c, ev); err != nil {
 				return
 			}
@@ -1873,6 +2857,60 @@ func (s *Server) handleRoomWS(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// commandErrorEvent is the room.command.error answer to a command.
+func commandErrorEvent(roomID, requestID, action string, err error) realtime.Event {
+	status, msg := mapAPIError(err)
+	payload := map[string]any{
+		"message": msg,
+		"status":  status,
+	}
+	if action != "" {
+		payload["action"] = action
+	}
+	if requestID != "" {
+		payload["requestId"] = requestID
+	}
+	return realtime.Event{Type: "room.command.error", RoomID: roomID, Payload: payload}
+}
+
+// realtimeStats sums the delivery counters of the local room hubs. Per-room counters stay
+// internal (realtime.Registry.Stats), as room IDs of private rooms must not leak.
+func (s *Server) realtimeStats() map[string]any {
+	var total realtime.HubStats
+	rooms := 0
+	if s.rt != nil {
+		for _, st := range s.rt.Stats() {
+			rooms++
+			total.Subscribers += st.Subscribers
+			total.Delivered += st.Delivered
+			total.Dropped += st.Dropped
+			total.Resyncs += st.Resyncs
+			total.Closed += st.Closed
+		}
+	}
+	return map[string]any{
+		"rooms":       rooms,
+		"subscribers": total.Subscribers,
+		"delivered":   total.Delivered,
+		"dropped":     total.Dropped,
+		"resyncs":     total.Resyncs,
+		"closed":      total.Closed,
+	}
+}
+
+// Room WebSocket protocol versions, negotiated through the Sec-WebSocket-Protocol header.
+// A client that offers none of them (or no header at all) speaks v1. v2 sends snapshot
+// updates as room.patch events (see snapshot_stream.go); command messages are the same in
+// both versions.
+const (
+	wsProtocolV1 = "bes-games.v1"
+	wsProtocolV2 = "bes-games.v2"
+)
+
+// wsStatusSlowConsumer closes a room WebSocket that kept missing events (see
+// realtime.DefaultDropLimit). The client should reconnect and reload the snapshot.
+const wsStatusSlowConsumer websocket.StatusCode = 4008
+
 func wsWriteJSON(ctx context.Context, c *websocket.Conn, v any) error {
 	b, err := json.Marshal(v)
 	if err != nil {
@@ -1,10 +1,15 @@
 package httpapi
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"slices"
+	"sort"
 	"strings"
 	"testing"
 	"time"
@@ -115,9 +120,12 @@ func TestRooms_CreateListJoinLeaveAndGetSnapshot(t *testing.T) {
 
 	roomID := createRoom(t, h, "owner-sub", "Test Room")
 
-	// List rooms shows our room
-	{
+	// List rooms shows our room, with its owner's sub only to the owner
+	for _, sub := range []string{"", "owner-sub"} {
 		req := httptest.NewRequest(http.MethodGet, "/api/games/name-that-tune/rooms", nil)
+		if sub != "" {
+			req.Header.Set("X-User-Sub", sub)
+		}
 		rr := httptest.NewRecorder()
 		h.ServeHTTP(rr, req)
 
@@ -129,6 +137,7 @@ func TestRooms_CreateListJoinLeaveAndGetSnapshot(t *testing.T) {
 			Rooms []struct {
 				RoomID        string `json:"roomId"`
 				Name          string `json:"name"`
+				OwnerSub      string `json:"ownerSub"`
 				OnlinePlayers int    `json:"onlinePlayers"`
 			} `json:"rooms"`
 		}
@@ -143,6 +152,9 @@ func TestRooms_CreateListJoinLeaveAndGetSnapshot(t *testing.T) {
 				if r.Name != "Test Room" {
 					t.Fatalf("expected room name %q, got %q", "Test Room", r.Name)
 				}
+				if r.OwnerSub != sub {
+					t.Fatalf("list rooms as %q: expected ownerSub %q, got %q", sub, sub, r.OwnerSub)
+				}
 				if r.OnlinePlayers != 0 {
 					t.Fatalf("expected onlinePlayers 0 before join, got %d", r.OnlinePlayers)
 				}
@@ -362,6 +374,110 @@ func TestProfileAndPlaylists_CRUDAndRoomLoad(t *testing.T) {
 	_ = playlistID
 }
 
+func TestPlaylists_PatchItemMetadata(t *testing.T) {
+	t.Setenv("BES_YOUTUBE_OEMBED_DISABLE", "1")
+
+	ctx := context.Background()
+	pool := freshDB(t, ctx)
+	srv := newTestServer(t, pool)
+	h := srv.Handler(Options{AllowedOrigins: []string{"http://localhost:5173"}})
+
+	ownerSub := "owner-sub"
+	playlistID := createPlaylistWithItems(t, h, ownerSub, "Hits", "https://www.youtube.com/watch?v=dQw4w9WgXcQ")
+	pl, err := srv.nttRepo.GetPlaylist(ctx, ownerSub, playlistID)
+	if err != nil {
+		t.Fatalf("get playlist: %v", err)
+	}
+	itemURL := "/api/games/name-that-tune/playlists/" + playlistID + "/items/" + pl.Items[0].ID
+
+	patch := func(body string) (int, namethattune.PlaylistItem) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPatch, itemURL, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User-Sub", ownerSub)
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+
+		var item namethattune.PlaylistItem
+		if rr.Code == http.StatusOK {
+			if err := json.Unmarshal(rr.Body.Bytes(), &item); err != nil {
+				t.Fatalf("patch item: unmarshal: %v", err)
+			}
+		}
+		return rr.Code, item
+	}
+	// readBack returns the item as stored, through GET playlist.
+	readBack := func() namethattune.PlaylistItem {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/games/name-that-tune/playlists/"+playlistID, nil)
+		req.Header.Set("X-User-Sub", ownerSub)
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("get playlist: expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var got namethattune.Playlist
+		if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+			t.Fatalf("get playlist: unmarshal: %v", err)
+		}
+		if len(got.Items) != 1 {
+			t.Fatalf("expected 1 item, got %d", len(got.Items))
+		}
+		return got.Items[0]
+	}
+
+	if code, _ := patch(`{}`); code != http.StatusBadRequest {
+		t.Fatalf("empty patch: expected 400, got %d", code)
+	}
+
+	if code, _ := patch(`{"artist":"  Rick Astley "}`); code != http.StatusOK {
+		t.Fatalf("patch artist: expected 200, got %d", code)
+	}
+	if got := readBack(); got.Artist != "Rick Astley" {
+		t.Fatalf("expected trimmed artist, got %q", got.Artist)
+	}
+
+	if code, _ := patch(`{"songTitle":"Never Gonna Give You Up"}`); code != http.StatusOK {
+		t.Fatalf("patch song title: expected 200, got %d", code)
+	}
+	if got := readBack(); got.SongTitle != "Never Gonna Give You Up" || got.Artist != "Rick Astley" {
+		t.Fatalf("expected song title set and artist kept, got %+v", got)
+	}
+
+	if code, _ := patch(`{"year":1987}`); code != http.StatusOK {
+		t.Fatalf("patch year: expected 200, got %d", code)
+	}
+	if got := readBack(); got.Year == nil || *got.Year != 1987 {
+		t.Fatalf("expected year 1987, got %v", got.Year)
+	}
+	if code, _ := patch(`{"year":87}`); code != http.StatusBadRequest {
+		t.Fatalf("patch invalid year: expected 400, got %d", code)
+	}
+	if code, _ := patch(`{"year":0}`); code != http.StatusOK {
+		t.Fatalf("clear year: expected 200, got %d", code)
+	}
+	if got := readBack(); got.Year != nil {
+		t.Fatalf("expected year 0 to clear the year, got %d", *got.Year)
+	}
+
+	if code, _ := patch(`{"acceptedAnswers":["Never Gonna Give You Up"," ","Rickroll"]}`); code != http.StatusOK {
+		t.Fatalf("patch accepted answers: expected 200, got %d", code)
+	}
+	if got := readBack(); strings.Join(got.AcceptedAnswers, "|") != "Never Gonna Give You Up|Rickroll" {
+		t.Fatalf("expected blank answers dropped, got %q", got.AcceptedAnswers)
+	}
+	if code, _ := patch(`{"acceptedAnswers":[]}`); code != http.StatusOK {
+		t.Fatalf("clear accepted answers: expected 200, got %d", code)
+	}
+	got := readBack()
+	if len(got.AcceptedAnswers) != 0 {
+		t.Fatalf("expected accepted answers cleared, got %q", got.AcceptedAnswers)
+	}
+	if got.Artist != "Rick Astley" || got.SongTitle != "Never Gonna Give You Up" {
+		t.Fatalf("expected untouched fields kept, got %+v", got)
+	}
+}
+
 func TestRoomWebSocket_ReceivesInitialSnapshot(t *testing.T) {
 	t.Parallel()
 
@@ -415,97 +531,1637 @@ func TestRoomWebSocket_ReceivesInitialSnapshot(t *testing.T) {
 	}
 }
 
-// --------------------
-// Test server wiring
-// --------------------
+func TestRoomWebSocket_PatchesAndResync(t *testing.T) {
+	t.Setenv("BES_YOUTUBE_OEMBED_DISABLE", "1")
 
-func newTestServerNoDB(t *testing.T) *Server {
-	t.Helper()
+	ctx := context.Background()
+	pool := freshDB(t, ctx)
+	srv := newTestServer(t, pool)
+	h := srv.Handler(Options{AllowedOrigins: []string{"http://localhost:5173"}})
+	ts := httptest.NewServer(h)
+	defer ts.Close()
 
-	// For routes that don't require DB (like /healthz), we can run a server with nil deps.
-	// Handlers that touch DB will panic; tests must not call them here.
-	return NewServer(nil, nil, realtime.NewRegistry(), nil, NewNameThatTuneModule())
-}
+	ownerSub := "owner-sub"
+	roomID := createRoom(t, h, ownerSub, "Patch Room")
+	playerID := joinRoom(t, h, roomID, "player-sub", `{"nickname":"Alice"}`)
 
-func newTestServerNoDBWithAuth(t *testing.T, auth *AuthService) *Server {
-	t.Helper()
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http") + "/api/games/name-that-tune/rooms/" + roomID + "/ws?patches=1"
+	readCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	c, _, err := websocket.Dial(readCtx, wsURL, nil)
+	if err != nil {
+		t.Fatalf("ws dial: %v", err)
+	}
+	defer func() { _ = c.Close(websocket.StatusNormalClosure, "bye") }()
 
-	return NewServer(nil, nil, realtime.NewRegistry(), auth, NewNameThatTuneModule())
-}
+	type frame struct {
+		Type    string          `json:"type"`
+		Seq     uint64          `json:"seq"`
+		Payload json.RawMessage `json:"payload"`
+	}
+	next := func(types ...string) frame {
+		t.Helper()
+		for {
+			_, data, err := c.Read(readCtx)
+			if err != nil {
+				t.Fatalf("waiting for %v: %v", types, err)
+			}
+			var f frame
+			if err := json.Unmarshal(data, &f); err != nil {
+				t.Fatalf("ws frame json: %v", err)
+			}
+			if slices.Contains(types, f.Type) {
+				return f
+			}
+		}
+	}
 
-func newTestServer(t *testing.T, pool *pgxpool.Pool) *Server {
-	t.Helper()
-	coreRepo := core.NewRepo(pool)
-	nttRepo := namethattune.NewRepo(pool)
-	rt := realtime.NewRegistry()
-	return NewServer(coreRepo, nttRepo, rt, nil, NewNameThatTuneModule())
+	initial := next("room.snapshot")
+	var doc any
+	if err := json.Unmarshal(initial.Payload, &doc); err != nil {
+		t.Fatalf("decode snapshot: %v", err)
+	}
+
+	if _, err := srv.doScoreAdd(ctx, roomID, ownerSub, playerID, 2); err != nil {
+		t.Fatalf("add score: %v", err)
+	}
+	patchFrame := next("room.patch", "room.snapshot")
+	if patchFrame.Type != "room.patch" || patchFrame.Seq <= initial.Seq {
+		t.Fatalf("expected a room.patch after seq %d, got %q seq %d", initial.Seq, patchFrame.Type, patchFrame.Seq)
+	}
+	var patch struct {
+		BaseSeq uint64             `json:"baseSeq"`
+		Ops     []realtime.PatchOp `json:"ops"`
+	}
+	if err := json.Unmarshal(patchFrame.Payload, &patch); err != nil {
+		t.Fatalf("decode patch: %v", err)
+	}
+	if patch.BaseSeq != initial.Seq {
+		t.Fatalf("expected the patch to apply on seq %d, got %d", initial.Seq, patch.BaseSeq)
+	}
+	if doc, err = realtime.Apply(doc, patch.Ops); err != nil {
+		t.Fatalf("apply patch: %v", err)
+	}
+	raw, _ := json.Marshal(doc)
+	var patched namethattune.RoomSnapshot
+	if err := json.Unmarshal(raw, &patched); err != nil {
+		t.Fatalf("decode patched snapshot: %v", err)
+	}
+	if p := findPlayer(t, patched, playerID); p.Score != 2 {
+		t.Fatalf("expected the patched score to be 2, got %d", p.Score)
+	}
+
+	// A client that noticed a gap asks for the whole snapshot again.
+	if err := c.Write(readCtx, websocket.MessageText, []byte(`{"type":"room.resync"}`)); err != nil {
+		t.Fatalf("ws write: %v", err)
+	}
+	if full := next("room.snapshot"); full.Seq < patchFrame.Seq {
+		t.Fatalf("expected the resync snapshot to cover seq %d, got %d", patchFrame.Seq, full.Seq)
+	}
 }
 
-func freshDB(t *testing.T, ctx context.Context) *pgxpool.Pool {
-	t.Helper()
+func TestRoomWebSocket_CommandsEchoRequestIDs(t *testing.T) {
+	t.Setenv("BES_YOUTUBE_OEMBED_DISABLE", "1")
 
-	// If no DB configured, skip integration tests.
-	testutil.SkipIfNoDB(t)
+	ctx := context.Background()
+	pool := freshDB(t, ctx)
+	srv := newTestServer(t, pool)
+	h := srv.Handler(Options{AllowedOrigins: []string{"http://localhost:5173"}})
+	ts := httptest.NewServer(h)
+	defer ts.Close()
 
-	pool := testutil.WithFreshDB(ctx, t)
-	return pool
+	ownerSub := "owner-sub"
+	roomID := createRoom(t, h, ownerSub, "Command Room")
+	playerID := joinRoom(t, h, roomID, "player-sub", `{"nickname":"Alice"}`)
+	ownerToken, err := srv.getOrCreateOwnerToken(roomID)
+	if err != nil {
+		t.Fatalf("owner token: %v", err)
+	}
+
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http") + "/api/games/name-that-tune/rooms/" + roomID + "/ws"
+	readCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+	c, _, err := websocket.Dial(readCtx, wsURL, &websocket.DialOptions{Subprotocols: []string{"bes-games.v2", "bes-games.v1"}})
+	if err != nil {
+		t.Fatalf("ws dial: %v", err)
+	}
+	defer func() { _ = c.Close(websocket.StatusNormalClosure, "bye") }()
+	if got := c.Subprotocol(); got != "bes-games.v2" {
+		t.Fatalf("expected protocol bes-games.v2, got %q", got)
+	}
+
+	send := func(requestID, payload string) map[string]any {
+		t.Helper()
+		msg := `{"type":"room.command","requestId":"` + requestID + `","payload":` + payload + `}`
+		if err := c.Write(readCtx, websocket.MessageText, []byte(msg)); err != nil {
+			t.Fatalf("ws write: %v", err)
+		}
+		for {
+			_, data, err := c.Read(readCtx)
+			if err != nil {
+				t.Fatalf("waiting for the answer to %s: %v", requestID, err)
+			}
+			var ev struct {
+				Type    string         `json:"type"`
+				Payload map[string]any `json:"payload"`
+			}
+			if err := json.Unmarshal(data, &ev); err != nil {
+				t.Fatalf("ws frame json: %v", err)
+			}
+			if (ev.Type == "room.command.ok" || ev.Type == "room.command.error") && ev.Payload["requestId"] == requestID {
+				ev.Payload["type"] = ev.Type
+				return ev.Payload
+			}
+		}
+	}
+
+	ok := send("r1", `{"action":"score.add","ownerToken":"`+ownerToken+`","playerId":"`+playerID+`","delta":3}`)
+	if ok["type"] != "room.command.ok" || ok["action"] != "score.add" {
+		t.Fatalf("expected score.add to be acknowledged, got %v", ok)
+	}
+	snap, err := srv.loadRoomSnapshot(ctx, roomID)
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	if p := findPlayer(t, snap, playerID); p.Score != 3 {
+		t.Fatalf("expected score 3, got %d", p.Score)
+	}
+
+	failed := send("r2", `{"action":"score.add","ownerToken":"`+ownerToken+`","playerId":"`+playerID+`"}`)
+	if failed["type"] != "room.command.error" || failed["message"] != "invalid input" || failed["status"] != float64(http.StatusBadRequest) {
+		t.Fatalf("expected an invalid input error for r2, got %v", failed)
+	}
+	if denied := send("r3", `{"action":"buzz","playerId":"`+playerID+`","playerToken":"nope"}`); denied["status"] != float64(http.StatusUnauthorized) {
+		t.Fatalf("expected an unauthorized error for r3, got %v", denied)
+	}
 }
 
-// --------------------
-// Helpers
-// --------------------
+func TestMatches_HistoryRecordedAndListed(t *testing.T) {
+	t.Setenv("BES_YOUTUBE_OEMBED_DISABLE", "1")
 
-func createRoom(t *testing.T, h http.Handler, ownerSub, name string) string {
-	t.Helper()
+	ctx := context.Background()
+	pool := freshDB(t, ctx)
+	srv := newTestServer(t, pool)
+	h := srv.Handler(Options{AllowedOrigins: []string{"http://localhost:5173"}})
 
-	req := httptest.NewRequest(http.MethodPost, "/api/games/name-that-tune/rooms", strings.NewReader(`{"name":"`+jsonEscape(name)+`"}`))
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-User-Sub", ownerSub)
+	ownerSub := "owner-sub"
+	playlistID := createPlaylistWithItems(t, h, ownerSub, "Hits", "https://www.youtube.com/watch?v=dQw4w9WgXcQ")
+	roomID := createRoom(t, h, ownerSub, "History Room")
+	playerID := joinRoom(t, h, roomID, "player-sub", `{"nickname":"Alice"}`)
+
+	if _, err := srv.doLoadPlaylist(ctx, roomID, ownerSub, playlistID); err != nil {
+		t.Fatalf("load playlist: %v", err)
+	}
+	if err := srv.nttRepo.TogglePauseSafe(ctx, roomID, ownerSub, false); err != nil {
+		t.Fatalf("start playback: %v", err)
+	}
+	if err := srv.doBuzz(ctx, roomID, playerID, nil, nil); err != nil {
+		t.Fatalf("buzz: %v", err)
+	}
+	if err := srv.doBuzzResolve(ctx, roomID, ownerSub, playerID, true); err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if err := srv.rooms.closeRoom(ctx, roomID, reasonOwnerLeftEmpty); err != nil {
+		t.Fatalf("close room: %v", err)
+	}
 
+	req := httptest.NewRequest(http.MethodGet, "/api/games/name-that-tune/matches", nil)
+	req.Header.Set("X-User-Sub", "player-sub")
 	rr := httptest.NewRecorder()
 	h.ServeHTTP(rr, req)
-
-	if rr.Code != http.StatusCreated {
-		t.Fatalf("create room: expected 201, got %d: %s", rr.Code, rr.Body.String())
+	if rr.Code != http.StatusOK {
+		t.Fatalf("list matches: expected 200, got %d: %s", rr.Code, rr.Body.String())
 	}
-
 	var res struct {
-		RoomID string `json:"roomId"`
+		Matches []namethattune.Match `json:"matches"`
 	}
 	if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
-		t.Fatalf("create room: unmarshal: %v", err)
+		t.Fatalf("list matches: unmarshal: %v", err)
 	}
-	if res.RoomID == "" {
-		t.Fatalf("create room: missing roomId in response: %s", rr.Body.String())
+	if len(res.Matches) != 1 {
+		t.Fatalf("expected 1 match, got %d", len(res.Matches))
+	}
+	if res.Matches[0].EndedAt == nil {
+		t.Fatalf("expected match to be finished")
+	}
+	if len(res.Matches[0].Standings) != 1 || res.Matches[0].Standings[0].Score != 1 {
+		t.Fatalf("unexpected standings: %#v", res.Matches[0].Standings)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/games/name-that-tune/matches/"+res.Matches[0].ID, nil)
+	req.Header.Set("X-User-Sub", ownerSub)
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("get match: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var match namethattune.Match
+	if err := json.Unmarshal(rr.Body.Bytes(), &match); err != nil {
+		t.Fatalf("get match: unmarshal: %v", err)
+	}
+	if len(match.Rounds) != 1 || len(match.Rounds[0].Buzzes) != 1 {
+		t.Fatalf("unexpected timeline: %#v", match.Rounds)
+	}
+	if c := match.Rounds[0].Buzzes[0].Correct; c == nil || !*c {
+		t.Fatalf("expected buzz to be marked correct")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/games/name-that-tune/matches/"+res.Matches[0].ID, nil)
+	req.Header.Set("X-User-Sub", "stranger-sub")
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("get match as stranger: expected 404, got %d: %s", rr.Code, rr.Body.String())
 	}
-	return res.RoomID
 }
 
-func joinRoom(t *testing.T, h http.Handler, roomID, sub, body string) string {
-	t.Helper()
+func TestMatches_StandingsKeepRemovedSeats(t *testing.T) {
+	t.Setenv("BES_YOUTUBE_OEMBED_DISABLE", "1")
 
-	req := httptest.NewRequest(http.MethodPost, "/api/games/name-that-tune/rooms/"+roomID+"/join", strings.NewReader(body))
-	req.Header.Set("Content-Type", "application/json")
-	if sub != "" {
-		req.Header.Set("X-User-Sub", sub)
+	ctx := context.Background()
+	pool := freshDB(t, ctx)
+	srv := newTestServer(t, pool)
+	h := srv.Handler(Options{AllowedOrigins: []string{"http://localhost:5173"}})
+
+	ownerSub := "owner-sub"
+	playlistID := createPlaylistWithItems(t, h, ownerSub, "Hits", "https://www.youtube.com/watch?v=dQw4w9WgXcQ")
+	roomID := createRoom(t, h, ownerSub, "History Room")
+	alice := joinRoom(t, h, roomID, "alice-sub", `{"nickname":"Alice"}`)
+	bob := joinRoom(t, h, roomID, "bob-sub", `{"nickname":"Bob"}`)
+	carol := joinRoom(t, h, roomID, "carol-sub", `{"nickname":"Carol"}`)
+
+	if _, err := srv.doLoadPlaylist(ctx, roomID, ownerSub, playlistID); err != nil {
+		t.Fatalf("load playlist: %v", err)
+	}
+	if err := srv.nttRepo.StartRound(ctx, roomID); err != nil {
+		t.Fatalf("start round: %v", err)
+	}
+	for playerID, points := range map[string]int{alice: 1, bob: 3, carol: 2} {
+		if _, err := srv.doScoreAdd(ctx, roomID, ownerSub, playerID, points); err != nil {
+			t.Fatalf("score %s: %v", playerID, err)
+		}
 	}
-	rr := httptest.NewRecorder()
-	h.ServeHTTP(rr, req)
 
-	if rr.Code != http.StatusOK {
-		t.Fatalf("join room: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	// Bob is kicked and Carol's seat expires before the match ends.
+	if _, err := srv.doKick(ctx, roomID, ownerSub, bob); err != nil {
+		t.Fatalf("kick: %v", err)
 	}
+	if _, err := srv.nttRepo.LeaveRoom(ctx, roomID, carol); err != nil {
+		t.Fatalf("leave: %v", err)
+	}
+	if _, err := pool.Exec(ctx, `UPDATE room_players SET left_at = now() - interval '1 hour' WHERE id = $1::uuid;`, carol); err != nil {
+		t.Fatalf("age seat: %v", err)
+	}
+	srv.SetSeatGracePeriod(30 * time.Minute)
+	srv.expireSeats(ctx)
 
-	var res struct {
-		PlayerID string `json:"playerId"`
+	if err := srv.rooms.closeRoom(ctx, roomID, reasonOwnerLeftEmpty); err != nil {
+		t.Fatalf("close room: %v", err)
 	}
-	if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
-		t.Fatalf("join room: unmarshal: %v", err)
+
+	matches, err := srv.nttRepo.ListUserMatches(ctx, ownerSub)
+	if err != nil || len(matches) != 1 {
+		t.Fatalf("list matches: expected 1 match, got %d (%v)", len(matches), err)
 	}
-	if res.PlayerID == "" {
-		t.Fatalf("join room: missing playerId in response: %s", rr.Body.String())
+	want := []struct {
+		playerID    string
+		score, rank int
+	}{{bob, 3, 1}, {carol, 2, 2}, {alice, 1, 3}}
+	got := matches[0].Standings
+	if len(got) != len(want) {
+		t.Fatalf("expected %d standings, got %#v", len(want), got)
 	}
-	return res.PlayerID
+	for i, w := range want {
+		if got[i].PlayerID != w.playerID || got[i].Score != w.score || got[i].Rank != w.rank {
+			t.Fatalf("standing %d: expected %+v, got %#v", i, w, got[i])
+		}
+	}
+}
+
+func TestMatches_AccountDeletionScrubsHistory(t *testing.T) {
+	t.Setenv("BES_YOUTUBE_OEMBED_DISABLE", "1")
+
+	ctx := context.Background()
+	pool := freshDB(t, ctx)
+	srv := newTestServer(t, pool)
+	h := srv.Handler(Options{AllowedOrigins: []string{"http://localhost:5173"}})
+
+	ownerSub := "owner-sub"
+	playlistID := createPlaylistWithItems(t, h, ownerSub, "Hits", "https://www.youtube.com/watch?v=dQw4w9WgXcQ")
+	roomID := createRoom(t, h, ownerSub, "History Room")
+	playerID := joinRoom(t, h, roomID, "player-sub", `{"nickname":"Alice","pictureUrl":"https://example.com/alice.png"}`)
+
+	if _, err := srv.doLoadPlaylist(ctx, roomID, ownerSub, playlistID); err != nil {
+		t.Fatalf("load playlist: %v", err)
+	}
+	if err := srv.nttRepo.TogglePauseSafe(ctx, roomID, ownerSub, false); err != nil {
+		t.Fatalf("start playback: %v", err)
+	}
+	if err := srv.doBuzz(ctx, roomID, playerID, nil, nil); err != nil {
+		t.Fatalf("buzz: %v", err)
+	}
+	if err := srv.doBuzzResolve(ctx, roomID, ownerSub, playerID, true); err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if err := srv.rooms.closeRoom(ctx, roomID, reasonOwnerLeftEmpty); err != nil {
+		t.Fatalf("close room: %v", err)
+	}
+
+	deleteMe := func(sub string) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodDelete, "/api/me", nil)
+		req.Header.Set("X-User-Sub", sub)
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("delete %s: expected 200, got %d: %s", sub, rr.Code, rr.Body.String())
+		}
+	}
+	deleteMe("player-sub")
+
+	matches, err := srv.nttRepo.ListUserMatches(ctx, ownerSub)
+	if err != nil || len(matches) != 1 {
+		t.Fatalf("list matches: expected 1 match, got %d (%v)", len(matches), err)
+	}
+	match, err := srv.nttRepo.GetMatch(ctx, ownerSub, matches[0].ID)
+	if err != nil {
+		t.Fatalf("get match: %v", err)
+	}
+	if len(match.Standings) != 1 || len(match.Rounds) != 1 || len(match.Rounds[0].Buzzes) != 1 {
+		t.Fatalf("unexpected match: %#v", match)
+	}
+	if st := match.Standings[0]; st.Sub != "" || st.Nickname != "Deleted User" || st.PictureURL != "" || st.Score != 1 {
+		t.Fatalf("expected an anonymized standing that keeps its score, got %#v", st)
+	}
+	if b := match.Rounds[0].Buzzes[0]; b.Sub != "" || b.Nickname != "Deleted User" {
+		t.Fatalf("expected an anonymized buzz, got %#v", b)
+	}
+
+	// The host's account goes too: the match stays, without an owner.
+	deleteMe(ownerSub)
+	var ownerLeft *string
+	if err := pool.QueryRow(ctx, `SELECT owner_sub FROM matches WHERE id = $1::uuid;`, match.ID).Scan(&ownerLeft); err != nil {
+		t.Fatalf("load match owner: %v", err)
+	}
+	if ownerLeft != nil {
+		t.Fatalf("expected the match owner to be cleared, got %q", *ownerLeft)
+	}
+}
+
+func TestRules_PenaltyCooldownAndBuzzLimit(t *testing.T) {
+	t.Setenv("BES_YOUTUBE_OEMBED_DISABLE", "1")
+
+	ctx := context.Background()
+	pool := freshDB(t, ctx)
+	srv := newTestServer(t, pool)
+	h := srv.Handler(Options{AllowedOrigins: []string{"http://localhost:5173"}})
+
+	ownerSub := "owner-sub"
+	playlistID := createPlaylistWithItems(t, h, ownerSub, "Hits", "https://www.youtube.com/watch?v=dQw4w9WgXcQ")
+	roomID := createRoom(t, h, ownerSub, "Rules Room")
+	playerID := joinRoom(t, h, roomID, "player-sub", `{"nickname":"Alice"}`)
+
+	snap, err := srv.doRulesSet(ctx, roomID, ownerSub, json.RawMessage(`{"penaltyWrong":2,"cooldownMs":0,"maxBuzzesPerTrack":1}`))
+	if err != nil {
+		t.Fatalf("set rules: %v", err)
+	}
+	if snap.Rules.PenaltyWrong != 2 || snap.Rules.PointsCorrect != 1 || !snap.Rules.AutoAdvance {
+		t.Fatalf("expected partial update merged onto defaults, got %#v", snap.Rules)
+	}
+	if _, err := srv.doRulesSet(ctx, roomID, ownerSub, json.RawMessage(`{"pointsCorect":3}`)); err == nil {
+		t.Fatalf("expected unknown rule key to be rejected")
+	}
+
+	if _, err := srv.doLoadPlaylist(ctx, roomID, ownerSub, playlistID); err != nil {
+		t.Fatalf("load playlist: %v", err)
+	}
+	if err := srv.nttRepo.TogglePauseSafe(ctx, roomID, ownerSub, false); err != nil {
+		t.Fatalf("start playback: %v", err)
+	}
+	if err := srv.doBuzz(ctx, roomID, playerID, nil, nil); err != nil {
+		t.Fatalf("buzz: %v", err)
+	}
+	if err := srv.doBuzzResolve(ctx, roomID, ownerSub, playerID, false); err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+
+	snap, err = srv.loadRoomSnapshot(ctx, roomID)
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	if got := findPlayer(t, snap, playerID).Score; got != -2 {
+		t.Fatalf("expected penalty to apply, got score %d", got)
+	}
+	if active, err := srv.buzzCooldownActive(roomID, playerID, time.Now().UTC()); err != nil || active {
+		t.Fatalf("expected no cooldown with cooldownMs=0")
+	}
+	if err := srv.doBuzz(ctx, roomID, playerID, nil, nil); err == nil {
+		t.Fatalf("expected buzz limit to be enforced")
+	}
+}
+
+func TestPlaylists_ImportFromYouTubePlaylist(t *testing.T) {
+	t.Setenv("BES_YOUTUBE_OEMBED_DISABLE", "1")
+
+	ctx := context.Background()
+	pool := freshDB(t, ctx)
+	srv := newTestServer(t, pool)
+	srv.SetPlaylistSource(namethattune.StaticPlaylistSource{
+		"PLabcdef123": {
+			{VideoID: "fJ9rUzIMcZQ", Title: "Queen - Bohemian Rhapsody (Official Video)"},
+			{VideoID: "dQw4w9WgXcQ", Title: "Rick Astley - Never Gonna Give You Up"},
+			{VideoID: "aaaaaaaaaaa", Title: "Private video", Unavailable: true},
+			{VideoID: "cvChjHcABPA", Title: "ABBA - SOS"},
+			{VideoID: "fJ9rUzIMcZQ", Title: "Queen - Bohemian Rhapsody (Live)"},
+		},
+	})
+	h := srv.Handler(Options{AllowedOrigins: []string{"http://localhost:5173"}})
+
+	ownerSub := "owner-sub"
+	playlistID := createPlaylistWithItems(t, h, ownerSub, "Hits", "https://www.youtube.com/watch?v=dQw4w9WgXcQ")
+
+	req := httptest.NewRequest(http.MethodPost, "/api/games/name-that-tune/playlists/"+playlistID+"/import", strings.NewReader(`{"url":"https://www.youtube.com/playlist?list=PLabcdef123"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User-Sub", ownerSub)
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("import: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var res struct {
+		Added    []namethattune.PlaylistItem       `json:"added"`
+		Skipped  []namethattune.PlaylistImportSkip `json:"skipped"`
+		Playlist namethattune.Playlist             `json:"playlist"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+		t.Fatalf("import: unmarshal: %v", err)
+	}
+	if len(res.Added) != 2 || res.Added[0].YouTubeID != "fJ9rUzIMcZQ" || res.Added[1].YouTubeID != "cvChjHcABPA" {
+		t.Fatalf("expected Queen then ABBA to be added, got %+v", res.Added)
+	}
+	if res.Added[0].Artist != "Queen" || res.Added[0].SongTitle != "Bohemian Rhapsody" {
+		t.Fatalf("expected parsed metadata, got %+v", res.Added[0])
+	}
+	if len(res.Skipped) != 3 {
+		t.Fatalf("expected 3 skipped entries, got %+v", res.Skipped)
+	}
+	for i, reason := range []string{namethattune.ImportSkipDuplicate, namethattune.ImportSkipUnavailable, namethattune.ImportSkipDuplicate} {
+		if res.Skipped[i].Reason != reason {
+			t.Fatalf("skipped[%d]: expected reason %q, got %+v", i, reason, res.Skipped[i])
+		}
+	}
+	if len(res.Playlist.Items) != 3 || res.Playlist.Items[0].YouTubeID != "dQw4w9WgXcQ" || res.Playlist.Items[2].YouTubeID != "cvChjHcABPA" {
+		t.Fatalf("expected imported items appended in order, got %+v", res.Playlist.Items)
+	}
+
+	// Unknown remote playlist.
+	req = httptest.NewRequest(http.MethodPost, "/api/games/name-that-tune/playlists/"+playlistID+"/import", strings.NewReader(`{"url":"https://www.youtube.com/playlist?list=PLmissing000"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User-Sub", ownerSub)
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("import missing: expected 404, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestPlaylists_ExportAndImportFile(t *testing.T) {
+	t.Setenv("BES_YOUTUBE_OEMBED_DISABLE", "1")
+
+	ctx := context.Background()
+	pool := freshDB(t, ctx)
+	srv := newTestServer(t, pool)
+	h := srv.Handler(Options{AllowedOrigins: []string{"http://localhost:5173"}})
+
+	ownerSub := "owner-sub"
+	playlistID := createPlaylistWithItems(t, h, ownerSub, "Hits",
+		"https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+		"https://youtu.be/fJ9rUzIMcZQ",
+	)
+
+	for _, format := range []string{"json", "csv"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/games/name-that-tune/playlists/"+playlistID+"/export?format="+format, nil)
+		req.Header.Set("X-User-Sub", ownerSub)
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("export %s: expected 200, got %d: %s", format, rr.Code, rr.Body.String())
+		}
+
+		req = httptest.NewRequest(http.MethodPost, "/api/games/name-that-tune/playlists/import?format="+format+"&name=Copy", bytes.NewReader(rr.Body.Bytes()))
+		req.Header.Set("X-User-Sub", "other-host")
+		rr = httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("import %s: expected 201, got %d: %s", format, rr.Code, rr.Body.String())
+		}
+
+		var pl namethattune.Playlist
+		if err := json.Unmarshal(rr.Body.Bytes(), &pl); err != nil {
+			t.Fatalf("import %s: unmarshal: %v", format, err)
+		}
+		if pl.Name != "Copy" || pl.OwnerSub != "other-host" || len(pl.Items) != 2 || pl.Items[0].YouTubeID != "dQw4w9WgXcQ" || pl.Items[1].YouTubeID != "fJ9rUzIMcZQ" {
+			t.Fatalf("import %s: unexpected playlist %+v", format, pl)
+		}
+	}
+
+	// An invalid item rejects the whole file.
+	req := httptest.NewRequest(http.MethodPost, "/api/games/name-that-tune/playlists/import?format=csv", strings.NewReader("title,youtube_id\nok,dQw4w9WgXcQ\nbroken,!!\n"))
+	req.Header.Set("X-User-Sub", "other-host")
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("import invalid: expected 400, got %d: %s", rr.Code, rr.Body.String())
+	}
+	lists, err := srv.nttRepo.ListPlaylists(ctx, "other-host")
+	if err != nil {
+		t.Fatalf("list playlists: %v", err)
+	}
+	if len(lists) != 2 {
+		t.Fatalf("expected failed import to leave no playlist behind, got %d playlists", len(lists))
+	}
+}
+
+func TestPlaylists_SharingAndDiscover(t *testing.T) {
+	t.Setenv("BES_YOUTUBE_OEMBED_DISABLE", "1")
+
+	ctx := context.Background()
+	pool := freshDB(t, ctx)
+	srv := newTestServer(t, pool)
+	h := srv.Handler(Options{AllowedOrigins: []string{"http://localhost:5173"}})
+
+	do := func(method, path, sub, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if sub != "" {
+			req.Header.Set("X-User-Sub", sub)
+		}
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	ownerSub, friendSub, strangerSub := "owner-sub", "friend-sub", "stranger-sub"
+	playlistID := createPlaylistWithItems(t, h, ownerSub, "80s Hits", "https://www.youtube.com/watch?v=dQw4w9WgXcQ")
+	base := "/api/games/name-that-tune/playlists/" + playlistID
+	for _, sub := range []string{friendSub, strangerSub} {
+		if rr := do(http.MethodPut, "/api/me", sub, `{"nickname":"`+sub+`"}`); rr.Code != http.StatusOK {
+			t.Fatalf("put me %s: expected 200, got %d: %s", sub, rr.Code, rr.Body.String())
+		}
+	}
+
+	// Private playlists are invisible to others.
+	if rr := do(http.MethodGet, base, friendSub, ""); rr.Code != http.StatusNotFound {
+		t.Fatalf("get private playlist: expected 404, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	// An editor can edit items but not sharing settings.
+	if rr := do(http.MethodPut, base+"/collaborators/"+friendSub, ownerSub, `{"role":"editor"}`); rr.Code != http.StatusOK {
+		t.Fatalf("add collaborator: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do(http.MethodPost, base+"/items", friendSub, `{"youtubeUrl":"https://youtu.be/fJ9rUzIMcZQ"}`); rr.Code != http.StatusCreated {
+		t.Fatalf("editor add item: expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do(http.MethodPatch, base, friendSub, `{"visibility":"public"}`); rr.Code != http.StatusForbidden {
+		t.Fatalf("editor set visibility: expected 403, got %d: %s", rr.Code, rr.Body.String())
+	}
+	lists, err := srv.nttRepo.ListPlaylists(ctx, friendSub)
+	if err != nil {
+		t.Fatalf("list playlists: %v", err)
+	}
+	if len(lists) != 1 || lists[0].ID != playlistID || lists[0].Role != namethattune.PlaylistRoleEditor {
+		t.Fatalf("expected shared playlist in collaborator's list, got %+v", lists)
+	}
+
+	// A viewer can read and use the playlist, not edit it.
+	if rr := do(http.MethodPut, base+"/collaborators/"+friendSub, ownerSub, `{"role":"viewer"}`); rr.Code != http.StatusOK {
+		t.Fatalf("downgrade collaborator: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do(http.MethodPatch, base, friendSub, `{"name":"Mine now"}`); rr.Code != http.StatusForbidden {
+		t.Fatalf("viewer rename: expected 403, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do(http.MethodPost, "/api/games/name-that-tune/rooms", friendSub, `{"name":"Friend Room","playlistId":"`+playlistID+`"}`); rr.Code != http.StatusCreated {
+		t.Fatalf("viewer create room with shared playlist: expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	// Public playlists are discoverable and usable by anyone.
+	if rr := do(http.MethodPost, "/api/games/name-that-tune/rooms", strangerSub, `{"name":"Stranger Room","playlistId":"`+playlistID+`"}`); rr.Code != http.StatusNotFound {
+		t.Fatalf("stranger create room with private playlist: expected 404, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := do(http.MethodPatch, base, ownerSub, `{"visibility":"public"}`); rr.Code != http.StatusOK {
+		t.Fatalf("set visibility: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	rr := do(http.MethodGet, "/api/games/name-that-tune/playlists/discover?q=80s", "", "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("discover: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var discovered struct {
+		Playlists []namethattune.PublicPlaylist `json:"playlists"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &discovered); err != nil {
+		t.Fatalf("discover: unmarshal: %v", err)
+	}
+	if len(discovered.Playlists) != 1 || discovered.Playlists[0].ID != playlistID || discovered.Playlists[0].ItemCount != 2 {
+		t.Fatalf("expected public playlist to be discoverable, got %+v", discovered.Playlists)
+	}
+	roomID := createRoom(t, h, strangerSub, "Stranger Room")
+	if _, err := srv.doLoadPlaylist(ctx, roomID, strangerSub, playlistID); err != nil {
+		t.Fatalf("stranger load public playlist: %v", err)
+	}
+	if rr := do(http.MethodPost, base+"/items", strangerSub, `{"youtubeUrl":"https://youtu.be/cvChjHcABPA"}`); rr.Code != http.StatusForbidden {
+		t.Fatalf("stranger add item: expected 403, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestPlaylists_ReorderAndBulkOperations(t *testing.T) {
+	t.Setenv("BES_YOUTUBE_OEMBED_DISABLE", "1")
+
+	ctx := context.Background()
+	pool := freshDB(t, ctx)
+	srv := newTestServer(t, pool)
+	h := srv.Handler(Options{AllowedOrigins: []string{"http://localhost:5173"}})
+
+	ownerSub := "owner-sub"
+	playlistID := createPlaylistWithItems(t, h, ownerSub, "Hits",
+		"https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+		"https://youtu.be/fJ9rUzIMcZQ",
+		"https://youtu.be/cvChjHcABPA",
+		"https://youtu.be/kJQP7kiw5Fk",
+	)
+	otherID := createPlaylistWithItems(t, h, ownerSub, "Other", "https://youtu.be/9bZkp7q19f0")
+	base := "/api/games/name-that-tune/playlists/" + playlistID
+
+	do := func(method, path, body string, wantStatus int) namethattune.Playlist {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User-Sub", ownerSub)
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		if rr.Code != wantStatus {
+			t.Fatalf("%s %s: expected %d, got %d: %s", method, path, wantStatus, rr.Code, rr.Body.String())
+		}
+		var pl namethattune.Playlist
+		_ = json.Unmarshal(rr.Body.Bytes(), &pl)
+		return pl
+	}
+	ids := func(pl namethattune.Playlist) []string {
+		out := make([]string, 0, len(pl.Items))
+		for _, it := range pl.Items {
+			out = append(out, it.ID)
+		}
+		return out
+	}
+	idList := func(ids ...string) string {
+		b, _ := json.Marshal(map[string]any{"itemIds": ids})
+		return string(b)
+	}
+
+	pl, err := srv.nttRepo.GetPlaylist(ctx, ownerSub, playlistID)
+	if err != nil {
+		t.Fatalf("get playlist: %v", err)
+	}
+	a, b, c, d := pl.Items[0].ID, pl.Items[1].ID, pl.Items[2].ID, pl.Items[3].ID
+
+	// Reorder keeps item IDs and metadata.
+	pl = do(http.MethodPut, base+"/items/order", idList(d, c, b, a), http.StatusOK)
+	if got := ids(pl); !reflect.DeepEqual(got, []string{d, c, b, a}) {
+		t.Fatalf("reorder: unexpected order %v", got)
+	}
+	do(http.MethodPut, base+"/items/order", idList(d, c, b), http.StatusBadRequest)
+	do(http.MethodPut, base+"/items/order", idList(d, c, b, b), http.StatusBadRequest)
+
+	// Bulk delete compacts positions: a following reorder must still see every item.
+	pl = do(http.MethodPost, base+"/items/delete", idList(c), http.StatusOK)
+	if got := ids(pl); !reflect.DeepEqual(got, []string{d, b, a}) {
+		t.Fatalf("bulk delete: unexpected order %v", got)
+	}
+
+	// Move appends to the target, keeping the source order.
+	req := httptest.NewRequest(http.MethodPost, base+"/items/move", strings.NewReader(`{"itemIds":["`+a+`","`+d+`"],"targetPlaylistId":"`+otherID+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User-Sub", ownerSub)
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("move: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var moved struct {
+		Playlist namethattune.Playlist `json:"playlist"`
+		Target   namethattune.Playlist `json:"target"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &moved); err != nil {
+		t.Fatalf("move: unmarshal: %v", err)
+	}
+	if got := ids(moved.Playlist); !reflect.DeepEqual(got, []string{b}) {
+		t.Fatalf("move: unexpected source %v", got)
+	}
+	if got := ids(moved.Target); len(got) != 3 || got[1] != d || got[2] != a {
+		t.Fatalf("move: unexpected target %v", got)
+	}
+
+	// Duplicate copies items (with new IDs) into a new playlist.
+	dup := do(http.MethodPost, "/api/games/name-that-tune/playlists/"+otherID+"/duplicate", "", http.StatusCreated)
+	if dup.Name != "Other (copy)" || len(dup.Items) != 3 || dup.Items[1].YouTubeID != moved.Target.Items[1].YouTubeID || dup.Items[1].ID == d {
+		t.Fatalf("duplicate: unexpected playlist %+v", dup)
+	}
+
+	// Shuffle persists a permutation.
+	pl = do(http.MethodPost, "/api/games/name-that-tune/playlists/"+otherID+"/shuffle", "", http.StatusOK)
+	got := ids(pl)
+	sort.Strings(got)
+	want := ids(moved.Target)
+	sort.Strings(want)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("shuffle: expected a permutation of %v, got %v", want, got)
+	}
+}
+
+func TestPlaylists_TrashRestoreAndPurge(t *testing.T) {
+	t.Setenv("BES_YOUTUBE_OEMBED_DISABLE", "1")
+
+	ctx := context.Background()
+	pool := freshDB(t, ctx)
+	srv := newTestServer(t, pool)
+	h := srv.Handler(Options{AllowedOrigins: []string{"http://localhost:5173"}})
+
+	ownerSub := "owner-sub"
+	roomID := createRoom(t, h, ownerSub, "Room")
+	playlistID := createPlaylistWithItems(t, h, ownerSub, "Hits", "https://www.youtube.com/watch?v=dQw4w9WgXcQ")
+	base := "/api/games/name-that-tune/playlists/"
+
+	if _, err := srv.doLoadPlaylist(ctx, roomID, ownerSub, playlistID); err != nil {
+		t.Fatalf("load playlist: %v", err)
+	}
+
+	do := func(method, path, sub string, wantStatus int) []byte {
+		t.Helper()
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("X-User-Sub", sub)
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		if rr.Code != wantStatus {
+			t.Fatalf("%s %s: expected %d, got %d: %s", method, path, wantStatus, rr.Code, rr.Body.String())
+		}
+		return rr.Body.Bytes()
+	}
+
+	// Only the owner can delete.
+	do(http.MethodDelete, base+playlistID, "someone-else", http.StatusNotFound)
+	do(http.MethodDelete, base+playlistID, ownerSub, http.StatusOK)
+
+	// The room no longer has it loaded, and it is hidden everywhere but the trash.
+	snap, err := srv.loadRoomSnapshot(ctx, roomID)
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	if snap.Playlist != nil || snap.Playback.PlaylistID != "" {
+		t.Fatalf("expected room to be unloaded, got playlist=%v playback=%q", snap.Playlist, snap.Playback.PlaylistID)
+	}
+	do(http.MethodGet, base+playlistID, ownerSub, http.StatusNotFound)
+
+	var trash struct {
+		Playlists []namethattune.TrashedPlaylist `json:"playlists"`
+	}
+	if err := json.Unmarshal(do(http.MethodGet, base+"trash", ownerSub, http.StatusOK), &trash); err != nil {
+		t.Fatalf("trash: unmarshal: %v", err)
+	}
+	if len(trash.Playlists) != 1 || trash.Playlists[0].ID != playlistID || trash.Playlists[0].ItemCount != 1 {
+		t.Fatalf("trash: unexpected listing %+v", trash.Playlists)
+	}
+
+	// Restore brings it back with its items.
+	var restored namethattune.Playlist
+	if err := json.Unmarshal(do(http.MethodPost, base+playlistID+"/restore", ownerSub, http.StatusOK), &restored); err != nil {
+		t.Fatalf("restore: unmarshal: %v", err)
+	}
+	if restored.ID != playlistID || len(restored.Items) != 1 {
+		t.Fatalf("restore: unexpected playlist %+v", restored)
+	}
+	do(http.MethodPost, base+playlistID+"/restore", ownerSub, http.StatusNotFound)
+
+	// Past the retention window, the purge removes it for good.
+	do(http.MethodDelete, base+playlistID, ownerSub, http.StatusOK)
+	n, err := srv.nttRepo.PurgeDeletedPlaylists(ctx, 0)
+	if err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("purge: expected 1 playlist removed, got %d", n)
+	}
+	do(http.MethodPost, base+playlistID+"/restore", ownerSub, http.StatusNotFound)
+}
+
+func TestPlayback_ClipWindow(t *testing.T) {
+	t.Setenv("BES_YOUTUBE_OEMBED_DISABLE", "1")
+
+	ctx := context.Background()
+	pool := freshDB(t, ctx)
+	srv := newTestServer(t, pool)
+	h := srv.Handler(Options{AllowedOrigins: []string{"http://localhost:5173"}})
+
+	ownerSub := "owner-sub"
+	roomID := createRoom(t, h, ownerSub, "Room")
+	playlistID := createPlaylistWithItems(t, h, ownerSub, "Hits",
+		"https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+		"https://youtu.be/fJ9rUzIMcZQ",
+	)
+
+	pl, err := srv.nttRepo.GetPlaylist(ctx, ownerSub, playlistID)
+	if err != nil {
+		t.Fatalf("get playlist: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPatch, "/api/games/name-that-tune/playlists/"+playlistID+"/items/"+pl.Items[0].ID, strings.NewReader(`{"startMs":30000,"clipDurationMs":300}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User-Sub", ownerSub)
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("patch item: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	if _, err := srv.doLoadPlaylist(ctx, roomID, ownerSub, playlistID); err != nil {
+		t.Fatalf("load playlist: %v", err)
+	}
+
+	// Playback begins at the clip start, even when asked to start at 0.
+	playing := false
+	position := 0
+	snap, err := srv.doPlaybackSet(ctx, roomID, ownerSub, 0, &playing, &position)
+	if err != nil {
+		t.Fatalf("playback set: %v", err)
+	}
+	if snap.Playback.PositionMS != 30000 {
+		t.Fatalf("expected playback to start at the clip start, got %d", snap.Playback.PositionMS)
+	}
+
+	// Once the clip has played, the server advances to the next track (auto-advance).
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		snap, err = srv.loadRoomSnapshot(ctx, roomID)
+		if err != nil {
+			t.Fatalf("snapshot: %v", err)
+		}
+		if snap.Playback.TrackIndex == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected clip end to advance, playback=%+v", snap.Playback)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if !snap.Playback.Paused || snap.Playback.PositionMS != 0 {
+		t.Fatalf("expected next track paused at its start, got %+v", snap.Playback)
+	}
+}
+
+func TestRules_RoundTimeoutAdvances(t *testing.T) {
+	t.Setenv("BES_YOUTUBE_OEMBED_DISABLE", "1")
+
+	ctx := context.Background()
+	pool := freshDB(t, ctx)
+	srv := newTestServer(t, pool)
+	h := srv.Handler(Options{AllowedOrigins: []string{"http://localhost:5173"}})
+
+	ownerSub := "owner-sub"
+	roomID := createRoom(t, h, ownerSub, "Timed Room")
+	playlistID := createPlaylistWithItems(t, h, ownerSub, "Hits",
+		"https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+		"https://youtu.be/fJ9rUzIMcZQ",
+	)
+	if _, err := srv.doLoadPlaylist(ctx, roomID, ownerSub, playlistID); err != nil {
+		t.Fatalf("load playlist: %v", err)
+	}
+	if _, err := srv.doRulesSet(ctx, roomID, ownerSub, json.RawMessage(`{"roundDurationMs":300}`)); err != nil {
+		t.Fatalf("set rules: %v", err)
+	}
+	if _, err := srv.doRulesSet(ctx, roomID, ownerSub, json.RawMessage(`{"roundDurationMs":-1}`)); err == nil {
+		t.Fatalf("expected negative round duration to be rejected")
+	}
+
+	// Paused: the round timer does not run.
+	paused := true
+	if _, err := srv.doPlaybackSet(ctx, roomID, ownerSub, 0, &paused, nil); err != nil {
+		t.Fatalf("playback set: %v", err)
+	}
+	if srv.timers.pending(roomID, roundTimer) {
+		t.Fatalf("expected no round timer while paused")
+	}
+
+	playing := false
+	snap, err := srv.doPlaybackSet(ctx, roomID, ownerSub, 0, &playing, nil)
+	if err != nil {
+		t.Fatalf("playback set: %v", err)
+	}
+	if !srv.timers.pending(roomID, roundTimer) {
+		t.Fatalf("expected the round timer to be armed, playback=%+v", snap.Playback)
+	}
+
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		snap, err = srv.loadRoomSnapshot(ctx, roomID)
+		if err != nil {
+			t.Fatalf("snapshot: %v", err)
+		}
+		if snap.Playback.TrackIndex == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected round timeout to advance, playback=%+v", snap.Playback)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if !snap.Playback.Paused || snap.Playback.RoundEndsAt != nil {
+		t.Fatalf("expected next track paused without a running round, got %+v", snap.Playback)
+	}
+
+	srv.clearRoomState(roomID)
+	if srv.timers.pending(roomID, roundTimer) || srv.timers.pending(roomID, clipTimer) {
+		t.Fatalf("expected clearRoomState to cancel room timers")
+	}
+}
+
+func TestRound_RevealRedactsUntilRevealed(t *testing.T) {
+	t.Setenv("BES_YOUTUBE_OEMBED_DISABLE", "1")
+
+	ctx := context.Background()
+	pool := freshDB(t, ctx)
+	srv := newTestServer(t, pool)
+	h := srv.Handler(Options{AllowedOrigins: []string{"http://localhost:5173"}})
+
+	ownerSub := "owner-sub"
+	roomID := createRoom(t, h, ownerSub, "Reveal Room")
+	playlistID := createPlaylistWithItems(t, h, ownerSub, "Hits", "https://www.youtube.com/watch?v=dQw4w9WgXcQ")
+	joinRoom(t, h, roomID, "player-sub", `{"nickname":"Alice"}`)
+	if _, err := srv.doLoadPlaylist(ctx, roomID, ownerSub, playlistID); err != nil {
+		t.Fatalf("load playlist: %v", err)
+	}
+
+	getRoom := func(sub string) namethattune.RoomSnapshot {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/games/name-that-tune/rooms/"+roomID, nil)
+		if sub != "" {
+			req.Header.Set("X-User-Sub", sub)
+		}
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("get room: expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var snap namethattune.RoomSnapshot
+		if err := json.Unmarshal(rr.Body.Bytes(), &snap); err != nil {
+			t.Fatalf("get room: unmarshal: %v", err)
+		}
+		return snap
+	}
+
+	owner := getRoom(ownerSub)
+	if owner.Playback.Phase != namethattune.RoundPhaseWaiting || owner.Playback.Track == nil || owner.Playback.Track.Title == "" {
+		t.Fatalf("expected owner to see the waiting track, got %+v", owner.Playback)
+	}
+	title := owner.Playback.Track.Title
+	for _, sub := range []string{"", "player-sub"} {
+		snap := getRoom(sub)
+		if snap.Playback.Track == nil || snap.Playback.Track.Title != "" || snap.Playback.Track.YouTubeURL != "" || snap.Playlist.Items[0].Title != "" {
+			t.Fatalf("expected %q to see a redacted track, got %+v", sub, snap.Playback.Track)
+		}
+		if snap.Playback.Track.YouTubeID != "dQw4w9WgXcQ" {
+			t.Fatalf("expected the video ID to stay playable, got %+v", snap.Playback.Track)
+		}
+	}
+
+	if _, err := srv.doRoundReveal(ctx, roomID, "player-sub"); err == nil {
+		t.Fatalf("expected non-owner reveal to be rejected")
+	}
+	snap, err := srv.doRoundReveal(ctx, roomID, ownerSub)
+	if err != nil {
+		t.Fatalf("reveal: %v", err)
+	}
+	if snap.Playback.Phase != namethattune.RoundPhaseRevealed {
+		t.Fatalf("expected revealed phase, got %q", snap.Playback.Phase)
+	}
+	if got := getRoom("player-sub"); got.Playback.Track == nil || got.Playback.Track.Title != title {
+		t.Fatalf("expected players to see the revealed track, got %+v", got.Playback.Track)
+	}
+
+	if got := getRoom("player-sub"); len(got.Players) != 1 || got.Players[0].Sub != "player-sub" {
+		t.Fatalf("expected the player to see their own sub, got %+v", got.Players)
+	}
+	if got := getRoom(""); got.OwnerSub != "" || len(got.Players) != 1 || got.Players[0].Sub != "" {
+		t.Fatalf("expected spectators to see no sub, got ownerSub=%q players=%+v", got.OwnerSub, got.Players)
+	}
+}
+
+func TestRooms_SpectateDoesNotTakeASeat(t *testing.T) {
+	t.Setenv("BES_YOUTUBE_OEMBED_DISABLE", "1")
+
+	ctx := context.Background()
+	pool := freshDB(t, ctx)
+	srv := newTestServer(t, pool)
+	h := srv.Handler(Options{AllowedOrigins: []string{"http://localhost:5173"}})
+
+	ownerSub := "owner-sub"
+	roomID, err := srv.nttRepo.CreateRoom(ctx, ownerSub, "TV Room", "", "public", "secret", namethattune.RoomRules{})
+	if err != nil {
+		t.Fatalf("create room: %v", err)
+	}
+	joinRoom(t, h, roomID, "player-sub", `{"nickname":"Alice","password":"secret"}`)
+
+	spectate := func(body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/games/name-that-tune/rooms/"+roomID+"/spectate", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := spectate(`{"password":"wrong"}`); rr.Code != http.StatusUnauthorized {
+		t.Fatalf("spectate with wrong password: expected 401, got %d: %s", rr.Code, rr.Body.String())
+	}
+	rr := spectate(`{"password":"secret"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("spectate: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var res struct {
+		SpectatorToken string                    `json:"spectatorToken"`
+		Snapshot       namethattune.RoomSnapshot `json:"snapshot"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+		t.Fatalf("spectate: unmarshal: %v", err)
+	}
+	if len(res.Snapshot.Players) != 1 || res.Snapshot.Players[0].Sub != "" || res.Snapshot.OwnerSub != "" {
+		t.Fatalf("expected the spectator view of a one-player roster, got %+v", res.Snapshot)
+	}
+	if res.SpectatorToken == "" {
+		t.Fatalf("expected a spectator token")
+	}
+
+	// The room WS only lets spectators of this password-protected room in with the token.
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http") + "/api/games/name-that-tune/rooms/" + roomID + "/ws"
+	firstEvent := func(query string) (string, error) {
+		t.Helper()
+		dialCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+		defer cancel()
+		c, _, err := websocket.Dial(dialCtx, wsURL+query, nil)
+		if err != nil {
+			t.Fatalf("ws dial: %v", err)
+		}
+		defer func() { _ = c.Close(websocket.StatusNormalClosure, "bye") }()
+		_, data, err := c.Read(dialCtx)
+		if err != nil {
+			return "", err
+		}
+		var ev struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(data, &ev); err != nil {
+			t.Fatalf("ws frame json: %v", err)
+		}
+		return ev.Type, nil
+	}
+	for _, query := range []string{"?spectate=1", "?spectate=1&spectatorToken=nope", ""} {
+		if _, err := firstEvent(query); websocket.CloseStatus(err) != websocket.StatusPolicyViolation {
+			t.Fatalf("ws %q: expected the connection to be refused, got %v", query, err)
+		}
+	}
+	for _, query := range []string{"?spectate=1&spectatorToken=" + res.SpectatorToken, "?spectatorToken=" + res.SpectatorToken} {
+		if typ, err := firstEvent(query); err != nil || typ != "room.snapshot" {
+			t.Fatalf("ws %q: expected a snapshot, got %q (%v)", query, typ, err)
+		}
+	}
+
+	snap, err := srv.loadRoomSnapshot(ctx, roomID)
+	if err != nil {
+		t.Fatalf("load snapshot: %v", err)
+	}
+	if len(snap.Players) != 1 {
+		t.Fatalf("expected spectating not to add a player, got %d players", len(snap.Players))
+	}
+
+	// Only the spectator join counts: not a connection that has not identified itself yet.
+	_, cancel := srv.rt.Room(roomID).Subscribe(4, realtime.Viewer{Role: realtime.RoleSpectator, Audience: true})
+	defer cancel()
+	_, cancelAnon := srv.rt.Room(roomID).Subscribe(4, realtime.Viewer{Role: realtime.RoleSpectator})
+	defer cancelAnon()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/games/name-that-tune/rooms", nil)
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	var list struct {
+		Rooms []struct {
+			RoomID        string `json:"roomId"`
+			OnlinePlayers int    `json:"onlinePlayers"`
+			Spectators    int    `json:"spectators"`
+		} `json:"rooms"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &list); err != nil {
+		t.Fatalf("list rooms: unmarshal: %v", err)
+	}
+	found := false
+	for _, ri := range list.Rooms {
+		if ri.RoomID != roomID {
+			continue
+		}
+		found = true
+		if ri.OnlinePlayers != 1 || ri.Spectators != 1 {
+			t.Fatalf("expected 1 online player and 1 spectator, got %+v", ri)
+		}
+	}
+	if !found {
+		t.Fatalf("room %q not found in list", roomID)
+	}
+}
+
+func TestRooms_HostDisplayStream(t *testing.T) {
+	t.Setenv("BES_YOUTUBE_OEMBED_DISABLE", "1")
+
+	ctx := context.Background()
+	pool := freshDB(t, ctx)
+	srv := newTestServer(t, pool)
+	h := srv.Handler(Options{AllowedOrigins: []string{"http://localhost:5173"}})
+
+	ownerSub := "owner-sub"
+	roomID := createRoom(t, h, ownerSub, "Venue Night")
+
+	req := httptest.NewRequest(http.MethodPost, "/api/games/name-that-tune/rooms/"+roomID+"/join", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User-Sub", ownerSub)
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("owner join: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var joined struct {
+		DisplayToken string `json:"displayToken"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &joined); err != nil || joined.DisplayToken == "" {
+		t.Fatalf("expected a display token for the owner, got %s", rr.Body.String())
+	}
+
+	displayURL := "/api/games/name-that-tune/rooms/" + roomID + "/display"
+	req = httptest.NewRequest(http.MethodGet, displayURL+"?displayToken=nope", nil)
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("display with a bad token: expected 401, got %d", rr.Code)
+	}
+
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+	streamCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	sreq, err := http.NewRequestWithContext(streamCtx, http.MethodGet, ts.URL+displayURL+"?displayToken="+joined.DisplayToken, nil)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	res, err := http.DefaultClient.Do(sreq)
+	if err != nil {
+		t.Fatalf("open display stream: %v", err)
+	}
+	defer res.Body.Close()
+	if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected an event stream, got %q", ct)
+	}
+
+	sc := bufio.NewScanner(res.Body)
+	var types []string
+	for len(types) < 2 && sc.Scan() {
+		if typ, ok := strings.CutPrefix(sc.Text(), "event: "); ok {
+			types = append(types, typ)
+		}
+	}
+	if len(types) != 2 || types[0] != "display.round" || types[1] != "display.leaderboard" {
+		t.Fatalf("expected the initial round and leaderboard, got %v", types)
+	}
+}
+
+func TestTeams_ScoresAndBuzzLock(t *testing.T) {
+	t.Setenv("BES_YOUTUBE_OEMBED_DISABLE", "1")
+
+	ctx := context.Background()
+	pool := freshDB(t, ctx)
+	srv := newTestServer(t, pool)
+	h := srv.Handler(Options{AllowedOrigins: []string{"http://localhost:5173"}})
+
+	ownerSub := "owner-sub"
+	playlistID := createPlaylistWithItems(t, h, ownerSub, "Hits", "https://www.youtube.com/watch?v=dQw4w9WgXcQ")
+	roomID := createRoom(t, h, ownerSub, "Pub Quiz")
+	alice := joinRoom(t, h, roomID, "alice-sub", `{"nickname":"Alice"}`)
+	bob := joinRoom(t, h, roomID, "bob-sub", `{"nickname":"Bob"}`)
+	carol := joinRoom(t, h, roomID, "carol-sub", `{"nickname":"Carol"}`)
+
+	if _, err := srv.doTeamCreate(ctx, roomID, "player-sub", "Red"); err == nil {
+		t.Fatalf("expected non-owner team creation to be rejected")
+	}
+	snap, err := srv.doTeamCreate(ctx, roomID, ownerSub, "Red")
+	if err != nil {
+		t.Fatalf("create team: %v", err)
+	}
+	if _, err := srv.doTeamCreate(ctx, roomID, ownerSub, "Red"); err == nil {
+		t.Fatalf("expected duplicate team name to be rejected")
+	}
+	if len(snap.Teams) != 1 {
+		t.Fatalf("expected one team, got %+v", snap.Teams)
+	}
+	red := snap.Teams[0].TeamID
+	for _, playerID := range []string{alice, bob} {
+		if _, err := srv.doTeamAssign(ctx, roomID, ownerSub, playerID, red); err != nil {
+			t.Fatalf("assign team: %v", err)
+		}
+	}
+	if _, err := srv.doScoreAdd(ctx, roomID, ownerSub, alice, 2); err != nil {
+		t.Fatalf("add score: %v", err)
+	}
+	if _, err := srv.doScoreAdd(ctx, roomID, ownerSub, bob, 3); err != nil {
+		t.Fatalf("add score: %v", err)
+	}
+	snap, err = srv.doScoreAdd(ctx, roomID, ownerSub, carol, 4)
+	if err != nil {
+		t.Fatalf("add score: %v", err)
+	}
+	if len(snap.Teams) != 1 || snap.Teams[0].Score != 5 || len(snap.Teams[0].PlayerIDs) != 2 {
+		t.Fatalf("expected Red to total 5 points over 2 players, got %+v", snap.Teams)
+	}
+
+	if _, err := srv.doRulesSet(ctx, roomID, ownerSub, json.RawMessage(`{"teamBuzzLock":true,"cooldownMs":10000}`)); err != nil {
+		t.Fatalf("set rules: %v", err)
+	}
+	if _, err := srv.doLoadPlaylist(ctx, roomID, ownerSub, playlistID); err != nil {
+		t.Fatalf("load playlist: %v", err)
+	}
+	if err := srv.nttRepo.TogglePauseSafe(ctx, roomID, ownerSub, false); err != nil {
+		t.Fatalf("start playback: %v", err)
+	}
+	if err := srv.doBuzz(ctx, roomID, alice, nil, nil); err != nil {
+		t.Fatalf("buzz: %v", err)
+	}
+	if err := srv.doBuzzResolve(ctx, roomID, ownerSub, alice, false); err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if active, err := srv.buzzCooldownActive(roomID, bob, time.Now().UTC()); err != nil || !active {
+		t.Fatalf("expected the wrong answer to lock out Alice's teammate")
+	}
+	if active, err := srv.buzzCooldownActive(roomID, carol, time.Now().UTC()); err != nil || active {
+		t.Fatalf("expected players outside the team to keep buzzing")
+	}
+	if err := srv.clearBuzzCooldown(roomID, bob); err != nil {
+		t.Fatalf("clear cooldown: %v", err)
+	}
+	if err := srv.doBuzz(ctx, roomID, bob, nil, nil); err == nil {
+		t.Fatalf("expected a second buzz from the team to be rejected")
+	}
+	if err := srv.doBuzz(ctx, roomID, carol, nil, nil); err != nil {
+		t.Fatalf("buzz from a teamless player: %v", err)
+	}
+
+	if _, err := srv.doTeamDelete(ctx, roomID, ownerSub, red); err != nil {
+		t.Fatalf("delete team: %v", err)
+	}
+	snap, err = srv.loadRoomSnapshot(ctx, roomID)
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	if len(snap.Teams) != 0 || findPlayer(t, snap, alice).TeamID != "" || findPlayer(t, snap, alice).Score != 2 {
+		t.Fatalf("expected deleting the team to keep players and scores, got %+v", snap.Players)
+	}
+}
+
+func TestRooms_ResumeTokenReclaimsSeat(t *testing.T) {
+	t.Setenv("BES_YOUTUBE_OEMBED_DISABLE", "1")
+
+	ctx := context.Background()
+	pool := freshDB(t, ctx)
+	srv := newTestServer(t, pool)
+	srv.SetResumeSecret("test-secret")
+	h := srv.Handler(Options{AllowedOrigins: []string{"http://localhost:5173"}})
+
+	ownerSub := "owner-sub"
+	roomID, err := srv.nttRepo.CreateRoom(ctx, ownerSub, "Resume Room", "", "public", "secret", namethattune.RoomRules{})
+	if err != nil {
+		t.Fatalf("create room: %v", err)
+	}
+
+	type joinResponse struct {
+		PlayerID    string `json:"playerId"`
+		ResumeToken string `json:"resumeToken"`
+		Resumed     bool   `json:"resumed"`
+	}
+	join := func(body string) joinResponse {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/games/name-that-tune/rooms/"+roomID+"/join", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("join: expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var res joinResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+			t.Fatalf("join: unmarshal: %v", err)
+		}
+		return res
+	}
+	leave := func(playerID string) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/games/name-that-tune/rooms/"+roomID+"/leave", strings.NewReader(`{"playerId":"`+playerID+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("leave: expected 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+	}
+
+	guest := join(`{"nickname":"Guest","password":"secret"}`)
+	if guest.ResumeToken == "" || guest.Resumed {
+		t.Fatalf("expected a fresh seat with a resume token, got %+v", guest)
+	}
+	if _, err := srv.doScoreAdd(ctx, roomID, ownerSub, guest.PlayerID, 7); err != nil {
+		t.Fatalf("add score: %v", err)
+	}
+	leave(guest.PlayerID)
+
+	// A new anonymous client (no sub, no password) presents the token after a refresh.
+	back := join(`{"resumeToken":"` + guest.ResumeToken + `"}`)
+	if !back.Resumed || back.PlayerID != guest.PlayerID || back.ResumeToken != guest.ResumeToken {
+		t.Fatalf("expected the seat to be reclaimed, got %+v", back)
+	}
+	snap, err := srv.loadRoomSnapshot(ctx, roomID)
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	if p := findPlayer(t, snap, guest.PlayerID); p.Score != 7 || p.Nickname != "Guest" || !p.Connected {
+		t.Fatalf("expected seat, score and nickname to be kept, got %+v", p)
+	}
+
+	// The seat is in use again: the same token cannot take it from its player.
+	if other := join(`{"resumeToken":"` + guest.ResumeToken + `","password":"secret"}`); other.Resumed || other.PlayerID == guest.PlayerID {
+		t.Fatalf("expected a claim on a connected seat to get a fresh seat, got %+v", other)
+	}
+
+	last := "A"
+	if strings.HasSuffix(guest.ResumeToken, last) {
+		last = "B"
+	}
+	tampered := guest.ResumeToken[:len(guest.ResumeToken)-1] + last
+	if other := join(`{"resumeToken":"` + tampered + `","password":"secret"}`); other.Resumed || other.PlayerID == guest.PlayerID {
+		t.Fatalf("expected a tampered token to get a fresh seat, got %+v", other)
+	}
+
+	// Past the grace period, the seat is gone and the token no longer resumes.
+	leave(guest.PlayerID)
+	if _, err := pool.Exec(ctx, `UPDATE room_players SET left_at = now() - interval '1 hour' WHERE id::uuid = $1;`, guest.PlayerID); err != nil {
+		t.Fatalf("age seat: %v", err)
+	}
+	srv.SetSeatGracePeriod(30 * time.Minute)
+	srv.expireSeats(ctx)
+	snap, err = srv.loadRoomSnapshot(ctx, roomID)
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	for _, p := range snap.Players {
+		if p.PlayerID == guest.PlayerID {
+			t.Fatalf("expected the expired seat to be removed")
+		}
+	}
+	if again := join(`{"resumeToken":"` + guest.ResumeToken + `","password":"secret"}`); again.Resumed {
+		t.Fatalf("expected an expired seat not to be resumable, got %+v", again)
+	}
+}
+
+func TestRooms_PresenceFollowsWebSocket(t *testing.T) {
+	t.Setenv("BES_YOUTUBE_OEMBED_DISABLE", "1")
+
+	ctx := context.Background()
+	pool := freshDB(t, ctx)
+	srv := newTestServer(t, pool)
+	srv.SetPresenceTimeout(50 * time.Millisecond)
+	h := srv.Handler(Options{AllowedOrigins: []string{"http://localhost:5173"}})
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+
+	ownerSub := "owner-sub"
+	roomID := createRoom(t, h, ownerSub, "Presence Room")
+	joinRoom(t, h, roomID, ownerSub, `{"nickname":"Owner"}`)
+	playerID := joinRoom(t, h, roomID, "player-sub", `{"nickname":"Alice"}`)
+	playerToken, err := srv.getOrCreatePlayerToken(roomID, playerID)
+	if err != nil {
+		t.Fatalf("player token: %v", err)
+	}
+	ownerToken, err := srv.getOrCreateOwnerToken(roomID)
+	if err != nil {
+		t.Fatalf("owner token: %v", err)
+	}
+
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http") + "/api/games/name-that-tune/rooms/" + roomID + "/ws"
+	dial := func(query string) *websocket.Conn {
+		t.Helper()
+		dialCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+		defer cancel()
+		c, _, err := websocket.Dial(dialCtx, wsURL+"?"+query, nil)
+		if err != nil {
+			t.Fatalf("ws dial: %v", err)
+		}
+		return c
+	}
+	owner := dial("ownerToken=" + ownerToken)
+	defer func() { _ = owner.Close(websocket.StatusNormalClosure, "bye") }()
+
+	// waitPresence waits for the player.presence event, then for the snapshot that follows
+	// it, which must agree with it.
+	waitPresence := func(connected bool) {
+		t.Helper()
+		readCtx, cancel := context.WithTimeout(ctx, 3*time.Second)
+		defer cancel()
+		seen := false
+		for {
+			_, data, err := owner.Read(readCtx)
+			if err != nil {
+				t.Fatalf("waiting for player.presence connected=%v (seen=%v): %v", connected, seen, err)
+			}
+			var ev struct {
+				Type    string          `json:"type"`
+				Payload json.RawMessage `json:"payload"`
+			}
+			if err := json.Unmarshal(data, &ev); err != nil {
+				t.Fatalf("ws frame json: %v", err)
+			}
+			switch {
+			case ev.Type == "player.presence":
+				var presence struct {
+					PlayerID  string `json:"playerId"`
+					Connected bool   `json:"connected"`
+				}
+				if err := json.Unmarshal(ev.Payload, &presence); err != nil {
+					t.Fatalf("ws presence payload: %v", err)
+				}
+				if presence.PlayerID == playerID && presence.Connected == connected {
+					seen = true
+				}
+			case ev.Type == "room.snapshot" && seen:
+				var snap namethattune.RoomSnapshot
+				if err := json.Unmarshal(ev.Payload, &snap); err != nil {
+					t.Fatalf("ws snapshot payload: %v", err)
+				}
+				if got := findPlayer(t, snap, playerID).Connected; got != connected {
+					t.Fatalf("expected the snapshot after player.presence to show connected=%v, got %v", connected, got)
+				}
+				return
+			}
+		}
+	}
+	connectedInDB := func() bool {
+		t.Helper()
+		snap, err := srv.loadRoomSnapshot(ctx, roomID)
+		if err != nil {
+			t.Fatalf("snapshot: %v", err)
+		}
+		return findPlayer(t, snap, playerID).Connected
+	}
+
+	// The player's tab goes away: the seat is disconnected after the presence timeout.
+	player := dial("playerId=" + playerID + "&playerToken=" + playerToken)
+	_ = player.Close(websocket.StatusNormalClosure, "bye")
+	waitPresence(false)
+	if connectedInDB() {
+		t.Fatalf("expected the seat to be disconnected once its WebSocket closed")
+	}
+
+	// A new connection with the player's token brings the seat back.
+	player = dial("playerId=" + playerID + "&playerToken=" + playerToken)
+	defer func() { _ = player.Close(websocket.StatusNormalClosure, "bye") }()
+	waitPresence(true)
+	if !connectedInDB() {
+		t.Fatalf("expected the seat to be connected again")
+	}
+}
+
+// --------------------
+// Test server wiring
+// --------------------
+
+func newTestServerNoDB(t *testing.T) *Server {
+	t.Helper()
+
+	// For routes that don't require DB (like /healthz), we can run a server with nil deps.
+	// Handlers that touch DB will panic; tests must not call them here.
+	return NewServer(nil, nil, realtime.NewRegistry(), nil, NewNameThatTuneModule())
+}
+
+func newTestServerNoDBWithAuth(t *testing.T, auth *AuthService) *Server {
+	t.Helper()
+
+	return NewServer(nil, nil, realtime.NewRegistry(), auth, NewNameThatTuneModule())
+}
+
+func newTestServer(t *testing.T, pool *pgxpool.Pool) *Server {
+	t.Helper()
+	coreRepo := core.NewRepo(pool)
+	nttRepo := namethattune.NewRepo(pool)
+	rt := realtime.NewRegistry()
+	return NewServer(coreRepo, nttRepo, rt, nil, NewNameThatTuneModule())
+}
+
+func freshDB(t *testing.T, ctx context.Context) *pgxpool.Pool {
+	t.Helper()
+
+	// If no DB configured, skip integration tests.
+	testutil.SkipIfNoDB(t)
+
+	pool := testutil.WithFreshDB(ctx, t)
+	return pool
+}
+
+// --------------------
+// Helpers
+// --------------------
+
+func createRoom(t *testing.T, h http.Handler, ownerSub, name string) string {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/games/name-that-tune/rooms", strings.NewReader(`{"name":"`+jsonEscape(name)+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User-Sub", ownerSub)
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create room: expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var res struct {
+		RoomID string `json:"roomId"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+		t.Fatalf("create room: unmarshal: %v", err)
+	}
+	if res.RoomID == "" {
+		t.Fatalf("create room: missing roomId in response: %s", rr.Body.String())
+	}
+	return res.RoomID
+}
+
+func joinRoom(t *testing.T, h http.Handler, roomID, sub, body string) string {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/games/name-that-tune/rooms/"+roomID+"/join", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if sub != "" {
+		req.Header.Set("X-User-Sub", sub)
+	}
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("join room: expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var res struct {
+		PlayerID string `json:"playerId"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+		t.Fatalf("join room: unmarshal: %v", err)
+	}
+	if res.PlayerID == "" {
+		t.Fatalf("join room: missing playerId in response: %s", rr.Body.String())
+	}
+	return res.PlayerID
+}
+
+func createPlaylistWithItems(t *testing.T, h http.Handler, ownerSub, name string, youtubeURLs ...string) string {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/games/name-that-tune/playlists", strings.NewReader(`{"name":"`+jsonEscape(name)+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User-Sub", ownerSub)
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create playlist: expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var pl namethattune.Playlist
+	if err := json.Unmarshal(rr.Body.Bytes(), &pl); err != nil {
+		t.Fatalf("create playlist: unmarshal: %v", err)
+	}
+
+	for _, u := range youtubeURLs {
+		req := httptest.NewRequest(http.MethodPost, "/api/games/name-that-tune/playlists/"+pl.ID+"/items", strings.NewReader(`{"youtubeUrl":"`+jsonEscape(u)+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User-Sub", ownerSub)
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("add item: expected 201, got %d: %s", rr.Code, rr.Body.String())
+		}
+	}
+	return pl.ID
 }
 
 func findPlayer(t *testing.T, snap namethattune.RoomSnapshot, playerID string) namethattune.PlayerView {
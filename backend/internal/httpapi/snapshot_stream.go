@@ -0,0 +1,77 @@
+package httpapi
+
+import (
+	"encoding/json"
+
+	"github.com/valentin/bes-games/backend/internal/realtime"
+)
+
+// A room WebSocket opened with ?patches=1 receives the room snapshot in full once, on
+// connect and on request (room.resync), then room.patch events instead of room.snapshot:
+// JSON-patch style diffs against the previous snapshot of the same connection. A patch
+// payload carries the seq of its base (baseSeq) and the operations (ops); when a patch
+// would not be smaller than the snapshot, the snapshot is sent in full instead.
+
+// snapshotStream tracks the last snapshot sent on a connection.
+type snapshotStream struct {
+	// doc is the last snapshot sent, decoded from its JSON.
+	doc any
+	// seq is the seq of the event that carried it.
+	seq uint64
+}
+
+// reset returns a room.snapshot event sent in full and makes it the base of the next
+// patches.
+func (st *snapshotStream) reset(ev realtime.Event) (realtime.Event, error) {
+	raw, doc, err := encodeSnapshot(ev.Payload)
+	if err != nil {
+		return realtime.Event{}, err
+	}
+	st.doc, st.seq = doc, ev.Seq
+	ev.Payload = json.RawMessage(raw)
+	return ev, nil
+}
+
+// next returns the event to send for a broadcast room.snapshot event: a room.patch against
+// the previous snapshot, or the snapshot itself when the patch would not be smaller.
+func (st *snapshotStream) next(ev realtime.Event) (realtime.Event, error) {
+	if st.doc == nil {
+		return st.reset(ev)
+	}
+	raw, doc, err := encodeSnapshot(ev.Payload)
+	if err != nil {
+		return realtime.Event{}, err
+	}
+	ops := realtime.Diff(st.doc, doc)
+	if ops == nil {
+		ops = []realtime.PatchOp{}
+	}
+	patch, err := json.Marshal(map[string]any{
+		"baseSeq": st.seq,
+		"ops":     ops,
+	})
+	if err != nil {
+		return realtime.Event{}, err
+	}
+
+	st.doc, st.seq = doc, ev.Seq
+	if len(patch) >= len(raw) {
+		ev.Payload = json.RawMessage(raw)
+		return ev, nil
+	}
+	ev.Type = "room.patch"
+	ev.Payload = json.RawMessage(patch)
+	return ev, nil
+}
+
+func encodeSnapshot(payload any) ([]byte, any, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	var doc any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, nil, err
+	}
+	return raw, doc, nil
+}
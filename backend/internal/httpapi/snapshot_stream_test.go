@@ -0,0 +1,77 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/valentin/bes-games/backend/internal/games/namethattune"
+	"github.com/valentin/bes-games/backend/internal/realtime"
+)
+
+func TestSnapshotStream_PatchesAgainstLastSnapshot(t *testing.T) {
+	t.Parallel()
+
+	items := make([]namethattune.PlaylistItem, 50)
+	for i := range items {
+		items[i] = namethattune.PlaylistItem{ID: "item", Title: strings.Repeat("t", 40), AcceptedAnswers: []string{}}
+	}
+	snap := namethattune.RoomSnapshot{
+		RoomID:   "room-1",
+		Players:  []namethattune.PlayerView{{PlayerID: "p1", Nickname: "Alice"}},
+		Playlist: &namethattune.PlaylistView{PlaylistID: "pl", Items: items},
+	}
+
+	var st snapshotStream
+	first, err := st.reset(realtime.Event{Type: "room.snapshot", Seq: 4, Payload: snap})
+	if err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	if first.Type != "room.snapshot" {
+		t.Fatalf("expected the first snapshot in full, got %q", first.Type)
+	}
+	var doc any
+	if err := json.Unmarshal(first.Payload.(json.RawMessage), &doc); err != nil {
+		t.Fatalf("decode snapshot: %v", err)
+	}
+
+	snap.Players = []namethattune.PlayerView{{PlayerID: "p1", Nickname: "Alice", Score: 1}}
+	ev, err := st.next(realtime.Event{Type: "room.snapshot", Seq: 5, Payload: snap})
+	if err != nil {
+		t.Fatalf("next: %v", err)
+	}
+	if ev.Type != "room.patch" || ev.Seq != 5 {
+		t.Fatalf("expected a room.patch with seq 5, got %q seq %d", ev.Type, ev.Seq)
+	}
+	var patch struct {
+		BaseSeq uint64             `json:"baseSeq"`
+		Ops     []realtime.PatchOp `json:"ops"`
+	}
+	if err := json.Unmarshal(ev.Payload.(json.RawMessage), &patch); err != nil {
+		t.Fatalf("decode patch: %v", err)
+	}
+	if patch.BaseSeq != 4 || len(patch.Ops) != 1 {
+		t.Fatalf("expected one op on top of seq 4, got %+v", patch)
+	}
+	got, err := realtime.Apply(doc, patch.Ops)
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	_, want, _ := encodeSnapshot(snap)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("patched snapshot differs from the new snapshot")
+	}
+
+	// A change touching every value goes out in full.
+	if _, err := st.reset(realtime.Event{Type: "room.snapshot", Seq: 6, Payload: []int{1, 2, 3, 4}}); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	ev, err = st.next(realtime.Event{Type: "room.snapshot", Seq: 7, Payload: []int{5, 6, 7, 8}})
+	if err != nil {
+		t.Fatalf("next: %v", err)
+	}
+	if ev.Type != "room.snapshot" || st.seq != 7 {
+		t.Fatalf("expected a full snapshot, got %q (base seq %d)", ev.Type, st.seq)
+	}
+}
@@ -0,0 +1,89 @@
+package httpapi
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/valentin/bes-games/backend/internal/games/namethattune"
+)
+
+func (s *Server) doTeamCreate(ctx context.Context, roomID, sub, name string) (namethattune.RoomSnapshot, error) {
+	if _, err := s.nttRepo.CreateTeam(ctx, roomID, sub, name); err != nil {
+		status, msg := mapDomainErr(err)
+		return namethattune.RoomSnapshot{}, &apiError{Status: status, Message: msg}
+	}
+	return s.teamsChanged(ctx, roomID)
+}
+
+func (s *Server) doTeamDelete(ctx context.Context, roomID, sub, teamID string) (namethattune.RoomSnapshot, error) {
+	if err := s.nttRepo.DeleteTeam(ctx, roomID, sub, strings.TrimSpace(teamID)); err != nil {
+		status, msg := mapDomainErr(err)
+		return namethattune.RoomSnapshot{}, &apiError{Status: status, Message: msg}
+	}
+	return s.teamsChanged(ctx, roomID)
+}
+
+// doTeamAssign moves a player into a team; an empty teamID makes the player teamless.
+func (s *Server) doTeamAssign(ctx context.Context, roomID, sub, playerID, teamID string) (namethattune.RoomSnapshot, error) {
+	if err := s.nttRepo.AssignTeam(ctx, roomID, sub, strings.TrimSpace(playerID), strings.TrimSpace(teamID)); err != nil {
+		status, msg := mapDomainErr(err)
+		return namethattune.RoomSnapshot{}, &apiError{Status: status, Message: msg}
+	}
+	return s.teamsChanged(ctx, roomID)
+}
+
+func (s *Server) teamsChanged(ctx context.Context, roomID string) (namethattune.RoomSnapshot, error) {
+	snap, err := s.loadRoomSnapshot(ctx, roomID)
+	if err != nil {
+		status, msg := mapDomainErr(err)
+		return namethattune.RoomSnapshot{}, &apiError{Status: status, Message: msg}
+	}
+	s.broadcastSnapshot(ctx, roomID)
+	return snap, nil
+}
+
+// buzzLockMates returns the players sharing playerID's buzz lock: the player's team under
+// the TeamBuzzLock rule, or nil when the player buzzes on their own.
+func (s *Server) buzzLockMates(ctx context.Context, roomID, playerID string, rules namethattune.RoomRules) ([]string, error) {
+	if !rules.TeamBuzzLock {
+		return nil, nil
+	}
+	mates, err := s.nttRepo.TeamMates(ctx, roomID, playerID)
+	if err != nil {
+		status, msg := mapDomainErr(err)
+		return nil, &apiError{Status: status, Message: msg}
+	}
+	return mates, nil
+}
+
+// teamBuzzed reports whether one of mates already buzzed (or answered) on the current
+// track.
+func teamBuzzed(p namethattune.PlaybackState, mates []string) bool {
+	for _, id := range mates {
+		if p.Buzzes[id] > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// setWrongAnswerCooldown puts the player, or their whole team under the TeamBuzzLock rule,
+// on cooldown until until. It returns the player IDs locked out.
+func (s *Server) setWrongAnswerCooldown(ctx context.Context, roomID, playerID string, rules namethattune.RoomRules, until time.Time) ([]string, error) {
+	locked := []string{playerID}
+	mates, err := s.buzzLockMates(ctx, roomID, playerID, rules)
+	if err != nil {
+		// Fall back to the player alone rather than failing the answer.
+		log.Printf("team cooldown: load team failed: roomId=%s playerId=%s err=%v", roomID, playerID, err)
+	} else if len(mates) > 0 {
+		locked = mates
+	}
+	for _, id := range locked {
+		if err := s.setBuzzCooldown(roomID, id, until); err != nil {
+			return nil, err
+		}
+	}
+	return locked, nil
+}
@@ -0,0 +1,82 @@
+package httpapi
+
+import (
+	"sync"
+	"time"
+)
+
+// roomTimers holds named one-shot timers per room (clip end, ...). Scheduling a timer
+// replaces the previous one with the same name; timers are process-local, like the
+// owner timeouts of roomLifecycle, so every request of a room must reach the same
+// replica (see the README): a timer armed on one replica is neither replaced nor
+// cancelled by actions handled on another.
+type roomTimers struct {
+	mu     sync.Mutex
+	timers map[roomTimerKey]*time.Timer
+}
+
+type roomTimerKey struct {
+	roomID string
+	name   string
+}
+
+func newRoomTimers() *roomTimers {
+	return &roomTimers{timers: make(map[roomTimerKey]*time.Timer)}
+}
+
+// schedule runs fn after delay, replacing any pending timer with the same name.
+func (t *roomTimers) schedule(roomID, name string, delay time.Duration, fn func()) {
+	key := roomTimerKey{roomID: roomID, name: name}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if prev, ok := t.timers[key]; ok {
+		prev.Stop()
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		t.mu.Lock()
+		if t.timers[key] == timer {
+			delete(t.timers, key)
+		}
+		t.mu.Unlock()
+		fn()
+	})
+	t.timers[key] = timer
+}
+
+// cancel stops the named timer of a room, if pending.
+func (t *roomTimers) cancel(roomID, name string) {
+	key := roomTimerKey{roomID: roomID, name: name}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if timer, ok := t.timers[key]; ok {
+		timer.Stop()
+		delete(t.timers, key)
+	}
+}
+
+// cancelRoom stops every pending timer of a room.
+func (t *roomTimers) cancelRoom(roomID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for key, timer := range t.timers {
+		if key.roomID == roomID {
+			timer.Stop()
+			delete(t.timers, key)
+		}
+	}
+}
+
+// pending reports whether the named timer of a room is scheduled.
+func (t *roomTimers) pending(roomID, name string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, ok := t.timers[roomTimerKey{roomID: roomID, name: name}]
+	return ok
+}
@@ -0,0 +1,58 @@
+package httpapi
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRoomTimers_ReplaceAndCancel(t *testing.T) {
+	t.Parallel()
+
+	timers := newRoomTimers()
+	fired := make(chan string, 4)
+
+	timers.schedule("room-1", "clip", time.Hour, func() { fired <- "stale" })
+	timers.schedule("room-1", "clip", 10*time.Millisecond, func() { fired <- "clip" })
+	select {
+	case got := <-fired:
+		if got != "clip" {
+			t.Fatalf("expected the replacing timer to fire, got %q", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timer did not fire")
+	}
+	if timers.pending("room-1", "clip") {
+		t.Fatalf("expected fired timer to be forgotten")
+	}
+
+	timers.schedule("room-1", "clip", 10*time.Millisecond, func() { fired <- "cancelled" })
+	timers.schedule("room-1", "other", 10*time.Millisecond, func() { fired <- "cancelled" })
+	timers.schedule("room-2", "clip", time.Hour, func() {})
+	timers.cancelRoom("room-1")
+	select {
+	case got := <-fired:
+		t.Fatalf("expected cancelled timers not to fire, got %q", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if !timers.pending("room-2", "clip") {
+		t.Fatalf("expected timers of other rooms to be kept")
+	}
+	timers.cancel("room-2", "clip")
+	if timers.pending("room-2", "clip") {
+		t.Fatalf("expected timer to be cancelled")
+	}
+}
+
+func TestRunEvery_DisabledWithoutInterval(t *testing.T) {
+	t.Parallel()
+
+	for _, interval := range []time.Duration{0, -time.Hour} {
+		runEvery(context.Background(), "test loop", interval, func(context.Context) {
+			t.Errorf("expected interval %s to disable the loop", interval)
+		})
+	}
+	// Returns instead of panicking in time.NewTicker (the server has no repo to purge with).
+	NewServer(nil, nil, nil, nil).RunPlaylistPurge(context.Background(), 0)
+	NewServer(nil, nil, nil, nil).RunSeatExpiry(context.Background(), -time.Minute)
+}
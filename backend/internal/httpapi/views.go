@@ -0,0 +1,85 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/valentin/bes-games/backend/internal/games/namethattune"
+	"github.com/valentin/bes-games/backend/internal/realtime"
+)
+
+// Room snapshots are rendered per viewer: the owner sees everything, players see the
+// redacted view (no unrevealed answers, no other player's sub) and spectators also lose
+// their own and the owner's subs. Tokens are never part of a snapshot.
+
+// viewSnapshot renders a room snapshot for viewer.
+func viewSnapshot(snap namethattune.RoomSnapshot, viewer realtime.Viewer) namethattune.RoomSnapshot {
+	switch viewer.Role {
+	case realtime.RoleOwner:
+		return snap
+	case realtime.RolePlayer:
+		return snap.Redacted().Anonymized(viewer.ID)
+	default:
+		snap = snap.Redacted().Anonymized("")
+		snap.OwnerSub = ""
+		return snap
+	}
+}
+
+// renderRoomEvent is the hub renderer of room events. Snapshots relayed by another
+// instance arrive as raw JSON; one that cannot be decoded is only sent to the owner.
+func renderRoomEvent(ev realtime.Event, viewer realtime.Viewer) (realtime.Event, bool) {
+	if ev.Type != "room.snapshot" || viewer.Role == realtime.RoleOwner {
+		return ev, true
+	}
+	switch p := ev.Payload.(type) {
+	case namethattune.RoomSnapshot:
+		ev.Payload = viewSnapshot(p, viewer)
+	case json.RawMessage:
+		var snap namethattune.RoomSnapshot
+		if err := json.Unmarshal(p, &snap); err != nil {
+			return ev, false
+		}
+		ev.Payload = viewSnapshot(snap, viewer)
+	default:
+		return ev, false
+	}
+	return ev, true
+}
+
+// requestViewer identifies who is looking at the room from a request: the owner (session
+// or ownerToken query parameter), a player (playerId and playerToken query parameters, or
+// a session/guest sub on the roster) or else a spectator.
+func (s *Server) requestViewer(r *http.Request, snap namethattune.RoomSnapshot) (realtime.Viewer, error) {
+	q := r.URL.Query()
+	sub := userSub(r)
+	if sub != "" && sub == snap.OwnerSub {
+		return realtime.Viewer{Role: realtime.RoleOwner}, nil
+	}
+	ok, err := s.validateOwnerToken(snap.RoomID, q.Get("ownerToken"))
+	if err != nil {
+		return realtime.Viewer{}, err
+	}
+	if ok {
+		return realtime.Viewer{Role: realtime.RoleOwner}, nil
+	}
+	playerID := q.Get("playerId")
+	ok, err = s.validatePlayerToken(snap.RoomID, playerID, q.Get("playerToken"))
+	if err != nil {
+		return realtime.Viewer{}, err
+	}
+	if ok {
+		return realtime.Viewer{Role: realtime.RolePlayer, ID: playerID}, nil
+	}
+	if sub == "" {
+		sub = guestSub(r)
+	}
+	if sub != "" {
+		for _, p := range snap.Players {
+			if p.Sub == sub {
+				return realtime.Viewer{Role: realtime.RolePlayer, ID: p.PlayerID}, nil
+			}
+		}
+	}
+	return realtime.Viewer{Role: realtime.RoleSpectator}, nil
+}
@@ -0,0 +1,75 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/valentin/bes-games/backend/internal/games/namethattune"
+	"github.com/valentin/bes-games/backend/internal/realtime"
+)
+
+func TestRenderRoomEvent(t *testing.T) {
+	t.Parallel()
+
+	track := namethattune.PlaylistItem{ID: "a", Title: "Queen - Bohemian Rhapsody", YouTubeID: "fJ9rUzIMcZQ"}
+	snap := namethattune.RoomSnapshot{
+		RoomID:   "room-1",
+		OwnerSub: "owner-sub",
+		Players:  []namethattune.PlayerView{{PlayerID: "p1", Sub: "sub-1"}, {PlayerID: "p2", Sub: "sub-2"}},
+		Playlist: &namethattune.PlaylistView{Items: []namethattune.PlaylistItem{track}},
+		Playback: namethattune.PlaybackView{Phase: namethattune.RoundPhasePlaying, Track: &track},
+	}
+	raw, err := json.Marshal(snap)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	for name, payload := range map[string]any{"local": snap, "relayed": json.RawMessage(raw)} {
+		ev := realtime.Event{Type: "room.snapshot", RoomID: "room-1", Payload: payload}
+
+		out, ok := renderRoomEvent(ev, realtime.Viewer{Role: realtime.RoleOwner})
+		if !ok {
+			t.Fatalf("%s: expected owner event to be sent", name)
+		}
+		if p, isSnap := out.Payload.(namethattune.RoomSnapshot); isSnap && p.Playback.Track.Title == "" {
+			t.Fatalf("%s: expected owner to see the full track", name)
+		}
+
+		out, ok = renderRoomEvent(ev, realtime.Viewer{Role: realtime.RolePlayer, ID: "p2"})
+		player, isSnap := out.Payload.(namethattune.RoomSnapshot)
+		if !ok || !isSnap {
+			t.Fatalf("%s: expected a player snapshot, got %T", name, out.Payload)
+		}
+		if player.Playback.Track.Title != "" || player.Playlist.Items[0].Title != "" {
+			t.Fatalf("%s: expected player view to be redacted, got %+v", name, player.Playback.Track)
+		}
+		if player.Players[0].Sub != "" || player.Players[1].Sub != "sub-2" || player.OwnerSub != "owner-sub" {
+			t.Fatalf("%s: expected player to only see their own sub and the owner's, got %+v", name, player)
+		}
+
+		out, ok = renderRoomEvent(ev, realtime.Viewer{Role: realtime.RoleSpectator})
+		spectator, isSnap := out.Payload.(namethattune.RoomSnapshot)
+		if !ok || !isSnap {
+			t.Fatalf("%s: expected a spectator snapshot, got %T", name, out.Payload)
+		}
+		if spectator.OwnerSub != "" || spectator.Players[0].Sub != "" || spectator.Players[1].Sub != "" {
+			t.Fatalf("%s: expected spectator to see no sub, got %+v", name, spectator)
+		}
+		if spectator.Playback.Track.Title != "" {
+			t.Fatalf("%s: expected spectator view to be redacted", name)
+		}
+	}
+
+	if snap.Players[0].Sub != "sub-1" || snap.Playback.Track.Title == "" {
+		t.Fatalf("expected rendering not to modify the broadcast snapshot")
+	}
+
+	other := realtime.Event{Type: "buzzer", Payload: map[string]any{"x": 1}}
+	if out, ok := renderRoomEvent(other, realtime.Viewer{Role: realtime.RoleSpectator}); !ok || out.Type != "buzzer" {
+		t.Fatalf("expected other events to pass through")
+	}
+	bad := realtime.Event{Type: "room.snapshot", Payload: json.RawMessage(`{`)}
+	if _, ok := renderRoomEvent(bad, realtime.Viewer{Role: realtime.RolePlayer}); ok {
+		t.Fatalf("expected undecodable snapshot to be withheld from players")
+	}
+}
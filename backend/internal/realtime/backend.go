@@ -0,0 +1,16 @@
+package realtime
+
+// Backend relays events between the hubs of every API instance.
+//
+// The in-process default (a Registry without backend) delivers straight to local
+// subscribers. Multi-instance deployments plug a shared transport (e.g. PostgresBackend)
+// so that a Broadcast on one replica reaches WebSocket clients connected to another.
+type Backend interface {
+	// Attach registers the function that fans an event out to the local hubs.
+	// It is called once by NewRegistryWithBackend.
+	Attach(deliver func(Event))
+
+	// Publish relays ev to every instance. Implementations must eventually invoke the
+	// attached deliver function on every instance, including the publishing one.
+	Publish(ev Event)
+}
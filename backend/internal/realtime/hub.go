@@ -8,26 +8,104 @@ import (
 // Hub is a simple fan-out pub/sub for room-scoped realtime events.
 //
 // Design goals:
-// - Minimal dependencies and easy to reason about.
-// - Non-blocking broadcasts: slow subscribers drop events rather than backpressure the whole room.
-// - Explicit subscribe/unsubscribe lifecycle.
-// - Room registry to create hubs on-demand.
+//   - Minimal dependencies and easy to reason about.
+//   - Non-blocking broadcasts: slow subscribers drop events rather than backpressure the whole room.
+//     A subscriber that dropped events gets an EventResync marker as soon as its buffer has
+//     room again; one that keeps dropping (DefaultDropLimit events in a row) is closed.
+//   - Explicit subscribe/unsubscribe lifecycle.
+//   - Room registry to create hubs on-demand.
 //
 // This is intended to be used by the HTTP/WebSocket layer:
 // - When a client connects to a room WS endpoint, call Registry.Room(roomID).Subscribe(...)
 // - When state changes (DB mutations), publish events via Registry.Room(roomID).Broadcast(...)
+//
+// Hubs obtained from a Registry publish through the registry's Backend, so a broadcast can
+// reach subscribers connected to other API instances. A standalone hub (NewHub) only
+// delivers locally.
+//
+// Each subscriber declares who it is (a Viewer). When the hub has a Renderer, every event
+// is rendered once per distinct viewer before delivery, so owners, players and spectators
+// can receive different views of the same event.
+//
+// Delivered events are numbered: Seq increases by one with every event the hub delivers,
+// so a subscriber that sees a jump knows it missed an event. Numbers are assigned by the
+// hub that delivers the event, i.e. per room and per API instance.
 type Hub struct {
-	mu   sync.RWMutex
-	subs map[uint64]chan Event
-	seq  uint64
+	mu        sync.RWMutex
+	subs      map[uint64]*subscriber
+	seq       uint64
+	lastSeq   uint64
+	publish   func(Event)
+	render    Renderer
+	dropLimit int
+	stats     HubStats
+}
+
+type subscriber struct {
+	ch     chan Event
+	viewer Viewer
+	// missed counts the events dropped since the subscriber last got an EventResync.
+	missed int
+}
+
+// EventResync is a control event the hub sends to a subscriber that dropped events: its
+// view of the room is stale and must be reloaded. Its payload is a Resync.
+const EventResync = "room.resync"
+
+// DefaultDropLimit is how many events in a row a subscriber can drop before the hub
+// closes it.
+const DefaultDropLimit = 32
+
+// Resync is the payload of an EventResync event.
+type Resync struct {
+	// Dropped is the number of events the subscriber missed.
+	Dropped int `json:"dropped"`
+	// Closed is true when the subscriber reached the drop limit: the event is the last one
+	// and the channel is closed right after it.
+	Closed bool `json:"closed,omitempty"`
 }
 
+// HubStats are the delivery counters of a hub.
+type HubStats struct {
+	Subscribers int    `json:"subscribers"`
+	Delivered   uint64 `json:"delivered"`
+	Dropped     uint64 `json:"dropped"`
+	Resyncs     uint64 `json:"resyncs"`
+	Closed      uint64 `json:"closed"`
+}
+
+// Viewer roles.
+const (
+	RoleOwner     = "owner"
+	RolePlayer    = "player"
+	RoleSpectator = "spectator"
+	RoleDisplay   = "display"
+)
+
+// Viewer identifies the receiver of a subscription.
+type Viewer struct {
+	// Role is one of RoleOwner, RolePlayer, RoleSpectator or RoleDisplay.
+	Role string
+	// ID is the viewer's identity within its role (e.g. the player ID), if any.
+	ID string
+	// Audience marks a spectator that joined as one, as opposed to a connection that
+	// gets the spectator view because it has not identified itself.
+	Audience bool
+}
+
+// Renderer adapts an event for a viewer. Returning false skips the event for that viewer.
+// It must not mutate the event payload in place: the same event is rendered for every
+// viewer.
+type Renderer func(ev Event, v Viewer) (Event, bool)
+
 // Event is a generic room event envelope.
 // Payload should be JSON-marshalable by the caller.
 // Timestamp defaults to time.Now().UTC() if zero.
+// Seq is set by the hub on delivery; events sent directly to one client have none.
 type Event struct {
 	Type      string    `json:"type"`
 	RoomID    string    `json:"roomId"`
+	Seq       uint64    `json:"seq,omitempty"`
 	Timestamp time.Time `json:"ts"`
 	Payload   any       `json:"payload,omitempty"`
 }
@@ -35,16 +113,35 @@ type Event struct {
 // NewHub creates a new Hub instance.
 func NewHub() *Hub {
 	return &Hub{
-		subs: make(map[uint64]chan Event),
+		subs:      make(map[uint64]*subscriber),
+		dropLimit: DefaultDropLimit,
 	}
 }
 
-// Subscribe registers a subscriber and returns:
+// SetDropLimit sets how many events in a row a subscriber can drop before it is closed.
+func (h *Hub) SetDropLimit(n int) {
+	if n <= 0 {
+		return
+	}
+	h.mu.Lock()
+	h.dropLimit = n
+	h.mu.Unlock()
+}
+
+// SetRenderer sets how events are rendered per viewer. A nil renderer delivers every event
+// as-is.
+func (h *Hub) SetRenderer(render Renderer) {
+	h.mu.Lock()
+	h.render = render
+	h.mu.Unlock()
+}
+
+// Subscribe registers a subscriber viewing the room as viewer and returns:
 // - a receive-only channel that will carry events
 // - a cancel function that unregisters the subscriber and closes the channel
 //
 // buffer defines the channel buffer; if <= 0 a default is used.
-func (h *Hub) Subscribe(buffer int) (<-chan Event, func()) {
+func (h *Hub) Subscribe(buffer int, viewer Viewer) (<-chan Event, func()) {
 	if buffer <= 0 {
 		buffer = 64
 	}
@@ -53,15 +150,15 @@ func (h *Hub) Subscribe(buffer int) (<-chan Event, func()) {
 	h.seq++
 	id := h.seq
 	ch := make(chan Event, buffer)
-	h.subs[id] = ch
+	h.subs[id] = &subscriber{ch: ch, viewer: viewer}
 	h.mu.Unlock()
 
 	cancel := func() {
 		h.mu.Lock()
-		c, ok := h.subs[id]
+		sub, ok := h.subs[id]
 		if ok {
 			delete(h.subs, id)
-			close(c)
+			close(sub.ch)
 		}
 		h.mu.Unlock()
 	}
@@ -71,22 +168,133 @@ func (h *Hub) Subscribe(buffer int) (<-chan Event, func()) {
 
 // Broadcast fan-outs an event to all current subscribers.
 //
-// If a subscriber is slow and its buffer is full, the event is dropped for that subscriber.
+// If a subscriber is slow and its buffer is full, the event is dropped for that subscriber,
+// which then gets an EventResync (see Hub).
 func (h *Hub) Broadcast(ev Event) {
 	if ev.Timestamp.IsZero() {
 		ev.Timestamp = time.Now().UTC()
 	}
+	if h.publish != nil {
+		h.publish(ev)
+		return
+	}
+	h.deliver(ev)
+}
 
-	h.mu.RLock()
-	defer h.mu.RUnlock()
+// deliver numbers an event and fan-outs it to the subscribers of this hub only.
+// It holds the write lock so that subscribers receive events in sequence order.
+func (h *Hub) deliver(ev Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastSeq++
+	ev.Seq = h.lastSeq
 
-	for _, ch := range h.subs {
-		select {
-		case ch <- ev:
-		default:
+	type rendered struct {
+		ev Event
+		ok bool
+	}
+	var views map[Viewer]rendered
+	for id, sub := range h.subs {
+		out := ev
+		if h.render != nil {
+			r, seen := views[sub.viewer]
+			if !seen {
+				r.ev, r.ok = h.render(ev, sub.viewer)
+				if views == nil {
+					views = make(map[Viewer]rendered)
+				}
+				views[sub.viewer] = r
+			}
+			if !r.ok {
+				continue
+			}
+			out = r.ev
+		}
+		// Tell a subscriber that dropped events before giving it anything else, once its
+		// buffer has room for both the marker and the event.
+		if sub.missed > 0 {
+			if cap(sub.ch)-len(sub.ch) < 2 || !offer(sub, resyncEvent(ev.RoomID, sub.missed, false)) {
+				h.drop(id, sub, ev.RoomID)
+				continue
+			}
+			h.stats.Resyncs++
+			sub.missed = 0
+		}
+		if !offer(sub, out) {
 			// Drop for this subscriber to avoid blocking.
+			h.drop(id, sub, ev.RoomID)
+			continue
 		}
+		h.stats.Delivered++
+	}
+}
+
+// drop records an event dropped for a subscriber and closes the subscriber once it
+// reaches the drop limit. The caller holds the write lock.
+func (h *Hub) drop(id uint64, sub *subscriber, roomID string) {
+	sub.missed++
+	h.stats.Dropped++
+	if sub.missed < h.dropLimit {
+		return
+	}
+	// Make room for a final marker so the subscriber learns why its channel closes.
+	select {
+	case <-sub.ch:
+	default:
+	}
+	offer(sub, resyncEvent(roomID, sub.missed, true))
+	delete(h.subs, id)
+	close(sub.ch)
+	h.stats.Closed++
+}
+
+// offer queues ev for a subscriber without blocking and reports whether it was queued.
+func offer(sub *subscriber, ev Event) bool {
+	select {
+	case sub.ch <- ev:
+		return true
+	default:
+		return false
+	}
+}
+
+func resyncEvent(roomID string, dropped int, closed bool) Event {
+	return Event{
+		Type:      EventResync,
+		RoomID:    roomID,
+		Timestamp: time.Now().UTC(),
+		Payload:   Resync{Dropped: dropped, Closed: closed},
+	}
+}
+
+// Render renders ev for viewer with the hub's renderer, for events sent directly to a
+// single subscriber rather than broadcast.
+func (h *Hub) Render(ev Event, viewer Viewer) (Event, bool) {
+	h.mu.RLock()
+	render := h.render
+	h.mu.RUnlock()
+	if render == nil {
+		return ev, true
 	}
+	return render(ev, viewer)
+}
+
+// Seq returns the sequence number of the last event delivered by the hub. An event
+// delivered after a call to Seq has a greater number.
+func (h *Hub) Seq() uint64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.lastSeq
+}
+
+// Stats returns the hub's delivery counters.
+func (h *Hub) Stats() HubStats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	stats := h.stats
+	stats.Subscribers = len(h.subs)
+	return stats
 }
 
 // SubscriberCount returns the current number of subscribers.
@@ -96,28 +304,106 @@ func (h *Hub) SubscriberCount() int {
 	return len(h.subs)
 }
 
+// ViewerCount returns the current number of subscribers whose viewer matches.
+func (h *Hub) ViewerCount(match func(Viewer) bool) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	n := 0
+	for _, sub := range h.subs {
+		if match(sub.viewer) {
+			n++
+		}
+	}
+	return n
+}
+
 // Close closes all subscriber channels and resets the hub.
 // After Close, the hub can be used again (new subscribers can be added).
 func (h *Hub) Close() {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	for id, ch := range h.subs {
+	for id, sub := range h.subs {
 		delete(h.subs, id)
-		close(ch)
+		close(sub.ch)
 	}
 }
 
 // Registry manages per-room hubs.
 type Registry struct {
-	mu    sync.RWMutex
-	rooms map[string]*Hub
+	mu        sync.RWMutex
+	rooms     map[string]*Hub
+	backend   Backend
+	render    Renderer
+	dropLimit int
 }
 
-// NewRegistry creates a new hub registry.
+// NewRegistry creates a new hub registry using the in-process backend.
 func NewRegistry() *Registry {
-	return &Registry{
-		rooms: make(map[string]*Hub),
+	return NewRegistryWithBackend(nil)
+}
+
+// NewRegistryWithBackend creates a hub registry that relays broadcasts through backend.
+// A nil backend means in-process delivery only (same as NewRegistry).
+func NewRegistryWithBackend(backend Backend) *Registry {
+	r := &Registry{
+		rooms:   make(map[string]*Hub),
+		backend: backend,
+	}
+	if backend != nil {
+		backend.Attach(r.deliver)
+	}
+	return r
+}
+
+// SetRenderer sets the renderer of every current and future room hub.
+func (r *Registry) SetRenderer(render Renderer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.render = render
+	for _, h := range r.rooms {
+		h.SetRenderer(render)
+	}
+}
+
+// SetDropLimit sets the drop limit (see Hub.SetDropLimit) of every current and future room
+// hub.
+func (r *Registry) SetDropLimit(n int) {
+	if n <= 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.dropLimit = n
+	for _, h := range r.rooms {
+		h.SetDropLimit(n)
+	}
+}
+
+// Stats returns the delivery counters of every room hub, by room ID.
+func (r *Registry) Stats() map[string]HubStats {
+	r.mu.RLock()
+	hubs := make(map[string]*Hub, len(r.rooms))
+	for roomID, h := range r.rooms {
+		hubs[roomID] = h
+	}
+	r.mu.RUnlock()
+
+	stats := make(map[string]HubStats, len(hubs))
+	for roomID, h := range hubs {
+		stats[roomID] = h.Stats()
+	}
+	return stats
+}
+
+// deliver hands a relayed event to the local hub of its room, if any.
+// Rooms without local subscribers have nothing to deliver to, so no hub is created.
+func (r *Registry) deliver(ev Event) {
+	r.mu.RLock()
+	h := r.rooms[ev.RoomID]
+	r.mu.RUnlock()
+	if h != nil {
+		h.deliver(ev)
 	}
 }
 
@@ -140,6 +426,13 @@ func (r *Registry) Room(roomID string) *Hub {
 	}
 
 	h = NewHub()
+	h.render = r.render
+	if r.dropLimit > 0 {
+		h.dropLimit = r.dropLimit
+	}
+	if r.backend != nil {
+		h.publish = r.backend.Publish
+	}
 	r.rooms[roomID] = h
 	return h
 }
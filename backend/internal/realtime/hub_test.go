@@ -0,0 +1,230 @@
+package realtime
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHub_BroadcastLocal(t *testing.T) {
+	t.Parallel()
+
+	reg := NewRegistry()
+	events, cancel := reg.Room("room-1").Subscribe(4, Viewer{Role: RoleSpectator})
+	defer cancel()
+
+	reg.Room("room-1").Broadcast(Event{Type: "room.snapshot", RoomID: "room-1"})
+
+	ev := receive(t, events)
+	if ev.Type != "room.snapshot" {
+		t.Fatalf("expected room.snapshot, got %q", ev.Type)
+	}
+	if ev.Timestamp.IsZero() {
+		t.Fatalf("expected timestamp to be set")
+	}
+}
+
+func TestRegistry_BackendRelaysAcrossInstances(t *testing.T) {
+	t.Parallel()
+
+	bus := &busBackend{}
+	a := NewRegistryWithBackend(bus.instance())
+	b := NewRegistryWithBackend(bus.instance())
+
+	evA, cancelA := a.Room("room-1").Subscribe(4, Viewer{Role: RoleSpectator})
+	defer cancelA()
+	evB, cancelB := b.Room("room-1").Subscribe(4, Viewer{Role: RoleSpectator})
+	defer cancelB()
+	evOther, cancelOther := b.Room("room-2").Subscribe(4, Viewer{Role: RoleSpectator})
+	defer cancelOther()
+
+	a.Room("room-1").Broadcast(Event{Type: "buzzer", RoomID: "room-1"})
+
+	if ev := receive(t, evA); ev.Type != "buzzer" {
+		t.Fatalf("instance a: expected buzzer, got %q", ev.Type)
+	}
+	if ev := receive(t, evB); ev.Type != "buzzer" {
+		t.Fatalf("instance b: expected buzzer, got %q", ev.Type)
+	}
+	select {
+	case ev := <-evOther:
+		t.Fatalf("room-2 should not receive room-1 events, got %q", ev.Type)
+	default:
+	}
+}
+
+// busBackend is an in-memory stand-in for a shared transport: every publish is delivered
+// to every attached instance.
+type busBackend struct {
+	mu       sync.Mutex
+	delivers []func(Event)
+}
+
+type busInstance struct{ bus *busBackend }
+
+func (b *busBackend) instance() Backend { return busInstance{bus: b} }
+
+func (i busInstance) Attach(deliver func(Event)) {
+	i.bus.mu.Lock()
+	defer i.bus.mu.Unlock()
+	i.bus.delivers = append(i.bus.delivers, deliver)
+}
+
+func (i busInstance) Publish(ev Event) {
+	i.bus.mu.Lock()
+	delivers := make([]func(Event), len(i.bus.delivers))
+	copy(delivers, i.bus.delivers)
+	i.bus.mu.Unlock()
+	for _, d := range delivers {
+		d(ev)
+	}
+}
+
+func receive(t *testing.T, ch <-chan Event) Event {
+	t.Helper()
+
+	select {
+	case ev, ok := <-ch:
+		if !ok {
+			t.Fatalf("channel closed")
+		}
+		return ev
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for event")
+	}
+	return Event{}
+}
+
+func TestHub_RendersPerViewer(t *testing.T) {
+	t.Parallel()
+
+	reg := NewRegistry()
+	renders := 0
+	reg.SetRenderer(func(ev Event, v Viewer) (Event, bool) {
+		renders++
+		if v.Role == RoleSpectator {
+			return ev, false
+		}
+		ev.Payload = v.Role + ":" + v.ID
+		return ev, true
+	})
+
+	hub := reg.Room("room-1")
+	owner, cancelOwner := hub.Subscribe(4, Viewer{Role: RoleOwner})
+	defer cancelOwner()
+	p1, cancelP1 := hub.Subscribe(4, Viewer{Role: RolePlayer, ID: "p1"})
+	defer cancelP1()
+	p1Again, cancelP1Again := hub.Subscribe(4, Viewer{Role: RolePlayer, ID: "p1"})
+	defer cancelP1Again()
+	spectator, cancelSpectator := hub.Subscribe(4, Viewer{Role: RoleSpectator})
+	defer cancelSpectator()
+
+	hub.Broadcast(Event{Type: "room.snapshot", RoomID: "room-1"})
+
+	if ev := receive(t, owner); ev.Payload != "owner:" {
+		t.Fatalf("owner: unexpected payload %v", ev.Payload)
+	}
+	if ev := receive(t, p1); ev.Payload != "player:p1" {
+		t.Fatalf("player: unexpected payload %v", ev.Payload)
+	}
+	if ev := receive(t, p1Again); ev.Payload != "player:p1" {
+		t.Fatalf("player (second connection): unexpected payload %v", ev.Payload)
+	}
+	select {
+	case ev := <-spectator:
+		t.Fatalf("spectator should not receive the event, got %v", ev.Payload)
+	default:
+	}
+	if renders != 3 {
+		t.Fatalf("expected one render per distinct viewer (3), got %d", renders)
+	}
+}
+
+func TestHub_ViewerCount(t *testing.T) {
+	t.Parallel()
+
+	hub := NewHub()
+	_, cancelOwner := hub.Subscribe(1, Viewer{Role: RoleOwner})
+	defer cancelOwner()
+	_, cancelA := hub.Subscribe(1, Viewer{Role: RoleSpectator, Audience: true})
+	_, cancelB := hub.Subscribe(1, Viewer{Role: RoleSpectator, Audience: true})
+	defer cancelB()
+	_, cancelAnon := hub.Subscribe(1, Viewer{Role: RoleSpectator})
+	defer cancelAnon()
+
+	audience := func(v Viewer) bool { return v.Audience }
+	if got := hub.ViewerCount(audience); got != 2 {
+		t.Fatalf("expected 2 spectators, got %d", got)
+	}
+	cancelA()
+	if got := hub.ViewerCount(audience); got != 1 {
+		t.Fatalf("expected 1 spectator after cancel, got %d", got)
+	}
+	if got := hub.SubscriberCount(); got != 3 {
+		t.Fatalf("expected 3 subscribers, got %d", got)
+	}
+}
+
+func TestHub_NumbersDeliveredEvents(t *testing.T) {
+	t.Parallel()
+
+	hub := NewHub()
+	events, cancel := hub.Subscribe(2, Viewer{Role: RoleSpectator})
+	defer cancel()
+
+	if got := hub.Seq(); got != 0 {
+		t.Fatalf("expected seq 0 before any event, got %d", got)
+	}
+	for i := 0; i < 3; i++ {
+		hub.Broadcast(Event{Type: "buzzer", RoomID: "room-1"})
+	}
+	// The buffer only held the first two events: the third one is a gap.
+	for want := uint64(1); want <= 2; want++ {
+		if ev := receive(t, events); ev.Seq != want {
+			t.Fatalf("expected seq %d, got %d", want, ev.Seq)
+		}
+	}
+	hub.Broadcast(Event{Type: "buzzer", RoomID: "room-1"})
+	if ev := receive(t, events); ev.Type != EventResync || ev.Payload != (Resync{Dropped: 1}) {
+		t.Fatalf("expected a resync marker for one dropped event, got %+v", ev)
+	}
+	if ev := receive(t, events); ev.Seq != 4 {
+		t.Fatalf("expected seq 4 after the gap, got %d", ev.Seq)
+	}
+	if got := hub.Seq(); got != 4 {
+		t.Fatalf("expected hub seq 4, got %d", got)
+	}
+}
+
+func TestHub_ClosesSubscriberAfterDropLimit(t *testing.T) {
+	t.Parallel()
+
+	hub := NewHub()
+	hub.SetDropLimit(3)
+	slow, cancelSlow := hub.Subscribe(1, Viewer{Role: RolePlayer, ID: "p1"})
+	defer cancelSlow()
+	fast, cancelFast := hub.Subscribe(8, Viewer{Role: RolePlayer, ID: "p2"})
+	defer cancelFast()
+
+	for i := 0; i < 4; i++ {
+		hub.Broadcast(Event{Type: "buzzer", RoomID: "room-1"})
+	}
+
+	ev := receive(t, slow)
+	if ev.Type != EventResync || ev.Payload != (Resync{Dropped: 3, Closed: true}) {
+		t.Fatalf("expected a closing resync marker, got %+v", ev)
+	}
+	if _, ok := <-slow; ok {
+		t.Fatalf("expected the slow subscriber to be closed")
+	}
+	for i := 0; i < 4; i++ {
+		if ev := receive(t, fast); ev.Type != "buzzer" {
+			t.Fatalf("fast subscriber: expected buzzer, got %q", ev.Type)
+		}
+	}
+
+	stats := hub.Stats()
+	if stats != (HubStats{Subscribers: 1, Delivered: 5, Dropped: 3, Closed: 1}) {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
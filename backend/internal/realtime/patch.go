@@ -0,0 +1,203 @@
+package realtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Snapshots can be sent as JSON-patch style diffs (RFC 6902 subset: add, remove and
+// replace) between two documents decoded from JSON with encoding/json into any
+// (map[string]any, []any and scalars).
+
+// Patch operations.
+const (
+	PatchAdd     = "add"
+	PatchRemove  = "remove"
+	PatchReplace = "replace"
+)
+
+// PatchOp is one operation of a diff. Path is a JSON pointer (RFC 6901).
+type PatchOp struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	Value any    `json:"value,omitempty"`
+}
+
+// jsonNull keeps an explicit null value from being omitted from an operation.
+var jsonNull = json.RawMessage("null")
+
+func newPatchOp(op, path string, value any) PatchOp {
+	if value == nil && op != PatchRemove {
+		value = jsonNull
+	}
+	return PatchOp{Op: op, Path: path, Value: value}
+}
+
+// Diff returns the operations that turn prev into next. Objects are compared key by key
+// and arrays index by index, so a change deep inside a large document stays small.
+func Diff(prev, next any) []PatchOp {
+	return appendDiff(nil, "", prev, next)
+}
+
+func appendDiff(ops []PatchOp, path string, prev, next any) []PatchOp {
+	switch a := prev.(type) {
+	case map[string]any:
+		b, ok := next.(map[string]any)
+		if !ok {
+			return append(ops, newPatchOp(PatchReplace, path, next))
+		}
+		for _, k := range sortedKeys(a) {
+			child := path + "/" + escapePointer(k)
+			if bv, ok := b[k]; ok {
+				ops = appendDiff(ops, child, a[k], bv)
+			} else {
+				ops = append(ops, newPatchOp(PatchRemove, child, nil))
+			}
+		}
+		for _, k := range sortedKeys(b) {
+			if _, ok := a[k]; !ok {
+				ops = append(ops, newPatchOp(PatchAdd, path+"/"+escapePointer(k), b[k]))
+			}
+		}
+		return ops
+	case []any:
+		b, ok := next.([]any)
+		if !ok {
+			return append(ops, newPatchOp(PatchReplace, path, next))
+		}
+		common := min(len(a), len(b))
+		for i := 0; i < common; i++ {
+			ops = appendDiff(ops, path+"/"+strconv.Itoa(i), a[i], b[i])
+		}
+		for i := common; i < len(b); i++ {
+			ops = append(ops, newPatchOp(PatchAdd, path+"/"+strconv.Itoa(i), b[i]))
+		}
+		// Remove from the end so that earlier indexes stay valid.
+		for i := len(a) - 1; i >= common; i-- {
+			ops = append(ops, newPatchOp(PatchRemove, path+"/"+strconv.Itoa(i), nil))
+		}
+		return ops
+	default:
+		switch next.(type) {
+		case map[string]any, []any:
+			return append(ops, newPatchOp(PatchReplace, path, next))
+		}
+		if prev != next {
+			ops = append(ops, newPatchOp(PatchReplace, path, next))
+		}
+		return ops
+	}
+}
+
+// Apply applies ops to doc and returns the patched document. doc may be modified in place.
+func Apply(doc any, ops []PatchOp) (any, error) {
+	for _, op := range ops {
+		var err error
+		if doc, err = applyOp(doc, op); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+func applyOp(doc any, op PatchOp) (any, error) {
+	value := op.Value
+	if raw, ok := value.(json.RawMessage); ok {
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, fmt.Errorf("patch %s %s: %w", op.Op, op.Path, err)
+		}
+	}
+	if op.Path == "" {
+		if op.Op == PatchRemove {
+			return nil, fmt.Errorf("patch remove: cannot remove the document root")
+		}
+		return value, nil
+	}
+	if !strings.HasPrefix(op.Path, "/") {
+		return nil, fmt.Errorf("patch %s %s: invalid path", op.Op, op.Path)
+	}
+	tokens := strings.Split(op.Path[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = unescapePointer(t)
+	}
+
+	// Walk to the parent of the target, remembering how to store a changed parent.
+	parent := doc
+	set := func(v any) { doc = v }
+	for _, t := range tokens[:len(tokens)-1] {
+		switch p := parent.(type) {
+		case map[string]any:
+			child, ok := p[t]
+			if !ok {
+				return nil, fmt.Errorf("patch %s %s: path not found", op.Op, op.Path)
+			}
+			set = func(v any) { p[t] = v }
+			parent = child
+		case []any:
+			i, err := strconv.Atoi(t)
+			if err != nil || i < 0 || i >= len(p) {
+				return nil, fmt.Errorf("patch %s %s: path not found", op.Op, op.Path)
+			}
+			set = func(v any) { p[i] = v }
+			parent = p[i]
+		default:
+			return nil, fmt.Errorf("patch %s %s: path not found", op.Op, op.Path)
+		}
+	}
+
+	last := tokens[len(tokens)-1]
+	switch p := parent.(type) {
+	case map[string]any:
+		if _, ok := p[last]; !ok && op.Op != PatchAdd {
+			return nil, fmt.Errorf("patch %s %s: path not found", op.Op, op.Path)
+		}
+		if op.Op == PatchRemove {
+			delete(p, last)
+		} else {
+			p[last] = value
+		}
+	case []any:
+		if op.Op == PatchAdd && last == "-" {
+			set(append(p, value))
+			break
+		}
+		i, err := strconv.Atoi(last)
+		if err != nil || i < 0 || i > len(p) || (i == len(p) && op.Op != PatchAdd) {
+			return nil, fmt.Errorf("patch %s %s: path not found", op.Op, op.Path)
+		}
+		switch op.Op {
+		case PatchAdd:
+			p = append(p, nil)
+			copy(p[i+1:], p[i:])
+			p[i] = value
+			set(p)
+		case PatchRemove:
+			set(append(p[:i], p[i+1:]...))
+		default:
+			p[i] = value
+		}
+	default:
+		return nil, fmt.Errorf("patch %s %s: path not found", op.Op, op.Path)
+	}
+	return doc, nil
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var (
+	pointerEscaper   = strings.NewReplacer("~", "~0", "/", "~1")
+	pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+)
+
+func escapePointer(s string) string   { return pointerEscaper.Replace(s) }
+func unescapePointer(s string) string { return pointerUnescaper.Replace(s) }
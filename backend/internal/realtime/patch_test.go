@@ -0,0 +1,81 @@
+package realtime
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func decodeDoc(t *testing.T, s string) any {
+	t.Helper()
+
+	var doc any
+	if err := json.Unmarshal([]byte(s), &doc); err != nil {
+		t.Fatalf("decode %s: %v", s, err)
+	}
+	return doc
+}
+
+func TestDiff_ApplyRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		prev, next string
+		ops        int
+	}{
+		"unchanged":      {`{"a":1,"b":[1,2]}`, `{"a":1,"b":[1,2]}`, 0},
+		"nested scalar":  {`{"players":[{"id":"p1","score":1},{"id":"p2","score":0}]}`, `{"players":[{"id":"p1","score":1},{"id":"p2","score":3}]}`, 1},
+		"key added":      {`{"a":1}`, `{"a":1,"b":{"c":true}}`, 1},
+		"key removed":    {`{"a":1,"b":2}`, `{"a":1}`, 1},
+		"array grows":    {`{"a":[1]}`, `{"a":[1,2,3]}`, 2},
+		"array shrinks":  {`{"a":[1,2,3]}`, `{"a":[1]}`, 2},
+		"type changes":   {`{"a":[1]}`, `{"a":{"b":1}}`, 1},
+		"null value":     {`{"a":1}`, `{"a":null}`, 1},
+		"escaped keys":   {`{"a/b":1,"c~d":2}`, `{"a/b":2,"c~d":3}`, 2},
+		"root replaced":  {`[1]`, `"x"`, 1},
+		"scalar to null": {`{"a":{"b":"x"}}`, `{"a":{"b":null}}`, 1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			prev, next := decodeDoc(t, tc.prev), decodeDoc(t, tc.next)
+			ops := Diff(prev, next)
+			if len(ops) != tc.ops {
+				t.Fatalf("expected %d ops, got %d: %+v", tc.ops, len(ops), ops)
+			}
+
+			// Patches travel as JSON: apply the decoded form to a fresh copy of prev.
+			raw, err := json.Marshal(ops)
+			if err != nil {
+				t.Fatalf("marshal ops: %v", err)
+			}
+			var decoded []PatchOp
+			if err := json.Unmarshal(raw, &decoded); err != nil {
+				t.Fatalf("unmarshal ops: %v", err)
+			}
+			got, err := Apply(decodeDoc(t, tc.prev), decoded)
+			if err != nil {
+				t.Fatalf("apply: %v", err)
+			}
+			if !reflect.DeepEqual(got, next) {
+				t.Fatalf("expected %v after apply, got %v (ops %s)", next, got, raw)
+			}
+		})
+	}
+}
+
+func TestApply_RejectsUnknownPaths(t *testing.T) {
+	t.Parallel()
+
+	doc := decodeDoc(t, `{"a":[1]}`)
+	for _, op := range []PatchOp{
+		{Op: PatchReplace, Path: "/b", Value: 1},
+		{Op: PatchRemove, Path: "/a/3"},
+		{Op: PatchAdd, Path: "/b/c", Value: 1},
+		{Op: PatchReplace, Path: "a", Value: 1},
+	} {
+		if _, err := Apply(doc, []PatchOp{op}); err == nil {
+			t.Fatalf("expected %+v to fail", op)
+		}
+	}
+}
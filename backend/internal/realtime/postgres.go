@@ -0,0 +1,271 @@
+package realtime
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// DefaultPostgresChannel is the LISTEN/NOTIFY channel used when none is configured.
+	DefaultPostgresChannel = "bes_realtime"
+
+	// Postgres rejects NOTIFY payloads of 8000 bytes or more. Larger events (e.g. snapshots
+	// with a long playlist) are spilled to realtime_events and notified by reference.
+	maxInlineNotifyBytes = 7500
+
+	spillRetention   = time.Minute
+	outboxSize       = 1024
+	listenRetryDelay = 2 * time.Second
+)
+
+// PostgresBackend relays events between instances through Postgres LISTEN/NOTIFY,
+// using the pool the API already holds.
+//
+// Local subscribers are served immediately on Publish; the event is then notified to the
+// other instances, which ignore notifications originating from themselves.
+//
+// Schema expectations (see migrations/0006_realtime_events.sql):
+// - realtime_events(id BIGSERIAL PK, payload TEXT, created_at)
+type PostgresBackend struct {
+	pool       *pgxpool.Pool
+	channel    string
+	instanceID string
+	outbox     chan Event
+
+	mu      sync.RWMutex
+	deliver func(Event)
+}
+
+// pgEnvelope is the NOTIFY payload. Exactly one of Event or Ref is set.
+type pgEnvelope struct {
+	Origin string  `json:"o"`
+	Event  *pgWire `json:"e,omitempty"`
+	Ref    int64   `json:"r,omitempty"`
+}
+
+// pgWire mirrors Event with a raw payload so remote instances forward it verbatim.
+type pgWire struct {
+	Type      string          `json:"type"`
+	RoomID    string          `json:"roomId"`
+	Timestamp time.Time       `json:"ts"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+}
+
+// NewPostgresBackend creates a backend on the given channel (DefaultPostgresChannel if empty).
+// Call Run to start relaying.
+func NewPostgresBackend(pool *pgxpool.Pool, channel string) *PostgresBackend {
+	if channel == "" {
+		channel = DefaultPostgresChannel
+	}
+	return &PostgresBackend{
+		pool:       pool,
+		channel:    channel,
+		instanceID: newInstanceID(),
+		outbox:     make(chan Event, outboxSize),
+	}
+}
+
+// Attach implements Backend.
+func (b *PostgresBackend) Attach(deliver func(Event)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.deliver = deliver
+}
+
+// Publish implements Backend. It never blocks: if the outbox is full the event still
+// reaches local subscribers but is not relayed.
+func (b *PostgresBackend) Publish(ev Event) {
+	b.deliverLocal(ev)
+	select {
+	case b.outbox <- ev:
+	default:
+		log.Printf("realtime: outbox full, event not relayed: roomId=%s type=%s", ev.RoomID, ev.Type)
+	}
+}
+
+// Run relays published events and listens for remote ones until ctx is done.
+func (b *PostgresBackend) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		b.runSender(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		b.runListener(ctx)
+	}()
+	wg.Wait()
+}
+
+func (b *PostgresBackend) deliverLocal(ev Event) {
+	b.mu.RLock()
+	deliver := b.deliver
+	b.mu.RUnlock()
+	if deliver != nil {
+		deliver(ev)
+	}
+}
+
+func (b *PostgresBackend) runSender(ctx context.Context) {
+	prune := time.NewTicker(spillRetention)
+	defer prune.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-prune.C:
+			const q = `DELETE FROM realtime_events WHERE created_at < now() - make_interval(secs => $1);`
+			if _, err := b.pool.Exec(ctx, q, spillRetention.Seconds()); err != nil && ctx.Err() == nil {
+				log.Printf("realtime: prune spilled events failed: %v", err)
+			}
+		case ev := <-b.outbox:
+			if err := b.notify(ctx, ev); err != nil && ctx.Err() == nil {
+				log.Printf("realtime: notify failed: roomId=%s type=%s err=%v", ev.RoomID, ev.Type, err)
+			}
+		}
+	}
+}
+
+func (b *PostgresBackend) notify(ctx context.Context, ev Event) error {
+	payload, err := json.Marshal(ev.Payload)
+	if err != nil {
+		return fmt.Errorf("marshal payload: %w", err)
+	}
+	env := pgEnvelope{
+		Origin: b.instanceID,
+		Event: &pgWire{
+			Type:      ev.Type,
+			RoomID:    ev.RoomID,
+			Timestamp: ev.Timestamp,
+			Payload:   payload,
+		},
+	}
+	msg, err := json.Marshal(env)
+	if err != nil {
+		return fmt.Errorf("marshal envelope: %w", err)
+	}
+
+	nctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if len(msg) > maxInlineNotifyBytes {
+		wire, err := json.Marshal(env.Event)
+		if err != nil {
+			return fmt.Errorf("marshal spilled event: %w", err)
+		}
+		const q = `INSERT INTO realtime_events (payload) VALUES ($1) RETURNING id;`
+		var id int64
+		if err := b.pool.QueryRow(nctx, q, string(wire)).Scan(&id); err != nil {
+			return fmt.Errorf("spill event: %w", err)
+		}
+		msg, err = json.Marshal(pgEnvelope{Origin: b.instanceID, Ref: id})
+		if err != nil {
+			return fmt.Errorf("marshal envelope ref: %w", err)
+		}
+	}
+
+	if _, err := b.pool.Exec(nctx, `SELECT pg_notify($1, $2);`, b.channel, string(msg)); err != nil {
+		return fmt.Errorf("pg_notify: %w", err)
+	}
+	return nil
+}
+
+func (b *PostgresBackend) runListener(ctx context.Context) {
+	for {
+		err := b.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("realtime: listener stopped, retrying in %s: %v", listenRetryDelay, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenRetryDelay):
+		}
+	}
+}
+
+func (b *PostgresBackend) listen(ctx context.Context) error {
+	conn, err := b.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire listener conn: %w", err)
+	}
+	// The connection carries LISTEN state; never hand it back to the pool.
+	defer conn.Hijack().Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{b.channel}.Sanitize()); err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+
+	for {
+		n, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("wait for notification: %w", err)
+		}
+
+		var env pgEnvelope
+		if err := json.Unmarshal([]byte(n.Payload), &env); err != nil {
+			log.Printf("realtime: invalid notification ignored: %v", err)
+			continue
+		}
+		if env.Origin == b.instanceID {
+			continue
+		}
+
+		wire := env.Event
+		if wire == nil && env.Ref != 0 {
+			wire, err = b.loadSpilled(ctx, env.Ref)
+			if err != nil {
+				log.Printf("realtime: load spilled event %d failed: %v", env.Ref, err)
+				continue
+			}
+		}
+		if wire == nil {
+			continue
+		}
+
+		b.deliverLocal(Event{
+			Type:      wire.Type,
+			RoomID:    wire.RoomID,
+			Timestamp: wire.Timestamp,
+			Payload:   wire.Payload,
+		})
+	}
+}
+
+func (b *PostgresBackend) loadSpilled(ctx context.Context, id int64) (*pgWire, error) {
+	const q = `SELECT payload FROM realtime_events WHERE id = $1;`
+	var raw string
+	err := b.pool.QueryRow(ctx, q, id).Scan(&raw)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("spilled event expired")
+	}
+	if err != nil {
+		return nil, err
+	}
+	var wire pgWire
+	if err := json.Unmarshal([]byte(raw), &wire); err != nil {
+		return nil, fmt.Errorf("decode spilled event: %w", err)
+	}
+	return &wire, nil
+}
+
+func newInstanceID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
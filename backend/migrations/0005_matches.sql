@@ -0,0 +1,77 @@
+-- +goose Up
+-- Match history for Name That Tune (matches, rounds, buzzes, final standings).
+--
+-- Notes:
+-- - A match is opened lazily when the first round starts in a room and closed when the
+--   room closes or another playlist is loaded.
+-- - Rows survive room deletion (room_id is set to NULL), so players can review past games.
+-- - Rows also survive the host's account deletion (owner_sub is set to NULL), so the other
+--   players keep their history and standings.
+-- - player_id is the room_players id at the time of the match; it is not a foreign key
+--   because room_players rows are deleted together with their room.
+
+CREATE TABLE IF NOT EXISTS matches (
+  id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  room_id       UUID NULL REFERENCES rooms(id) ON DELETE SET NULL,
+  room_name     TEXT NOT NULL,
+  owner_sub     TEXT NULL REFERENCES users(sub) ON DELETE SET NULL,
+  playlist_id   UUID NULL REFERENCES playlists(id) ON DELETE SET NULL,
+  playlist_name TEXT NOT NULL DEFAULT '',
+  started_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+  ended_at      TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_matches_owner_sub ON matches (owner_sub);
+CREATE INDEX IF NOT EXISTS idx_matches_started_at ON matches (started_at DESC);
+
+-- At most one open match per room.
+CREATE UNIQUE INDEX IF NOT EXISTS uq_matches_room_open
+  ON matches (room_id) WHERE ended_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS match_rounds (
+  id               UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  match_id         UUID NOT NULL REFERENCES matches(id) ON DELETE CASCADE,
+  round_number     INTEGER NOT NULL, -- 1-based, in play order
+  track_index      INTEGER NOT NULL,
+  playlist_item_id UUID NULL REFERENCES playlist_items(id) ON DELETE SET NULL,
+  title            TEXT NOT NULL,
+  youtube_id       TEXT NOT NULL DEFAULT '',
+  started_at       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_match_rounds_match_number
+  ON match_rounds (match_id, round_number);
+
+CREATE TABLE IF NOT EXISTS match_buzzes (
+  id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  round_id      UUID NOT NULL REFERENCES match_rounds(id) ON DELETE CASCADE,
+  player_id     UUID NOT NULL,
+  user_sub      TEXT NULL REFERENCES users(sub) ON DELETE SET NULL,
+  nickname      TEXT NOT NULL,
+  buzzed_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+  correct       BOOLEAN NULL, -- NULL until the owner resolves the buzz
+  resolved_at   TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_match_buzzes_round_id ON match_buzzes (round_id);
+CREATE INDEX IF NOT EXISTS idx_match_buzzes_user_sub ON match_buzzes (user_sub);
+
+CREATE TABLE IF NOT EXISTS match_standings (
+  match_id      UUID NOT NULL REFERENCES matches(id) ON DELETE CASCADE,
+  player_id     UUID NOT NULL,
+  user_sub      TEXT NULL REFERENCES users(sub) ON DELETE SET NULL,
+  nickname      TEXT NOT NULL,
+  picture_url   TEXT NOT NULL DEFAULT '',
+  score         INTEGER NOT NULL DEFAULT 0,
+  rank          INTEGER NOT NULL,
+  PRIMARY KEY (match_id, player_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_match_standings_user_sub ON match_standings (user_sub);
+
+-- +goose Down
+
+DROP TABLE IF EXISTS match_standings;
+DROP TABLE IF EXISTS match_buzzes;
+DROP TABLE IF EXISTS match_rounds;
+DROP TABLE IF EXISTS matches;
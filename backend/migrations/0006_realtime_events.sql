@@ -0,0 +1,17 @@
+-- +goose Up
+-- Spill table for realtime events relayed through Postgres LISTEN/NOTIFY.
+--
+-- NOTIFY payloads are limited to 8000 bytes; larger events (e.g. room snapshots with long
+-- playlists) are stored here and notified by id. Rows are short-lived and pruned by the
+-- API instances.
+
+CREATE TABLE IF NOT EXISTS realtime_events (
+  id          BIGSERIAL PRIMARY KEY,
+  payload     TEXT NOT NULL,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_realtime_events_created_at ON realtime_events (created_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS realtime_events;
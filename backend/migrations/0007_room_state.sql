@@ -0,0 +1,14 @@
+-- +goose Up
+-- Volatile per-room state (access tokens, buzz cooldowns, playback sync flags).
+--
+-- Used when BES_ROOM_STATE_BACKEND=postgres so the state survives restarts and is shared
+-- by replicas. Rows go away with their room.
+
+CREATE TABLE IF NOT EXISTS room_state (
+  room_id     UUID PRIMARY KEY REFERENCES rooms(id) ON DELETE CASCADE,
+  state       JSONB NOT NULL DEFAULT '{}'::jsonb,
+  updated_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS room_state;
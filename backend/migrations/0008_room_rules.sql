@@ -0,0 +1,11 @@
+-- +goose Up
+-- Per-room house rules (scoring, cooldown, speed bonus, buzz limit, auto-advance).
+--
+-- Stored as a JSON document; keys missing from it fall back to the application defaults,
+-- so existing rooms keep the historical rules.
+
+ALTER TABLE rooms
+  ADD COLUMN IF NOT EXISTS rules JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+-- +goose Down
+ALTER TABLE rooms DROP COLUMN IF EXISTS rules;
@@ -0,0 +1,18 @@
+-- +goose Up
+-- Structured playlist item metadata: artist, song title, year and alternative answers.
+--
+-- artist/song_title are parsed from the video title on add and can be fixed by the owner;
+-- typed-answer matching prefers them over the raw title when set.
+
+ALTER TABLE playlist_items
+  ADD COLUMN IF NOT EXISTS artist TEXT NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS song_title TEXT NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS year INT NULL,
+  ADD COLUMN IF NOT EXISTS accepted_answers TEXT[] NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE playlist_items
+  DROP COLUMN IF EXISTS accepted_answers,
+  DROP COLUMN IF EXISTS year,
+  DROP COLUMN IF EXISTS song_title,
+  DROP COLUMN IF EXISTS artist;
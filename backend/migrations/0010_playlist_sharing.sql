@@ -0,0 +1,33 @@
+-- +goose Up
+-- Playlist sharing: visibility and collaborators.
+--
+-- visibility:
+-- - private: owner and collaborators only
+-- - unlisted: anyone with the playlist ID can read (and load it into a room)
+-- - public: unlisted + listed by the discover endpoint
+--
+-- Collaborators are "editor" (edit name and items) or "viewer" (read-only). Only the owner
+-- manages visibility and collaborators.
+
+ALTER TABLE playlists
+  ADD COLUMN IF NOT EXISTS visibility TEXT NOT NULL DEFAULT 'private'
+    CHECK (visibility IN ('private', 'unlisted', 'public'));
+
+CREATE INDEX IF NOT EXISTS playlists_public_idx
+  ON playlists (updated_at DESC)
+  WHERE visibility = 'public' AND deleted_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS playlist_collaborators (
+  playlist_id UUID NOT NULL REFERENCES playlists(id) ON DELETE CASCADE,
+  user_sub TEXT NOT NULL REFERENCES users(sub) ON DELETE CASCADE,
+  role TEXT NOT NULL CHECK (role IN ('editor', 'viewer')),
+  added_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (playlist_id, user_sub)
+);
+
+CREATE INDEX IF NOT EXISTS playlist_collaborators_user_idx ON playlist_collaborators (user_sub);
+
+-- +goose Down
+DROP TABLE IF EXISTS playlist_collaborators;
+DROP INDEX IF EXISTS playlists_public_idx;
+ALTER TABLE playlists DROP COLUMN IF EXISTS visibility;
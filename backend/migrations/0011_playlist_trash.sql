@@ -0,0 +1,9 @@
+-- +goose Up
+-- Playlist trash: deleted playlists (deleted_at set) are listed and purged by deletion date.
+
+CREATE INDEX IF NOT EXISTS playlists_deleted_at_idx
+  ON playlists (owner_sub, deleted_at)
+  WHERE deleted_at IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS playlists_deleted_at_idx;
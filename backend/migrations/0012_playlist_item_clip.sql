@@ -0,0 +1,12 @@
+-- +goose Up
+-- Clip window per playlist item: playback starts at start_ms and stops after
+-- clip_duration_ms (0 = until the end of the video).
+
+ALTER TABLE playlist_items
+  ADD COLUMN IF NOT EXISTS start_ms INT NOT NULL DEFAULT 0 CHECK (start_ms >= 0),
+  ADD COLUMN IF NOT EXISTS clip_duration_ms INT NOT NULL DEFAULT 0 CHECK (clip_duration_ms >= 0);
+
+-- +goose Down
+ALTER TABLE playlist_items
+  DROP COLUMN IF EXISTS clip_duration_ms,
+  DROP COLUMN IF EXISTS start_ms;
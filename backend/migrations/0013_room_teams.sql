@@ -0,0 +1,21 @@
+-- +goose Up
+-- Teams per room. Players join at most one team; deleting a team leaves its players
+-- teamless. Team scores are the sum of their players' scores.
+
+CREATE TABLE IF NOT EXISTS room_teams (
+  id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  room_id    UUID NOT NULL REFERENCES rooms(id) ON DELETE CASCADE,
+  name       TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  UNIQUE (room_id, name)
+);
+
+ALTER TABLE room_players
+  ADD COLUMN IF NOT EXISTS team_id UUID REFERENCES room_teams(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_room_players_team_id ON room_players (team_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_room_players_team_id;
+ALTER TABLE room_players DROP COLUMN IF EXISTS team_id;
+DROP TABLE IF EXISTS room_teams;
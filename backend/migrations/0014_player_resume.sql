@@ -0,0 +1,13 @@
+-- +goose Up
+-- Resume tokens: each seat gets a random nonce, signed into the token handed to its
+-- player, so the seat (and score) can be reclaimed after a refresh or a restart.
+-- Seats left for longer than the grace period are removed.
+
+ALTER TABLE room_players
+  ADD COLUMN IF NOT EXISTS resume_nonce TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_room_players_left_at ON room_players (left_at) WHERE NOT connected;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_room_players_left_at;
+ALTER TABLE room_players DROP COLUMN IF EXISTS resume_nonce;